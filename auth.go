@@ -0,0 +1,146 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/joe-elliott/cert-exporter/src/checkers"
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+// authenticator reports whether a request carries valid credentials
+type authenticator func(r *http.Request) bool
+
+// authenticators accept a request if any of them does.  Without any, requests are not authenticated.
+var authenticators []authenticator
+
+// requireAuthentication rejects requests no authenticator accepts
+func requireAuthentication(next http.Handler) http.Handler {
+	if len(authenticators) == 0 {
+		return next
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, authenticate := range authenticators {
+			if authenticate(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		if authBasicUsername != "" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="cert-exporter"`)
+		}
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+	})
+}
+
+// readSecretFile returns the trimmed content of a file holding a token or password
+func readSecretFile(file string) string {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		glog.Fatalf("Error reading %v: %v", file, err)
+	}
+
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		glog.Fatalf("%v is empty", file)
+	}
+	return secret
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return "", false
+	}
+	return strings.TrimPrefix(header, "Bearer "), true
+}
+
+func bearerTokenAuthenticator(token string) authenticator {
+	return func(r *http.Request) bool {
+		t, ok := bearerToken(r)
+		return ok && equal(t, token)
+	}
+}
+
+func basicAuthenticator(username, password string) authenticator {
+	return func(r *http.Request) bool {
+		u, p, ok := r.BasicAuth()
+		return ok && equal(u, username) && equal(p, password)
+	}
+}
+
+// tokenReviewCacheTTL is how long the result of a TokenReview is reused for the same token, so every scrape does not
+// hit the API server
+const tokenReviewCacheTTL = time.Minute
+
+// tokenReviewAuthenticator accepts bearer tokens the kubernetes API server authenticates, e.g. the service account
+// token of Prometheus
+func tokenReviewAuthenticator(kubeconfigPath string) authenticator {
+	config, err := checkers.BuildConfig(kubeconfigPath)
+	if err != nil {
+		glog.Fatalf("Error building kubeconfig: %s", err.Error())
+	}
+
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		glog.Fatalf("kubernetes.NewForConfig failed: %v", err)
+	}
+
+	var mutex sync.Mutex
+	reviewed := map[[sha256.Size]byte]time.Time{}
+
+	return func(r *http.Request) bool {
+		token, ok := bearerToken(r)
+		if !ok {
+			return false
+		}
+
+		key := sha256.Sum256([]byte(token))
+		mutex.Lock()
+		expiry, ok := reviewed[key]
+		mutex.Unlock()
+		if ok && time.Now().Before(expiry) {
+			return true
+		}
+
+		ctx, cancel := checkers.RequestContext(r.Context())
+		defer cancel()
+		review, err := client.AuthenticationV1().TokenReviews().Create(ctx, &authenticationv1.TokenReview{
+			Spec: authenticationv1.TokenReviewSpec{Token: token},
+		}, metav1.CreateOptions{})
+		if err != nil {
+			metrics.RecordError("server", "", metrics.ReasonAuth)
+			glog.Errorf("Error reviewing token: %v", err)
+			return false
+		}
+		if !review.Status.Authenticated {
+			return false
+		}
+
+		mutex.Lock()
+		for k, e := range reviewed {
+			if time.Now().After(e) {
+				delete(reviewed, k)
+			}
+		}
+		reviewed[key] = time.Now().Add(tokenReviewCacheTTL)
+		mutex.Unlock()
+		return true
+	}
+}
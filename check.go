@@ -0,0 +1,87 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v3"
+
+	"github.com/joe-elliott/cert-exporter/src/exporters"
+)
+
+const (
+	checkOK       = 0
+	checkWarning  = 1
+	checkCritical = 2
+)
+
+var checkStatuses = []string{"OK", "WARNING", "CRITICAL"}
+
+// runCheck implements `cert-exporter check <path|glob>...`.  It prints every cert found in the matching files and
+// returns 2 if any of them expires within the critical window or a file cannot be parsed, 1 if any of them expires
+// within the warning window and 0 otherwise.
+func runCheck(arguments []string) int {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	warning := flags.Int("warning-days", 30, "Certs expiring within this many days are reported as warnings.")
+	critical := flags.Int("critical-days", 7, "Certs expiring within this many days are reported as critical.")
+	password := flags.String("password", "", "Password of PKCS12 and JKS files.")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: %s check [flags] <path|glob>...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(arguments)
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return checkCritical
+	}
+
+	var files []string
+	for _, pattern := range flags.Args() {
+		matches, err := doublestar.Glob(pattern)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid glob %v: %v\n", pattern, err)
+			return checkCritical
+		}
+		if len(matches) == 0 {
+			fmt.Fprintf(os.Stderr, "No file matches %v\n", pattern)
+			return checkCritical
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+
+	result := checkOK
+	out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(out, "STATUS\tFILE\tCN\tISSUER\tNOT AFTER\tDAYS LEFT")
+	for _, file := range files {
+		certs, err := exporters.ParseCertificateFile(file, *password)
+		if err != nil {
+			fmt.Fprintf(out, "%s\t%s\t%v\t\t\t\n", checkStatuses[checkCritical], file, err)
+			result = checkCritical
+			continue
+		}
+
+		for _, cert := range certs {
+			left := time.Until(cert.NotAfter)
+			status := checkOK
+			if left < time.Duration(*critical)*24*time.Hour {
+				status = checkCritical
+			} else if left < time.Duration(*warning)*24*time.Hour {
+				status = checkWarning
+			}
+			if status > result {
+				result = status
+			}
+
+			fmt.Fprintf(out, "%s\t%s\t%s\t%s\t%s\t%d\n", checkStatuses[status], file, cert.Subject.CommonName, cert.Issuer.CommonName, cert.NotAfter.Format(time.RFC3339), int(left.Hours()/24))
+		}
+	}
+	out.Flush()
+
+	return result
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/joe-elliott/cert-exporter/src/outputs"
+)
+
+// maxDogStatsDPacket keeps every datagram within the MTU of most networks
+const maxDogStatsDPacket = 1432
+
+// dogStatsDTagReplacer replaces the characters DogStatsD does not allow in tags
+var dogStatsDTagReplacer = strings.NewReplacer(",", "_", "|", "_", "\n", "_", "#", "_")
+
+// dogStatsDWriter sends every expiry gauge to a DogStatsD agent, tagged with the labels of the series, for fleets that
+// only run Datadog
+type dogStatsDWriter struct {
+	address string
+}
+
+func init() {
+	outputs.Register("dogstatsd", func(settings map[string]string) (outputs.Output, error) {
+		if settings["address"] == "" {
+			return nil, errors.New("dogstatsd needs an address")
+		}
+		return newDogStatsDWriter(settings["address"]), nil
+	})
+}
+
+func newDogStatsDWriter(address string) *dogStatsDWriter {
+	return &dogStatsDWriter{address: address}
+}
+
+// Write sends the current value of every expiry gauge
+func (w *dogStatsDWriter) Write() error {
+	err := w.send()
+	if err != nil {
+		return fmt.Errorf("%v: %w", w.address, err)
+	}
+	return nil
+}
+
+func (w *dogStatsDWriter) send() error {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.Dial("udp", w.address)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	var packet bytes.Buffer
+	flush := func() error {
+		if packet.Len() == 0 {
+			return nil
+		}
+		_, err := conn.Write(packet.Bytes())
+		packet.Reset()
+		return err
+	}
+
+	for _, family := range families {
+		if !strings.Contains(family.GetName(), "_expires_in_seconds") {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			line := dogStatsDGauge(family.GetName(), metric.GetGauge().GetValue(), metric.GetLabel())
+			if packet.Len() > 0 && packet.Len()+1+len(line) > maxDogStatsDPacket {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+			if packet.Len() > 0 {
+				packet.WriteByte('\n')
+			}
+			packet.WriteString(line)
+		}
+	}
+	return flush()
+}
+
+// dogStatsDGauge formats a gauge as name:value|g|#label:value,...  Empty labels are left out.
+func dogStatsDGauge(name string, value float64, labels []*dto.LabelPair) string {
+	tags := make([]string, 0, len(labels))
+	for _, label := range labels {
+		if label.GetValue() == "" {
+			continue
+		}
+		tags = append(tags, label.GetName()+":"+dogStatsDTagReplacer.Replace(label.GetValue()))
+	}
+
+	line := name + ":" + strconv.FormatFloat(value, 'f', -1, 64) + "|g"
+	if len(tags) > 0 {
+		line += "|#" + strings.Join(tags, ",")
+	}
+	return line
+}
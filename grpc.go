@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"sync"
+
+	"github.com/golang/glog"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+
+	certexporterv1 "github.com/joe-elliott/cert-exporter/src/api/certexporterv1"
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+var grpcListenAddress string
+
+// inventoryService serves the certs of metrics.Inventory over the gRPC inventory service, for controllers that do not
+// scrape Prometheus
+type inventoryService struct {
+	// windowSeconds is the window of WatchExpiries calls that do not request one
+	windowSeconds float64
+
+	mutex    sync.Mutex
+	watchers map[chan struct{}]bool
+}
+
+func newInventoryService(windowSeconds float64) *inventoryService {
+	return &inventoryService{windowSeconds: windowSeconds, watchers: map[chan struct{}]bool{}}
+}
+
+// scanFinished wakes up every WatchExpiries call to send the expiries of the scan
+func (s *inventoryService) scanFinished() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for watcher := range s.watchers {
+		select {
+		case watcher <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// ListCerts returns every cert exported by any checker, sorted like cert-exporter list
+func (s *inventoryService) ListCerts(ctx context.Context, request *certexporterv1.ListCertsRequest) (*certexporterv1.ListCertsResponse, error) {
+	entries, err := metrics.Inventory()
+	if err != nil {
+		return nil, certexporterv1.Errorf(certexporterv1.CodeInternal, "gathering metrics: %v", err)
+	}
+
+	response := &certexporterv1.ListCertsResponse{}
+	for _, e := range entries {
+		if request.Namespace == "" || e.Namespace == request.Namespace {
+			response.Certs = append(response.Certs, inventoryCert(e))
+		}
+	}
+	return response, nil
+}
+
+// WatchExpiries sends the certs expiring within the window right away and again after every scan, until the client
+// goes away
+func (s *inventoryService) WatchExpiries(request *certexporterv1.WatchExpiriesRequest, stream certexporterv1.ExpiryStream) error {
+	if request.WindowSeconds < 0 {
+		return certexporterv1.Errorf(certexporterv1.CodeInvalidArgument, "window_seconds must not be negative")
+	}
+	windowSeconds := s.windowSeconds
+	if request.WindowSeconds > 0 {
+		windowSeconds = float64(request.WindowSeconds)
+	}
+
+	scans := make(chan struct{}, 1)
+	s.mutex.Lock()
+	s.watchers[scans] = true
+	s.mutex.Unlock()
+	defer func() {
+		s.mutex.Lock()
+		delete(s.watchers, scans)
+		s.mutex.Unlock()
+	}()
+
+	for {
+		entries, err := metrics.Inventory()
+		if err != nil {
+			return certexporterv1.Errorf(certexporterv1.CodeInternal, "gathering metrics: %v", err)
+		}
+		for _, e := range entries {
+			if (request.Namespace != "" && e.Namespace != request.Namespace) || e.SecondsLeft >= windowSeconds {
+				continue
+			}
+			if err := stream.Send(&certexporterv1.ExpiryEvent{Cert: inventoryCert(e), Expired: e.Expired}); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-scans:
+		}
+	}
+}
+
+// inventoryCert returns the message of an inventory entry
+func inventoryCert(e metrics.InventoryEntry) *certexporterv1.Cert {
+	return &certexporterv1.Cert{
+		Kind:      e.Kind,
+		Namespace: e.Namespace,
+		Name:      e.Name,
+		Key:       e.Key,
+		CN:        e.CN,
+		DaysLeft:  int64(e.DaysLeft),
+		Issuer:    e.Issuer,
+		Cluster:   e.Cluster,
+		Node:      e.Node,
+	}
+}
+
+// serveGRPC serves the inventory service on address behind the authentication of the metrics.  It is served over TLS
+// with --tls-cert-file and --tls-key-file, and over plaintext HTTP/2 otherwise.
+func serveGRPC(address string, service *inventoryService) {
+	handler := requireAuthentication(certexporterv1.NewInventoryHandler(service))
+
+	if tlsCertFile == "" && tlsKeyFile == "" {
+		glog.Fatal(http.ListenAndServe(address, h2c.NewHandler(handler, &http2.Server{})))
+	}
+
+	reloader, err := newCertReloader(tlsCertFile, tlsKeyFile)
+	if err != nil {
+		glog.Fatalf("Error loading --tls-cert-file %q and --tls-key-file %q: %v", tlsCertFile, tlsKeyFile, err)
+	}
+	server := &http.Server{
+		Addr:      address,
+		Handler:   handler,
+		TLSConfig: &tls.Config{GetCertificate: reloader.GetCertificate, MinVersion: tls.VersionTLS12},
+	}
+	glog.Fatal(server.ListenAndServeTLS("", ""))
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/golang/glog"
+
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+var listOutput string
+
+// printInventory implements `cert-exporter list`.  It prints the certs found by a --run-once scan in the --output
+// format and returns the exit code.
+func printInventory() int {
+	entries, err := metrics.Inventory()
+	if err != nil {
+		glog.Errorf("Error gathering metrics: %v", err)
+		return 1
+	}
+
+	switch listOutput {
+	case "json", "csv":
+		err = writeReport(os.Stdout, listOutput, entries)
+	default:
+		out := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(out, "NAMESPACE\tKIND\tNAME\tKEY\tNODE\tCN\tDAYS LEFT")
+		for _, e := range entries {
+			fmt.Fprintf(out, "%s\t%s\t%s\t%s\t%s\t%s\t%d\n", e.Namespace, e.Kind, e.Name, e.Key, e.Node, e.CN, e.DaysLeft)
+		}
+		err = out.Flush()
+	}
+
+	if err != nil {
+		glog.Errorf("Error printing inventory: %v", err)
+		return 1
+	}
+	return 0
+}
@@ -1,21 +1,30 @@
 package main
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"flag"
+	"fmt"
+	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang/glog"
-	"github.com/prometheus/client_golang/prometheus"
-	"github.com/prometheus/client_golang/prometheus/promhttp"
 
+	"github.com/joe-elliott/cert-exporter/src/appconfig"
 	"github.com/joe-elliott/cert-exporter/src/args"
 	"github.com/joe-elliott/cert-exporter/src/checkers"
+	"github.com/joe-elliott/cert-exporter/src/config"
 	"github.com/joe-elliott/cert-exporter/src/exporters"
 	"github.com/joe-elliott/cert-exporter/src/metrics"
+	"github.com/joe-elliott/cert-exporter/src/outputs"
+	"github.com/joe-elliott/cert-exporter/src/tracing"
 )
 
 var (
@@ -26,14 +35,36 @@ var (
 
 var (
 	includeCertGlobs                  args.GlobArgs
+	certPasswords                     args.GlobArgs
+	driftSecrets                      args.GlobArgs
+	watchFiles                        bool
+	preset                            string
+	nodeName                          string
+	kubeletPKIDir                     string
+	windowsCertStores                 string
+	presetRoot                        string
 	excludeCertGlobs                  args.GlobArgs
 	includeKubeConfigGlobs            args.GlobArgs
 	excludeKubeConfigGlobs            args.GlobArgs
 	prometheusExporterMetricsDisabled bool
+	goCollectorDisabled               bool
+	processCollectorDisabled          bool
 	prometheusListenAddress           string
 	prometheusPath                    string
+	tlsCertFile                       string
+	tlsKeyFile                        string
+	authBearerTokenFile               string
+	authBasicUsername                 string
+	authBasicPasswordFile             string
+	authTokenReview                   bool
 	pollingPeriod                     time.Duration
 	kubeconfigPath                    string
+	kubeContext                       string
+	impersonateUser                   string
+	impersonateGroups                 args.GlobArgs
+	clusterName                       string
+	metricsConstLabels                string
+	metricsPrefix                     string
 	secretsLabelSelector              args.GlobArgs
 	secretsAnnotationSelector         args.GlobArgs
 	secretsNamespace                  string
@@ -41,69 +72,571 @@ var (
 	includeSecretsDataGlobs           args.GlobArgs
 	excludeSecretsDataGlobs           args.GlobArgs
 	includeSecretsTypes               args.GlobArgs
+	excludeSecretsTypes               args.GlobArgs
+	secretsTLSMode                    bool
+	doubleBase64                      bool
+	secretsArchiveMaxBytes            int64
+	gzipMaxBytes                      int64
+	candidatePasswords                args.GlobArgs
 	configMapsLabelSelector           args.GlobArgs
 	configMapsAnnotationSelector      args.GlobArgs
 	configMapsNamespace               string
 	configMapsListOfNamespaces        string
 	includeConfigMapsDataGlobs        args.GlobArgs
 	excludeConfigMapsDataGlobs        args.GlobArgs
+	configMapsConfigExtractors        args.GlobArgs
+	configMapsEmbeddedCertPaths       args.GlobArgs
+	configMapsConfigFileRoot          string
+	watchNamespaces                   bool
+	namespaceAccessReview             bool
+	includeNamespaceGlobs             args.GlobArgs
+	excludeNamespaceGlobs             args.GlobArgs
 	webhookCheckEnabled               bool
 	webhooksLabelSelector             args.GlobArgs
 	webhooksAnnotationSelector        args.GlobArgs
+	routeCheckEnabled                 bool
+	routesLabelSelector               args.GlobArgs
+	routesAnnotationSelector          args.GlobArgs
+	routesListOfNamespaces            string
+	gatewayCheckEnabled               bool
+	gatewaysLabelSelector             args.GlobArgs
+	gatewaysAnnotationSelector        args.GlobArgs
+	gatewaysListOfNamespaces          string
+	envoyAdminURLs                    args.GlobArgs
+	envoyPodsLabelSelector            args.GlobArgs
+	envoyPodsListOfNamespaces         string
+	envoyAdminPort                    int
+	csrCheckEnabled                   bool
+	csrsLabelSelector                 args.GlobArgs
+	csrStuckAge                       time.Duration
+	bootstrapTokenCheckEnabled        bool
 	awsAccount                        string
 	awsRegion                         string
 	awsSecrets                        args.GlobArgs
+	noEgress                          bool
+	readOnly                          bool
+	pretendNow                        string
+	minRSAKeySize                     int
+	minECDSAKeySize                   int
+	maxSeriesPerNamespace             int
+	maxSeriesPerObject                int
+	maxCertsPerKey                    int
+	maxParseFailureRatio              float64
+	dropLabels                        string
+	hashLabels                        string
+	certInfoEnabled                   bool
+	lifetimeMetricsEnabled            bool
+	copyLabels                        string
+	copyAnnotations                   string
+	serialLabelEnabled                bool
+	fingerprintLabelEnabled           bool
+	subjectLabelEnabled               bool
+	skipCACerts                       bool
+	leafOnly                          bool
+	caOnly                            bool
+	configFile                        string
+	verifyChains                      bool
+	checkChainCompleteness            bool
+	checkCT                           bool
+	verifyCABundle                    string
+	showVersion                       bool
+	runOnce                           bool
+	runOnceOutputFile                 string
+	pushgatewayURL                    string
+	remoteWriteURL                    string
+	remoteWriteBearerTokenFile        string
+	remoteWriteCAFile                 string
+	dogStatsDAddress                  string
+	certificateReports                bool
+	annotateObjects                   bool
+	warningDays                       int
+	criticalDays                      int
+	renewCertManagerDays              int
+	restartOnRotation                 bool
+	rotationHistoryConfigMap          string
+	otlpEndpoint                      string
+	listRetries                       int
+	listRetryBackoff                  time.Duration
+	requestTimeout                    time.Duration
+	periodJitter                      time.Duration
+	initialDelay                      time.Duration
 )
 
 func init() {
 	flag.Var(&includeCertGlobs, "include-cert-glob", "File globs to include when looking for certs.")
+	flag.Var(&certPasswords, "cert-password", "<path glob>=env:<variable> or <path glob>=file:<path> reading the password of the PKCS12 and JKS files matching the glob from an environment variable or a file.")
+	flag.Var(&driftSecrets, "drift-secret", "<path glob>=<namespace>/<secret>[/<key>] comparing the cert files matching the glob with the key of the secret they are mounted from, by default named like the file.")
+	flag.BoolVar(&watchFiles, "watch-files", false, "Rescan cert and kubeconfig files as soon as they change, watching their directories with inotify, next to the periodic scans.")
+	flag.StringVar(&nodeName, "node-name", os.Getenv("NODE_NAME"), "Name of the node the cert and kubeconfig files are read on, added as the nodename label of their metrics. Defaults to $NODE_NAME, e.g. set from spec.nodeName with the downward API.")
+	flag.StringVar(&kubeletPKIDir, "kubelet-pki-dir", "", "Directory holding kubelet-client-current.pem and kubelet-server-current.pem, e.g. /var/lib/kubelet/pki, to monitor the rotation of the kubelet certs.")
+	flag.StringVar(&windowsCertStores, "windows-cert-stores", "", "Comma-delimited list of LocalMachine certificate stores, e.g. My,Root, whose certs are exported by thumbprint. Only supported on Windows.")
+	flag.StringVar(&preset, "preset", "", "Comma-delimited list of well-known layouts whose cert and kubeconfig files are checked, labeled with their component. Supported: kubeadm, system-trust.")
+	flag.StringVar(&presetRoot, "preset-root", "", "Directory the host filesystem is mounted at, prepended to the paths of --preset.")
 	flag.Var(&excludeCertGlobs, "exclude-cert-glob", "File globs to exclude when looking for certs.")
 	flag.Var(&includeKubeConfigGlobs, "include-kubeconfig-glob", "File globs to include when looking for kubeconfigs.")
 	flag.Var(&excludeKubeConfigGlobs, "exclude-kubeconfig-glob", "File globs to exclude when looking for kubeconfigs.")
 	flag.StringVar(&prometheusPath, "prometheus-path", "/metrics", "The path to publish Prometheus metrics to.")
+	flag.StringVar(&reportFile, "report-file", "", "File to write a report of the certs found by every checker to after every scan, as CSV if it ends in .csv and JSON otherwise.")
+	flag.StringVar(&uiPath, "ui-path", "/ui", "The path to serve a read-only HTML table of the certs found by every checker at. Empty disables it.")
 	flag.StringVar(&prometheusListenAddress, "prometheus-listen-address", ":8080", "The address to listen on for Prometheus scrapes.")
+	flag.StringVar(&tlsCertFile, "tls-cert-file", "", "Certificate to serve metrics over HTTPS with. It is reloaded when the file changes.")
+	flag.StringVar(&tlsKeyFile, "tls-key-file", "", "Private key of --tls-cert-file.")
+	flag.StringVar(&authBearerTokenFile, "auth-bearer-token-file", "", "File holding a bearer token every request to the metrics endpoint must carry.")
+	flag.StringVar(&authBasicUsername, "auth-basic-username", "", "Username every request to the metrics endpoint must authenticate as with basic auth.")
+	flag.StringVar(&authBasicPasswordFile, "auth-basic-password-file", "", "File holding the password of --auth-basic-username.")
+	flag.BoolVar(&authTokenReview, "auth-token-review", false, "Accept requests to the metrics endpoint carrying a bearer token the kubernetes API server authenticates with a TokenReview.")
 	flag.BoolVar(&prometheusExporterMetricsDisabled, "prometheus-disable-exporter-metrics", false, "Exclude metrics about the exporter itself (promhttp_*, process_*, go_*).")
+	flag.BoolVar(&goCollectorDisabled, "prometheus-disable-go-collector", false, "Exclude the metrics about the Go runtime (go_*).")
+	flag.BoolVar(&processCollectorDisabled, "prometheus-disable-process-collector", false, "Exclude the metrics about the process (process_*).")
+	flag.BoolVar(&pprofEnabled, "enable-pprof", false, "Serve the net/http/pprof profiles under /debug/pprof/, to profile the exporter in place.")
+	flag.StringVar(&pprofListenAddress, "pprof-listen-address", "", "The address to serve the profiles of --enable-pprof on, e.g. localhost:6060. They are served without authentication. Default: the address of the metrics, behind their authentication.")
+	flag.StringVar(&grpcListenAddress, "grpc-listen-address", "", "The address to serve the gRPC inventory service on, e.g. :9090. Empty disables it.")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "", "OTLP/HTTP endpoint of an OpenTelemetry collector to send traces of the scans to, e.g. http://otel-collector:4318.")
 	flag.DurationVar(&pollingPeriod, "polling-period", time.Hour, "Periodic interval in which to check certs.")
+	flag.DurationVar(&periodJitter, "period-jitter", 0, "Random delay of up to this duration added to the first scan and to every --polling-period of every checker, so exporters restarted together do not scan in lockstep.")
+	flag.DurationVar(&initialDelay, "initial-delay", 0, "How long every checker waits before its first scan.")
+	flag.IntVar(&listRetries, "list-retries", 3, "How many times a list request to the kubernetes API failing with a transient error is retried within the same scan. 0 disables retrying.")
+	flag.DurationVar(&listRetryBackoff, "list-retry-backoff", time.Second, "How long to wait before the first retry of a failed list request. The wait doubles with every further retry, up to 30s.")
+	flag.DurationVar(&requestTimeout, "request-timeout", 30*time.Second, "Timeout of every request to the kubernetes API, so a hung connection fails the request instead of stalling the scan. 0 disables it.")
 
 	flag.StringVar(&kubeconfigPath, "kubeconfig", "", "Path to a kubeconfig. Only required if out-of-cluster.")
+	flag.StringVar(&kubeContext, "context", "", "Context of the kubeconfig to use instead of its current context.")
+	flag.StringVar(&impersonateUser, "as", "", "User to impersonate in every request to the kubernetes API, e.g. a read-only identity.")
+	flag.Var(&impersonateGroups, "as-group", "Group to impersonate in every request to the kubernetes API. This flag can be repeated to specify multiple groups.")
 	flag.Var(&secretsLabelSelector, "secrets-label-selector", "Label selector to find secrets to publish as metrics.")
-	flag.Var(&secretsAnnotationSelector, "secrets-annotation-selector", "Annotation selector to find secrets to publish as metrics.")
+	flag.Var(&secretsAnnotationSelector, "secrets-annotation-selector", "Annotation selector to find secrets to publish as metrics: an annotation key, or key=value where value may be a glob.")
 	flag.StringVar(&secretsNamespace, "secrets-namespace", "", "Kubernetes namespace to list secrets.")
 	flag.StringVar(&secretsListOfNamespaces, "secrets-namespaces", "", "Kubernetes comma-delimited list of namespaces to search for secrets.")
 	flag.Var(&includeSecretsDataGlobs, "secrets-include-glob", "Secret globs to include when looking for secret data keys (Default \"*\").")
-	flag.Var(&includeSecretsTypes, "secret-include-types", "Select only specific a secret type (Default nil).")
+	flag.Var(&includeSecretsTypes, "secret-include-types", "Select only specific a secret type, or a glob of types like kubernetes.io/* where * also matches / (Default nil).")
+	flag.Var(&excludeSecretsTypes, "secret-exclude-types", "Ignore the secrets of a type, e.g. helm.sh/release.v1, or a glob of types (Default nil).")
 	flag.Var(&excludeSecretsDataGlobs, "secrets-exclude-glob", "Secret globs to exclude when looking for secret data keys.")
+	flag.Var(&candidatePasswords, "candidate-password", "Password tried, in the order given, when a PKCS12 or JKS bundle cannot be opened with its own password. Can be empty.")
+	flag.Int64Var(&secretsArchiveMaxBytes, "secrets-archive-max-bytes", 0, "Expand .tar, .tar.gz and .zip archives in secret data and export the certs of their members, reading at most this many uncompressed bytes per archive. 0 leaves archives alone.")
+	flag.Int64Var(&gzipMaxBytes, "gzip-max-bytes", 10<<20, "Decompress gzip compressed certs, e.g. truststores in the binary data of secrets and configmaps, reading at most this many decompressed bytes. 0 leaves gzip compressed data alone.")
+	flag.BoolVar(&doubleBase64, "double-base64", false, "Base64 decode certs that are not PEM, PKCS12 or JKS once more before giving up, for certs stored base64 encoded in secret data.")
+	flag.BoolVar(&secretsTLSMode, "secrets-tls-mode", false, "Export kubernetes.io/tls secrets by the role of tls.crt, tls.key and ca.crt, ignoring the secret include/exclude globs.")
 
 	flag.Var(&configMapsLabelSelector, "configmaps-label-selector", "Label selector to find configmaps to publish as metrics.")
-	flag.Var(&configMapsAnnotationSelector, "configmaps-annotation-selector", "Annotation selector to find configmaps to publish as metrics.")
+	flag.Var(&configMapsAnnotationSelector, "configmaps-annotation-selector", "Annotation selector to find configmaps to publish as metrics: an annotation key, or key=value where value may be a glob.")
 	flag.StringVar(&configMapsNamespace, "configmaps-namespace", "", "Kubernetes namespace to list configmaps.")
 	flag.StringVar(&configMapsListOfNamespaces, "configmaps-namespaces", "", "Kubernetes comma-delimited list of namespaces to search for configmaps.")
 	flag.Var(&includeConfigMapsDataGlobs, "configmaps-include-glob", "Configmap globs to include when looking for configmap data keys (Default \"*\").")
 	flag.Var(&excludeConfigMapsDataGlobs, "configmaps-exclude-glob", "Configmap globs to exclude when looking for configmap data keys.")
+	flag.Var(&configMapsConfigExtractors, "configmaps-config-extractor", "Application config format to export referenced certs from (prometheus, alertmanager, etcd, haproxy, saml-metadata or jwks).")
+	flag.Var(&configMapsEmbeddedCertPaths, "configmaps-embedded-cert-path", "<key glob>=<path> of PEM certs embedded in the YAML or JSON configs stored under the matching configmap keys, e.g. app.yaml=server.tls.ca.")
+	flag.StringVar(&configMapsConfigFileRoot, "configmaps-config-file-root", "", "Local directory to resolve cert paths referenced from application configs in, when they are not stored in the configmap.")
+
+	flag.BoolVar(&watchNamespaces, "watch-namespaces", false, "Watch namespaces and scan secrets and configmaps in every namespace matching the namespace globs, as they are created and deleted. Ignored by checkers given explicit namespaces.")
+	flag.BoolVar(&namespaceAccessReview, "namespace-access-review", false, "Ask the API server with a SelfSubjectAccessReview whether secrets and configmaps may be listed in a namespace before listing them, and skip the namespaces they may not.")
+	flag.Var(&includeNamespaceGlobs, "namespaces-include-glob", "Namespace globs to include when watching namespaces (Default \"*\").")
+	flag.Var(&excludeNamespaceGlobs, "namespaces-exclude-glob", "Namespace globs to exclude when watching namespaces.")
 
 	flag.BoolVar(&webhookCheckEnabled, "enable-webhook-cert-check", false, "Enable webhook cert check.")
 	flag.Var(&webhooksLabelSelector, "webhooks-label-selector", "Label selector to find webhooks to publish as metrics.")
-	flag.Var(&webhooksAnnotationSelector, "webhooks-annotation-selector", "Annotation selector to find webhooks to publish as metrics.")
+	flag.Var(&webhooksAnnotationSelector, "webhooks-annotation-selector", "Annotation selector to find webhooks to publish as metrics: an annotation key, or key=value where value may be a glob.")
+	flag.BoolVar(&routeCheckEnabled, "enable-route-cert-check", false, "Enable the check of the certs of OpenShift routes.")
+	flag.Var(&routesLabelSelector, "routes-label-selector", "Label selector to find routes to publish as metrics.")
+	flag.Var(&routesAnnotationSelector, "routes-annotation-selector", "Annotation selector to find routes to publish as metrics: an annotation key, or key=value where value may be a glob.")
+	flag.StringVar(&routesListOfNamespaces, "routes-namespaces", "", "Comma-delimited list of namespaces to search for routes in. Defaults to every namespace.")
+	flag.BoolVar(&gatewayCheckEnabled, "enable-gateway-cert-check", false, "Enable the check of the certs referenced by the listeners of Gateway API gateways.")
+	flag.Var(&gatewaysLabelSelector, "gateways-label-selector", "Label selector to find gateways to publish as metrics.")
+	flag.Var(&gatewaysAnnotationSelector, "gateways-annotation-selector", "Annotation selector to find gateways to publish as metrics: an annotation key, or key=value where value may be a glob.")
+	flag.BoolVar(&csrCheckEnabled, "enable-csr-check", false, "Enable the check of CertificateSigningRequests: the certs they were issued and the requests stuck without one.")
+	flag.Var(&csrsLabelSelector, "csrs-label-selector", "Label selector to find CertificateSigningRequests to publish as metrics.")
+	flag.DurationVar(&csrStuckAge, "csr-stuck-age", 15*time.Minute, "CertificateSigningRequests Pending or Denied for longer than this are flagged as stuck.")
+	flag.BoolVar(&bootstrapTokenCheckEnabled, "enable-bootstrap-token-check", false, "Enable the check of the expiration of the bootstrap tokens in kube-system.")
+	flag.Var(&envoyAdminURLs, "envoy-admin-url", "URL of an Envoy admin interface, e.g. http://ingress-gateway:15000, whose in-memory certs are exported.")
+	flag.Var(&envoyPodsLabelSelector, "envoy-pods-label-selector", "Label selector to find the pods running Envoy whose in-memory certs are exported, reached on --envoy-admin-port.")
+	flag.StringVar(&envoyPodsListOfNamespaces, "envoy-pods-namespaces", "", "Comma-delimited list of namespaces to search for Envoy pods in. Defaults to every namespace.")
+	flag.IntVar(&envoyAdminPort, "envoy-admin-port", 15000, "Port of the Envoy admin interface of the pods of --envoy-pods-label-selector.")
+	flag.StringVar(&gatewaysListOfNamespaces, "gateways-namespaces", "", "Comma-delimited list of namespaces to search for gateways in. Defaults to every namespace.")
 
 	flag.StringVar(&awsAccount, "aws-account", "", "AWS account to search for secrets in")
 	flag.StringVar(&awsRegion, "aws-region", "", "AWS region to search for secrets in")
 	flag.Var(&awsSecrets, "aws-secret", "AWS secrets to export")
+
+	flag.IntVar(&minRSAKeySize, "min-rsa-key-size", 2048, "RSA keys smaller than this many bits are flagged by cert_exporter_cert_key_too_small.")
+	flag.IntVar(&minECDSAKeySize, "min-ecdsa-key-size", 256, "ECDSA keys smaller than this many bits are flagged by cert_exporter_cert_key_too_small.")
+	flag.BoolVar(&lifetimeMetricsEnabled, "enable-lifetime-metrics", false, "Export cert_exporter_cert_validity_seconds, cert_exporter_cert_age_seconds and cert_exporter_cert_lifetime_used_ratio for every cert. Adds three series per cert.")
+	flag.BoolVar(&certInfoEnabled, "enable-cert-info-metric", false, "Export cert_exporter_cert_info with the subject, issuer, SANs and serial of every cert. Adds one series per cert.")
+	flag.StringVar(&copyLabels, "copy-labels", "serviceline", "Comma-delimited list of secret and configmap label keys copied onto their metrics. Keys are sanitized into valid label names.")
+	flag.StringVar(&copyAnnotations, "copy-annotations", "", "Comma-delimited list of secret and configmap annotation keys copied onto their metrics. Keys are sanitized into valid label names.")
+	flag.BoolVar(&serialLabelEnabled, "enable-serial-label", false, "Label every expiry metric with the serial number of the cert. Rotating a cert then creates a new series.")
+	flag.BoolVar(&fingerprintLabelEnabled, "enable-fingerprint-label", false, "Label every expiry metric with the SHA-256 fingerprint of the cert.")
+	flag.BoolVar(&subjectLabelEnabled, "enable-subject-label", false, "Label every expiry metric with the full subject distinguished name of the cert, not just its CN.")
+	flag.BoolVar(&skipCACerts, "skip-ca-certs", false, "Ignore self-signed CA certs, e.g. the long-lived roots of bundles.")
+	flag.BoolVar(&leafOnly, "leaf-only", false, "Ignore every CA cert, roots and intermediates alike.")
+	flag.BoolVar(&caOnly, "ca-only", false, "Ignore every cert that is not a CA.")
+	flag.BoolVar(&verifyChains, "verify-chains", false, "Verify the chain of every leaf cert and export cert_exporter_cert_verified.")
+	flag.BoolVar(&checkChainCompleteness, "check-chain-completeness", false, "Export cert_exporter_cert_chain_incomplete telling whether the certs stored next to every leaf cert complete its chain up to a trusted root.")
+	flag.BoolVar(&checkCT, "check-ct", false, "Export cert_exporter_cert_ct_logged telling whether every leaf cert embeds Certificate Transparency timestamps.")
+	flag.StringVar(&verifyCABundle, "verify-ca-bundle", "", "PEM bundle of the CAs chains are verified against with --verify-chains and --check-chain-completeness (Default: the system roots).")
+	flag.IntVar(&maxSeriesPerNamespace, "max-series-per-namespace", 0, "Maximum number of series exported per namespace by each of the secret and configmap checkers. 0 means unlimited.")
+	flag.IntVar(&maxSeriesPerObject, "max-series-per-object", 0, "Maximum number of series exported per secret or configmap. 0 means unlimited.")
+	flag.IntVar(&maxCertsPerKey, "max-certs-per-key", 0, "Maximum number of certs exported per secret or configmap data key. Only the soonest expiring certs are exported. 0 means unlimited.")
+	flag.Float64Var(&maxParseFailureRatio, "ready-max-parse-failure-ratio", 0, "Fail /readyz while the last scan of the secret or configmap checker could not parse more than this ratio of its data keys, e.g. 0.5. 0 disables the check.")
+	flag.StringVar(&dropLabels, "drop-labels", "", "Comma-delimited list of labels removed from every metric, e.g. cn,issuer. Series left with the same labels are merged.")
+	flag.StringVar(&hashLabels, "hash-labels", "", "Comma-delimited list of labels whose values are replaced with a short hash in every metric, e.g. cn.")
+	flag.StringVar(&pretendNow, "pretend-now", "", "RFC3339 timestamp to compute expiry metrics against instead of the current time. Intended for fire drills only.")
+	flag.BoolVar(&runOnce, "run-once", false, "Run a single scan, publish the results and exit with code 1 if any cert expires within --warning-days. Intended for CronJobs and CI pipelines.")
+	flag.StringVar(&runOnceOutputFile, "run-once-output-file", "", "File to write the metrics of --run-once to, in the Prometheus text format.")
+	flag.StringVar(&pushgatewayURL, "pushgateway-url", "", "Pushgateway to push the metrics of --run-once to.")
+	flag.StringVar(&remoteWriteURL, "remote-write-url", "", "Prometheus remote_write endpoint every metric is pushed to after each scan.")
+	flag.StringVar(&remoteWriteBearerTokenFile, "remote-write-bearer-token-file", "", "File holding the bearer token sent to --remote-write-url.")
+	flag.BoolVar(&annotateObjects, "annotate-objects", false, "Annotate every secret and configmap holding certs with cert-exporter.io/not-after and cert-exporter.io/days-remaining.")
+	flag.IntVar(&renewCertManagerDays, "renew-cert-manager-days", 0, "Trigger the renewal of the cert-manager Certificates of secrets holding certs expiring within this many days. 0 disables renewals.")
+	flag.StringVar(&rotationHistoryConfigMap, "rotation-history-configmap", "", "<namespace>/<name> of a configmap to persist when the serial of every cert changed in, exporting cert_exporter_cert_last_rotation_timestamp and cert_exporter_cert_rotations.")
+	flag.BoolVar(&restartOnRotation, "restart-on-rotation", false, "Restart the Deployments and StatefulSets annotated with cert-exporter.io/restart-on-rotation=true when a secret they mount rotates.")
+	flag.BoolVar(&certificateReports, "certificate-reports", false, "Maintain a CertificateReport custom resource summarizing the certs of every namespace after each scan.")
+	flag.StringVar(&dogStatsDAddress, "dogstatsd-address", "", "host:port of a DogStatsD agent every expiry gauge is sent to after each scan.")
+	flag.StringVar(&remoteWriteCAFile, "remote-write-ca-file", "", "PEM bundle of the CAs trusted for --remote-write-url. Defaults to the system roots.")
+	flag.IntVar(&warningDays, "warning-days", 30, "Certs expiring within this many days are flagged by cert_exporter_cert_warning, fail --run-once and are counted as expiring in CertificateReports.")
+	flag.IntVar(&criticalDays, "critical-days", 7, "Certs expiring within this many days are flagged by cert_exporter_cert_critical.")
+	flag.BoolVar(&showVersion, "version", false, "Print the version and exit.")
+	flag.StringVar(&clusterName, "cluster-name", os.Getenv("CLUSTER_NAME"), "Name of the cluster cert-exporter runs in, added as the cluster label of every metric. Defaults to $CLUSTER_NAME.")
+	flag.StringVar(&metricsConstLabels, "metrics-const-labels", "", "Comma separated key=value labels added to every metric, e.g. environment=prod,region=eu-west-1.")
+	flag.StringVar(&metricsPrefix, "metrics-prefix", "cert_exporter", "Prefix of the name of every metric, replacing cert_exporter.")
+	flag.StringVar(&configFile, "config", "", "Path to a config file defining additional named scan profiles, trust bundles and clusters.")
+	flag.BoolVar(&readOnly, "read-only", false, "Refuse to start any feature that writes to the cluster and reject every kubernetes request other than get, list and watch.")
+	flag.BoolVar(&noEgress, "no-egress", false, "Refuse to start any feature that makes calls outside of the cluster (currently AWS Secrets Manager and the Pushgateway).")
 }
 
 func main() {
+	listMode := false
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "check":
+			os.Exit(runCheck(os.Args[2:]))
+		case "list":
+			listMode = true
+			flag.StringVar(&listOutput, "output", "table", "Format of the inventory: table, json or csv.")
+			os.Args = append(os.Args[:1], os.Args[2:]...)
+		}
+	}
+
 	flag.Parse()
+
+	if showVersion {
+		fmt.Printf("cert-exporter %s (commit %s; date %s; %s)\n", version, commit, date, runtime.Version())
+		return
+	}
+
+	// list is a single scan printing its results instead of publishing them
+	if listMode {
+		runOnce = true
+		if listOutput != "table" && listOutput != "json" && listOutput != "csv" {
+			glog.Fatalf("Unknown --output %q. Supported formats are table, json and csv", listOutput)
+		}
+	}
+
+	cfg := &config.Config{}
+	if configFile != "" {
+		var err error
+		cfg, err = config.Load(configFile)
+		if err != nil {
+			glog.Fatalf("Invalid --config %q: %v", configFile, err)
+		}
+	}
+
+	// optional labels change the expiry metrics, so they are set before the metrics are created
+	if serialLabelEnabled {
+		exporters.EnableSerialLabel()
+	}
+	if fingerprintLabelEnabled {
+		exporters.EnableFingerprintLabel()
+	}
+	if subjectLabelEnabled {
+		exporters.EnableSubjectLabel()
+	}
+	for _, name := range splitList(preset) {
+		p, err := exporters.LookupPreset(name, presetRoot)
+		if err != nil {
+			glog.Fatalf("Invalid --preset: %v", err)
+		}
+		includeCertGlobs = append(includeCertGlobs, p.CertGlobs...)
+		includeKubeConfigGlobs = append(includeKubeConfigGlobs, p.KubeConfigGlobs...)
+		exporters.EnablePreset(p)
+	}
+	if len(cfg.Profiles) > 0 {
+		exporters.EnableProfileLabel()
+	}
+	if len(cfg.Clusters) > 0 {
+		exporters.EnableClusterLabel()
+	}
+	if clusterName != "" {
+		exporters.SetClusterName(clusterName)
+	}
+	flagCopyLabels := append([]string{}, splitList(copyLabels)...)
+	copiedLabels := flagCopyLabels
+	for _, profile := range cfg.Profiles {
+		for _, key := range profile.CopyLabels {
+			if !contains(copiedLabels, key) {
+				copiedLabels = append(copiedLabels, key)
+			}
+		}
+	}
+	if err := exporters.SetCopiedLabels(copiedLabels, splitList(copyAnnotations)); err != nil {
+		glog.Fatalf("Invalid copied labels %q or --copy-annotations %q: %v", copiedLabels, copyAnnotations, err)
+	}
+	constLabels := map[string]string{}
+	for _, pair := range splitList(metricsConstLabels) {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			glog.Fatalf("Invalid --metrics-const-labels %q: %q is not key=value", metricsConstLabels, pair)
+		}
+		constLabels[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+	}
+	if err := metrics.SetConstLabels(constLabels); err != nil {
+		glog.Fatalf("Invalid --metrics-const-labels %q: %v", metricsConstLabels, err)
+	}
+	if err := metrics.SetMetricPrefix(metricsPrefix); err != nil {
+		glog.Fatalf("Invalid --metrics-prefix: %v", err)
+	}
+	if err := metrics.SetDroppedLabels(splitList(dropLabels)); err != nil {
+		glog.Fatalf("Invalid --drop-labels: %v", err)
+	}
+	if err := metrics.SetHashedLabels(splitList(hashLabels)); err != nil {
+		glog.Fatalf("Invalid --hash-labels: %v", err)
+	}
 	metrics.Init(prometheusExporterMetricsDisabled)
+	if goCollectorDisabled {
+		metrics.DisableGoCollector()
+	}
+	if processCollectorDisabled {
+		metrics.DisableProcessCollector()
+	}
 
 	glog.Infof("Starting cert-exporter (version %s; commit %s; date %s)", version, commit, date)
+	metrics.BuildInfo.WithLabelValues(version, commit, runtime.Version()).Set(1)
+
+	exporters.SetMinimumKeySizes(minRSAKeySize, minECDSAKeySize)
+	if criticalDays > warningDays {
+		glog.Fatalf("--critical-days %d must not exceed --warning-days %d", criticalDays, warningDays)
+	}
+	exporters.SetThresholds(time.Duration(warningDays)*24*time.Hour, time.Duration(criticalDays)*24*time.Hour)
+	metrics.SetMaxSeriesPerNamespace(maxSeriesPerNamespace)
+	metrics.SetMaxSeriesPerObject(maxSeriesPerObject)
+	exporters.SetMaxCertsPerKey(maxCertsPerKey)
+	metrics.SetMaxParseFailureRatio(maxParseFailureRatio)
+	if secretsTLSMode {
+		checkers.EnableTLSSecretMode()
+	}
+	if doubleBase64 {
+		exporters.EnableDoubleBase64()
+	}
+	exporters.SetGzipMaxBytes(gzipMaxBytes)
+	if secretsArchiveMaxBytes > 0 {
+		exporters.EnableArchiveExpansion(secretsArchiveMaxBytes)
+	}
+	exporters.SetCandidatePasswords(candidatePasswords)
+	switch {
+	case boolCount(skipCACerts, leafOnly, caOnly) > 1:
+		glog.Fatal("Only one of --skip-ca-certs, --leaf-only and --ca-only can be set")
+	case skipCACerts:
+		exporters.SkipRootCerts()
+	case leafOnly:
+		exporters.KeepLeafCertsOnly()
+	case caOnly:
+		exporters.KeepCACertsOnly()
+	}
+	if certInfoEnabled {
+		exporters.EnableCertInfo()
+	}
+	if lifetimeMetricsEnabled {
+		exporters.EnableLifetimeMetrics()
+	}
+	if checkCT {
+		exporters.EnableCTCheck()
+	}
+	if verifyChains || checkChainCompleteness {
+		roots := loadCABundle(verifyCABundle)
+		if verifyChains {
+			exporters.EnableChainVerification(roots)
+		}
+		if checkChainCompleteness {
+			exporters.EnableChainCompletenessCheck(roots)
+		}
+		for _, b := range cfg.TrustBundles {
+			exporters.AddTrustBundle(b.Namespaces, b.Annotations, loadCABundle(b.CABundle))
+		}
+	} else if len(cfg.TrustBundles) > 0 {
+		glog.Warning("trustBundles are ignored without --verify-chains or --check-chain-completeness")
+	}
+
+	if pretendNow != "" {
+		t, err := time.Parse(time.RFC3339, pretendNow)
+		if err != nil {
+			glog.Fatalf("Invalid --pretend-now %q: %v", pretendNow, err)
+		}
+
+		exporters.SetPretendNow(t)
+		metrics.TimeOffsetSeconds.Set(exporters.TimeOffset().Seconds())
+		glog.Warningf("Computing expiry metrics as if the current time were %v. Do not run this in production.", t)
+	}
+
+	if noEgress {
+		glog.Info("Running in no-egress mode. All outbound features are disabled.")
+		metrics.EgressModeInfo.WithLabelValues("no-egress").Set(1)
+		for _, feature := range outboundFeatures {
+			metrics.EgressFeatureEnabled.WithLabelValues(feature).Set(0)
+		}
+	} else {
+		metrics.EgressModeInfo.WithLabelValues("default").Set(1)
+	}
+
+	if len(impersonateGroups) > 0 && impersonateUser == "" {
+		glog.Fatal("--as-group requires --as")
+	}
+	checkers.SetKubeConfigOverrides(kubeconfigPath, kubeContext, impersonateUser, impersonateGroups)
+	checkers.SetListRetries(listRetries, listRetryBackoff)
+	checkers.SetRequestTimeout(requestTimeout)
+	checkers.SetSchedule(initialDelay, periodJitter)
+
+	if readOnly {
+		glog.Info("Running in read-only mode. All features writing to the cluster are disabled.")
+		checkers.SetReadOnly()
+		metrics.ReadOnlyMode.Set(1)
+	}
+
+	if runOnce {
+		checkers.SetRunOnce()
+	}
+	if runOnce && !listMode {
+		if runOnceOutputFile != "" {
+			outputs.Attach("textfile", textfileWriter{path: runOnceOutputFile})
+		}
+		if pushgatewayURL != "" && egressAllowed("pushgateway") {
+			outputs.Attach("pushgateway", pushgatewayWriter{url: pushgatewayURL})
+		}
+	}
+
+	if remoteWriteURL != "" && egressAllowed("remote-write") {
+		outputs.Attach("remote-write", newRemoteWriter(remoteWriteURL, remoteWriteBearerTokenFile, remoteWriteCAFile))
+	}
+	if dogStatsDAddress != "" && egressAllowed("dogstatsd") {
+		outputs.Attach("dogstatsd", newDogStatsDWriter(dogStatsDAddress))
+	}
+	for _, o := range cfg.Outputs {
+		if contains(outboundFeatures, o.Name) && !egressAllowed(o.Name) {
+			continue
+		}
+		output, err := outputs.New(o.Name, o.Settings)
+		if err != nil {
+			glog.Fatalf("Invalid output %v in --config %q: %v", o.Name, configFile, err)
+		}
+		outputs.Attach(o.Name, output)
+	}
+	if otlpEndpoint != "" && egressAllowed("otlp-tracing") {
+		tracing.Enable(otlpEndpoint, "cert-exporter")
+	}
+	if annotateObjects && writeAllowed("object-annotations") {
+		useCapabilities("object-annotations")
+		checkers.EnableObjectAnnotations()
+	}
+	if renewCertManagerDays > 0 && writeAllowed("cert-manager-renewal") {
+		useCapabilities("cert-manager-renewal")
+		checkers.EnableCertManagerRenewal(time.Duration(renewCertManagerDays) * 24 * time.Hour)
+	}
+	if restartOnRotation && writeAllowed("rollout-restarts") {
+		useCapabilities("rollout-restarts")
+		checkers.EnableRolloutRestarts()
+	}
+	if rotationHistoryConfigMap != "" && writeAllowed("rotation-history") {
+		parts := strings.SplitN(rotationHistoryConfigMap, "/", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			glog.Fatalf("--rotation-history-configmap %q is not <namespace>/<name>", rotationHistoryConfigMap)
+		}
+		useCapabilities("rotation-history")
+		err := exporters.EnableRotationHistory(checkers.NewConfigMapRotationStore(kubeconfigPath, parts[0], parts[1]))
+		if err != nil {
+			glog.Fatalf("Error loading the rotation history from %v: %v", rotationHistoryConfigMap, err)
+		}
+		checkers.OnScanFinished(exporters.SaveRotationHistory)
+	}
+	if certificateReports && writeAllowed("certificate-reports") {
+		useCapabilities("certificate-reports")
+		checkers.OnScanFinished(checkers.NewCertificateReportWriter(kubeconfigPath, time.Duration(warningDays)*24*time.Hour).Write)
+	}
+
+	if reportFile != "" {
+		outputs.Attach("report-file", reportFileWriter{path: reportFile})
+	}
+
+	var inventory *inventoryService
+	if grpcListenAddress != "" && !runOnce {
+		inventory = newInventoryService(float64(warningDays) * 24 * 60 * 60)
+		checkers.OnScanFinished(inventory.scanFinished)
+	}
+
+	if namespaceAccessReview {
+		useCapabilities("namespace-access-review")
+		checkers.EnableNamespaceAccessReview()
+	}
+
+	var namespaceWatcher *checkers.NamespaceWatcher
+	if watchNamespaces {
+		if len(includeNamespaceGlobs) == 0 {
+			includeNamespaceGlobs = args.GlobArgs([]string{"*"})
+		}
+
+		useCapabilities("namespace-watcher")
+		namespaceWatcher = checkers.NewNamespaceWatcher(includeNamespaceGlobs, excludeNamespaceGlobs, kubeconfigPath)
+		namespaceWatcher.StartWatching()
+	}
+
+	if watchFiles {
+		checkers.EnableFileWatching()
+	}
+
+	if (len(includeCertGlobs) > 0 || len(includeKubeConfigGlobs) > 0 || kubeletPKIDir != "" || windowsCertStores != "") && nodeName == "" {
+		glog.Warning("The nodename label of file metrics is empty without --node-name or $NODE_NAME")
+	}
 
 	if len(includeCertGlobs) > 0 {
-		certChecker := checkers.NewCertChecker(pollingPeriod, includeCertGlobs, excludeCertGlobs, os.Getenv("NODE_NAME"), &exporters.CertExporter{})
-		go certChecker.StartChecking()
+		var passwords []exporters.FilePassword
+		for _, value := range certPasswords {
+			password, err := exporters.ParseFilePassword(value)
+			if err != nil {
+				glog.Fatalf("Invalid --cert-password: %v", err)
+			}
+			passwords = append(passwords, password)
+		}
+
+		certChecker := checkers.NewCertChecker(pollingPeriod, includeCertGlobs, excludeCertGlobs, nodeName, &exporters.CertExporter{Passwords: passwords})
+		if len(driftSecrets) > 0 {
+			var drifts []exporters.SecretDrift
+			for _, value := range driftSecrets {
+				drift, err := exporters.ParseSecretDrift(value)
+				if err != nil {
+					glog.Fatalf("Invalid --drift-secret: %v", err)
+				}
+				drifts = append(drifts, drift)
+			}
+
+			useCapabilities("secret-drift")
+			certChecker.SetDriftSecrets(drifts, kubeconfigPath)
+		}
+		startChecker(certChecker)
+	}
+
+	if kubeletPKIDir != "" {
+		kubeletGlobs := []string{filepath.Join(kubeletPKIDir, "kubelet-client-current.pem"), filepath.Join(kubeletPKIDir, "kubelet-server-current.pem")}
+		kubeletChecker := checkers.NewCertChecker(pollingPeriod, kubeletGlobs, nil, nodeName, &exporters.KubeletExporter{})
+		startChecker(kubeletChecker)
+	}
+
+	if windowsCertStores != "" {
+		if runtime.GOOS != "windows" {
+			glog.Fatal("--windows-cert-stores is only supported on Windows")
+		}
+		windowsChecker := checkers.NewWindowsCertStoreChecker(pollingPeriod, splitList(windowsCertStores), nodeName, &exporters.WindowsCertStoreExporter{})
+		startChecker(windowsChecker)
 	}
 
 	if len(includeKubeConfigGlobs) > 0 {
-		configChecker := checkers.NewCertChecker(pollingPeriod, includeKubeConfigGlobs, excludeKubeConfigGlobs, os.Getenv("NODE_NAME"), &exporters.KubeConfigExporter{})
-		go configChecker.StartChecking()
+		configChecker := checkers.NewCertChecker(pollingPeriod, includeKubeConfigGlobs, excludeKubeConfigGlobs, nodeName, &exporters.KubeConfigExporter{})
+		startChecker(configChecker)
 	}
 
 	if len(secretsLabelSelector) > 0 || len(secretsAnnotationSelector) > 0 || len(includeSecretsDataGlobs) > 0 {
@@ -112,14 +645,20 @@ func main() {
 		}
 		secretsNamespaces := getSanitizedNamespaceList(secretsListOfNamespaces, secretsNamespace)
 
-		configChecker := checkers.NewSecretChecker(pollingPeriod, secretsLabelSelector, includeSecretsDataGlobs, excludeSecretsDataGlobs, secretsAnnotationSelector, secretsNamespaces, kubeconfigPath, &exporters.SecretExporter{}, includeSecretsTypes)
-		go configChecker.StartChecking()
+		useCapabilities("secrets")
+		configChecker := checkers.NewSecretChecker(pollingPeriod, secretsLabelSelector, includeSecretsDataGlobs, excludeSecretsDataGlobs, secretsAnnotationSelector, secretsNamespaces, kubeconfigPath, &exporters.SecretExporter{CopyLabels: flagCopyLabels}, includeSecretsTypes)
+		configChecker.SetExcludeSecretsTypes(excludeSecretsTypes)
+		if namespaceWatcher != nil && secretsListOfNamespaces == "" && secretsNamespace == "" {
+			configChecker.SetNamespaceWatcher(namespaceWatcher)
+		}
+		startChecker(configChecker)
 	}
 
-	if len(awsAccount) > 0 && len(awsRegion) > 0 && len(awsSecrets) > 0 {
+	if len(awsAccount) > 0 && len(awsRegion) > 0 && len(awsSecrets) > 0 && egressAllowed("aws-secrets-manager") {
 		glog.Infof("Starting check for AWS Secrets Manager in Account %s and Region %s and Secrets %s", awsAccount, awsRegion, awsSecrets)
+		useCapabilities("aws-secrets-manager")
 		awsChecker := checkers.NewAwsChecker(awsAccount, awsRegion, awsSecrets, pollingPeriod, &exporters.AwsExporter{})
-		go awsChecker.StartChecking()
+		startChecker(awsChecker)
 	}
 
 	if len(configMapsLabelSelector) > 0 || len(configMapsAnnotationSelector) > 0 || len(includeConfigMapsDataGlobs) > 0 {
@@ -128,38 +667,339 @@ func main() {
 		}
 		configMapsNamespaces := getSanitizedNamespaceList(configMapsListOfNamespaces, configMapsNamespace)
 
-		configChecker := checkers.NewConfigMapChecker(pollingPeriod, configMapsLabelSelector, includeConfigMapsDataGlobs, excludeConfigMapsDataGlobs, configMapsAnnotationSelector, configMapsNamespaces, kubeconfigPath, &exporters.ConfigMapExporter{})
-		go configChecker.StartChecking()
+		for _, format := range configMapsConfigExtractors {
+			if !contains(appconfig.Formats, format) {
+				glog.Fatalf("Unknown --configmaps-config-extractor %q. Supported formats are %v", format, appconfig.Formats)
+			}
+		}
+
+		embeddedCertPaths, err := appconfig.ParseEmbeddedCertPaths(configMapsEmbeddedCertPaths...)
+		if err != nil {
+			glog.Fatalf("Invalid --configmaps-embedded-cert-path: %v", err)
+		}
+
+		useCapabilities("configmaps")
+		configChecker := checkers.NewConfigMapChecker(pollingPeriod, configMapsLabelSelector, includeConfigMapsDataGlobs, excludeConfigMapsDataGlobs, configMapsAnnotationSelector, configMapsNamespaces, kubeconfigPath, &exporters.ConfigMapExporter{ConfigFileRoot: configMapsConfigFileRoot, CopyLabels: flagCopyLabels}, configMapsConfigExtractors)
+		configChecker.SetEmbeddedCertPaths(embeddedCertPaths)
+		if namespaceWatcher != nil && configMapsListOfNamespaces == "" && configMapsNamespace == "" {
+			configChecker.SetNamespaceWatcher(namespaceWatcher)
+		}
+		startChecker(configChecker)
+	}
+
+	for _, profile := range cfg.Profiles {
+		startProfile(profile, namespaceWatcher)
+	}
+	for _, cluster := range cfg.Clusters {
+		startCluster(cluster, flagCopyLabels)
 	}
 
 	if webhookCheckEnabled {
+		useCapabilities("webhooks")
 		configChecker := checkers.NewWebhookChecker(pollingPeriod, webhooksLabelSelector, webhooksAnnotationSelector, kubeconfigPath, &exporters.WebhookExporter{})
-		go configChecker.StartChecking()
+		startChecker(configChecker)
+	}
+
+	if routeCheckEnabled {
+		useCapabilities("routes")
+		routeChecker := checkers.NewRouteChecker(pollingPeriod, routesLabelSelector, routesAnnotationSelector, getSanitizedNamespaceList(routesListOfNamespaces, ""), kubeconfigPath, &exporters.RouteExporter{})
+		startChecker(routeChecker)
+	}
+
+	if gatewayCheckEnabled {
+		useCapabilities("gateways")
+		gatewayChecker := checkers.NewGatewayChecker(pollingPeriod, gatewaysLabelSelector, gatewaysAnnotationSelector, getSanitizedNamespaceList(gatewaysListOfNamespaces, ""), kubeconfigPath, &exporters.GatewayExporter{})
+		startChecker(gatewayChecker)
 	}
 
-	handler := promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{})
+	if csrCheckEnabled {
+		useCapabilities("csrs")
+		csrChecker := checkers.NewCSRChecker(pollingPeriod, csrsLabelSelector, csrStuckAge, kubeconfigPath, &exporters.CSRExporter{})
+		startChecker(csrChecker)
+	}
 
-	if !prometheusExporterMetricsDisabled {
-		handler = promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, handler)
+	if bootstrapTokenCheckEnabled {
+		useCapabilities("bootstrap-tokens")
+		bootstrapTokenChecker := checkers.NewBootstrapTokenChecker(pollingPeriod, kubeconfigPath, &exporters.BootstrapTokenExporter{})
+		startChecker(bootstrapTokenChecker)
+	}
+
+	if (len(envoyAdminURLs) > 0 || len(envoyPodsLabelSelector) > 0) && egressAllowed("envoy-admin") {
+		if len(envoyPodsLabelSelector) > 0 {
+			useCapabilities("envoy-pods")
+		}
+		envoyChecker := checkers.NewEnvoyChecker(pollingPeriod, envoyAdminURLs, envoyPodsLabelSelector, getSanitizedNamespaceList(envoyPodsListOfNamespaces, ""), envoyAdminPort, kubeconfigPath, &exporters.EnvoyExporter{})
+		startChecker(envoyChecker)
+	}
+
+	if runOnce {
+		checkersRunning.Wait()
+		if listMode {
+			os.Exit(printInventory())
+		}
+		os.Exit(finishRunOnce())
+	}
+
+	scrape := newScrapeEndpoint(prometheusExporterMetricsDisabled)
+	outputs.Attach("prometheus", scrape)
+
+	mux := http.NewServeMux()
+	mux.Handle(prometheusPath, scrape)
+	mux.HandleFunc("/debug/cardinality", metrics.CardinalityHandler)
+	mux.HandleFunc("/report", serveReport)
+	if uiPath != "" {
+		mux.HandleFunc(uiPath, serveUI)
+	}
+	if pprofEnabled {
+		if pprofListenAddress == "" {
+			mux.Handle("/debug/pprof/", pprofHandler())
+		} else {
+			go func() {
+				log.Fatal(http.ListenAndServe(pprofListenAddress, pprofHandler()))
+			}()
+		}
+	}
+
+	if authBearerTokenFile != "" {
+		authenticators = append(authenticators, bearerTokenAuthenticator(readSecretFile(authBearerTokenFile)))
+	}
+	if authBasicUsername != "" {
+		if authBasicPasswordFile == "" {
+			glog.Fatal("--auth-basic-username requires --auth-basic-password-file")
+		}
+		authenticators = append(authenticators, basicAuthenticator(authBasicUsername, readSecretFile(authBasicPasswordFile)))
+	}
+	if authTokenReview {
+		useCapabilities("token-review")
+		authenticators = append(authenticators, tokenReviewAuthenticator(kubeconfigPath))
 	}
 
-	http.Handle(prometheusPath, handler)
-	log.Fatal(http.ListenAndServe(prometheusListenAddress, nil))
+	// /readyz is served without authentication, so kubelet can probe it
+	rootMux := http.NewServeMux()
+	rootMux.HandleFunc("/readyz", metrics.ReadyzHandler)
+	rootMux.Handle("/", requireAuthentication(mux))
+
+	if inventory != nil {
+		go serveGRPC(grpcListenAddress, inventory)
+	}
+
+	if tlsCertFile == "" && tlsKeyFile == "" {
+		log.Fatal(http.ListenAndServe(prometheusListenAddress, rootMux))
+	}
+
+	reloader, err := newCertReloader(tlsCertFile, tlsKeyFile)
+	if err != nil {
+		glog.Fatalf("Error loading --tls-cert-file %q and --tls-key-file %q: %v", tlsCertFile, tlsKeyFile, err)
+	}
+	server := &http.Server{
+		Addr:      prometheusListenAddress,
+		Handler:   rootMux,
+		TLSConfig: &tls.Config{GetCertificate: reloader.GetCertificate, MinVersion: tls.VersionTLS12},
+	}
+	log.Fatal(server.ListenAndServeTLS("", ""))
 }
 
-// Get the trimmed and sanitized list of namespaces
-func getSanitizedNamespaceList(rawListOfNamespaces, namespace string) []string {
-	var selected []string
-
-	if len(rawListOfNamespaces) > 0 {
-		provided := strings.Split(rawListOfNamespaces, ",")
-		for _, v := range provided {
-			v = strings.TrimSpace(v)
-			if v != "" {
-				selected = append(selected, v)
+// checkersRunning counts the checkers that have not returned yet.  They only return with --run-once.
+var checkersRunning sync.WaitGroup
+
+func startChecker(checker interface{ StartChecking() }) {
+	checkersRunning.Add(1)
+	go func() {
+		defer checkersRunning.Done()
+		checker.StartChecking()
+	}()
+}
+
+// finishRunOnce returns the exit code of a --run-once scan: 1 if any cert expires within --warning-days, 0 otherwise.
+// Its metrics were already written by the outputs after the scan of every checker.
+func finishRunOnce() int {
+	expiring, err := metrics.ExpiringSeries(time.Duration(warningDays) * 24 * time.Hour)
+	if err != nil {
+		glog.Fatalf("Error gathering metrics: %v", err)
+	}
+	for _, series := range expiring {
+		glog.Warningf("Expires within %d days: %s", warningDays, series)
+	}
+	tracing.Flush()
+	glog.Flush()
+
+	if len(expiring) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// loadCABundle returns the CAs in a PEM bundle, or nil for the system roots if file is empty
+func loadCABundle(file string) *x509.CertPool {
+	if file == "" {
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		glog.Fatalf("Error reading %v: %v", file, err)
+	}
+
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(data) {
+		glog.Fatalf("No certificate found in %v", file)
+	}
+	return roots
+}
+
+// startProfile starts the secret and configmap checkers of a config profile.  Unset periods and include globs default
+// like their flags do, and profiles without namespaces scan every namespace, or the watched ones.
+func startProfile(profile config.Profile, namespaceWatcher *checkers.NamespaceWatcher) {
+	period := profile.PollingPeriod
+	if period == 0 {
+		period = pollingPeriod
+	}
+	copyLabels := append([]string{}, profile.CopyLabels...)
+
+	glog.Infof("Starting profile %s", profile.Name)
+
+	if s := profile.Secrets; s != nil {
+		useCapabilities("secrets")
+		secretChecker := checkers.NewSecretChecker(period, s.LabelSelectors, profileIncludeGlobs(s), s.ExcludeGlobs, s.AnnotationSelectors, profileNamespaces(s), kubeconfigPath, &exporters.SecretExporter{Profile: profile.Name, CopyLabels: copyLabels}, s.IncludeTypes)
+		secretChecker.SetExcludeSecretsTypes(s.ExcludeTypes)
+		if namespaceWatcher != nil && len(s.Namespaces) == 0 {
+			secretChecker.SetNamespaceWatcher(namespaceWatcher)
+		}
+		startChecker(secretChecker)
+	}
+
+	if c := profile.ConfigMaps; c != nil {
+		useCapabilities("configmaps")
+		configMapChecker := checkers.NewConfigMapChecker(period, c.LabelSelectors, profileIncludeGlobs(c), c.ExcludeGlobs, c.AnnotationSelectors, profileNamespaces(c), kubeconfigPath, &exporters.ConfigMapExporter{ConfigFileRoot: configMapsConfigFileRoot, Profile: profile.Name, CopyLabels: copyLabels}, nil)
+		if namespaceWatcher != nil && len(c.Namespaces) == 0 {
+			configMapChecker.SetNamespaceWatcher(namespaceWatcher)
+		}
+		startChecker(configMapChecker)
+	}
+}
+
+// startCluster starts the secret and configmap checkers of another cluster.  They default like the checkers of a profile
+// do, except that namespaces are never watched as the watcher only knows the namespaces of this cluster.
+func startCluster(cluster config.Cluster, copyLabels []string) {
+	period := cluster.PollingPeriod
+	if period == 0 {
+		period = pollingPeriod
+	}
+	kubeconfig := cluster.Kubeconfig
+	if kubeconfig == "" {
+		kubeconfig = kubeconfigPath
+	}
+
+	glog.Infof("Starting cluster %s", cluster.Name)
+
+	if s := cluster.Secrets; s != nil {
+		useCapabilities("secrets")
+		secretChecker := checkers.NewSecretChecker(period, s.LabelSelectors, profileIncludeGlobs(s), s.ExcludeGlobs, s.AnnotationSelectors, profileNamespaces(s), kubeconfig, &exporters.SecretExporter{Cluster: cluster.Name, CopyLabels: copyLabels}, s.IncludeTypes)
+		secretChecker.SetExcludeSecretsTypes(s.ExcludeTypes)
+		secretChecker.SetKubeContext(cluster.Context)
+		startChecker(secretChecker)
+	}
+
+	if c := cluster.ConfigMaps; c != nil {
+		useCapabilities("configmaps")
+		configMapChecker := checkers.NewConfigMapChecker(period, c.LabelSelectors, profileIncludeGlobs(c), c.ExcludeGlobs, c.AnnotationSelectors, profileNamespaces(c), kubeconfig, &exporters.ConfigMapExporter{Cluster: cluster.Name, CopyLabels: copyLabels}, nil)
+		configMapChecker.SetKubeContext(cluster.Context)
+		startChecker(configMapChecker)
+	}
+}
+
+func profileIncludeGlobs(s *config.Selection) []string {
+	if len(s.IncludeGlobs) == 0 {
+		return []string{"*"}
+	}
+	return s.IncludeGlobs
+}
+
+func profileNamespaces(s *config.Selection) []string {
+	if len(s.Namespaces) == 0 {
+		return []string{""}
+	}
+	return s.Namespaces
+}
+
+// capabilities lists the API verbs every feature uses, per resource
+var capabilities = map[string]map[string][]string{
+	"secrets":                 {"secrets": {"list", "get"}},
+	"configmaps":              {"configmaps": {"list"}, "secrets": {"get"}},
+	"namespace-watcher":       {"namespaces": {"list", "watch"}},
+	"namespace-access-review": {"selfsubjectaccessreviews": {"create"}},
+	"webhooks":                {"mutatingwebhookconfigurations": {"list"}, "validatingwebhookconfigurations": {"list"}},
+	"aws-secrets-manager":     {"secretsmanager": {"get"}},
+	"token-review":            {"tokenreviews": {"create"}},
+	"certificate-reports":     {"certificatereports": {"get", "create"}, "certificatereports/status": {"update"}},
+	"object-annotations":      {"secrets": {"patch"}, "configmaps": {"patch"}},
+	"cert-manager-renewal":    {"certificates": {"get"}, "certificates/status": {"update"}},
+	"rollout-restarts":        {"deployments": {"list", "patch"}, "statefulsets": {"list", "patch"}},
+	"rotation-history":        {"configmaps": {"get", "create", "update"}},
+	"secret-drift":            {"secrets": {"get"}},
+	"routes":                  {"routes": {"list"}},
+	"gateways":                {"gateways": {"list"}, "secrets": {"get"}},
+	"envoy-pods":              {"pods": {"list"}},
+	"csrs":                    {"certificatesigningrequests": {"list"}},
+	"bootstrap-tokens":        {"secrets": {"list"}},
+}
+
+// readVerbs are the only verbs allowed in read-only mode
+var readVerbs = []string{"get", "list", "watch"}
+
+// useCapabilities exports the verbs a feature is about to use.  In read-only mode the exporter refuses to start if the
+// feature needs anything but reads.
+func useCapabilities(feature string) {
+	for resource, verbs := range capabilities[feature] {
+		for _, verb := range verbs {
+			if readOnly && !contains(readVerbs, verb) {
+				glog.Fatalf("read-only: %s needs to %s %s", feature, verb, resource)
 			}
+			metrics.CapabilityInfo.WithLabelValues(feature, resource, verb).Set(1)
 		}
 	}
+}
+
+// writeAllowed reports whether a feature writing to the cluster may be started.  Every feature that creates or modifies
+// objects (events, annotations, custom resources, remediation hooks) must be gated by this so --read-only is a hard
+// guarantee.
+func writeAllowed(feature string) bool {
+	if readOnly {
+		glog.Warningf("read-only: refusing to start %s", feature)
+		return false
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// outboundFeatures lists every feature that makes calls outside of the cluster.  In no-egress mode all of them are
+// reported as disabled, whether they are configured or not.
+var outboundFeatures = []string{"aws-secrets-manager", "pushgateway", "remote-write", "dogstatsd", "otlp-tracing", "envoy-admin"}
+
+// egressAllowed reports whether an outbound feature may be started and records the decision.  Every feature that
+// reaches outside of the cluster must be gated by this so --no-egress is a hard guarantee.
+func egressAllowed(feature string) bool {
+	if noEgress {
+		glog.Warningf("no-egress: refusing to start %s", feature)
+		metrics.EgressFeatureEnabled.WithLabelValues(feature).Set(0)
+		return false
+	}
+
+	metrics.EgressFeatureEnabled.WithLabelValues(feature).Set(1)
+	return true
+}
+
+// Get the trimmed and sanitized list of namespaces
+func getSanitizedNamespaceList(rawListOfNamespaces, namespace string) []string {
+	selected := splitList(rawListOfNamespaces)
 
 	if len(namespace) > 0 {
 		selected = append(selected, namespace)
@@ -171,3 +1011,26 @@ func getSanitizedNamespaceList(rawListOfNamespaces, namespace string) []string {
 
 	return selected
 }
+
+// boolCount returns the number of values that are true
+func boolCount(values ...bool) int {
+	n := 0
+	for _, v := range values {
+		if v {
+			n++
+		}
+	}
+	return n
+}
+
+// splitList returns the trimmed, non empty values of a comma-delimited list
+func splitList(rawList string) []string {
+	var values []string
+	for _, v := range strings.Split(rawList, ",") {
+		v = strings.TrimSpace(v)
+		if v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
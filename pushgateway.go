@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+
+	"github.com/joe-elliott/cert-exporter/src/outputs"
+)
+
+// pushgatewayWriter pushes every metric to a Pushgateway, replacing the metrics of the cert_exporter job, e.g. for
+// CronJobs running --run-once that are gone before Prometheus scrapes them
+type pushgatewayWriter struct {
+	url string
+}
+
+func init() {
+	outputs.Register("pushgateway", func(settings map[string]string) (outputs.Output, error) {
+		if settings["url"] == "" {
+			return nil, errors.New("pushgateway needs a url")
+		}
+		return pushgatewayWriter{url: settings["url"]}, nil
+	})
+}
+
+// Write pushes the current value of every metric
+func (w pushgatewayWriter) Write() error {
+	err := push.New(w.url, "cert_exporter").Gatherer(prometheus.DefaultGatherer).Push()
+	if err != nil {
+		return fmt.Errorf("%v: %w", w.url, err)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/encoding/protowire"
+
+	"github.com/joe-elliott/cert-exporter/src/outputs"
+)
+
+// remoteWriter pushes every metric to a Prometheus remote_write endpoint, e.g. a central Mimir, for clusters without a
+// local Prometheus
+type remoteWriter struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func init() {
+	outputs.Register("remote-write", func(settings map[string]string) (outputs.Output, error) {
+		if settings["url"] == "" {
+			return nil, errors.New("remote-write needs a url")
+		}
+		return newRemoteWriter(settings["url"], settings["bearerTokenFile"], settings["caFile"]), nil
+	})
+}
+
+func newRemoteWriter(url, bearerTokenFile, caFile string) *remoteWriter {
+	w := &remoteWriter{
+		url: url,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{RootCAs: loadCABundle(caFile), MinVersion: tls.VersionTLS12},
+			},
+		},
+	}
+	if bearerTokenFile != "" {
+		w.token = readSecretFile(bearerTokenFile)
+	}
+	return w
+}
+
+// Write pushes the current value of every metric
+func (w *remoteWriter) Write() error {
+	err := w.push()
+	if err != nil {
+		return fmt.Errorf("%v: %w", w.url, err)
+	}
+	return nil
+}
+
+func (w *remoteWriter) push() error {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		return err
+	}
+
+	body := snappyEncode(writeRequest(families, time.Now()))
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if w.token != "" {
+		req.Header.Set("Authorization", "Bearer "+w.token)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%v: %s", resp.Status, bytes.TrimSpace(message))
+	}
+	return nil
+}
+
+// writeRequest encodes the families as a remote_write WriteRequest protobuf, expanding summaries and histograms into
+// their series like the text format does
+func writeRequest(families []*dto.MetricFamily, now time.Time) []byte {
+	timestamp := now.UnixNano() / int64(time.Millisecond)
+
+	var request []byte
+	add := func(name string, labels []*dto.LabelPair, value float64, extra ...string) {
+		series := map[string]string{"__name__": name}
+		for _, label := range labels {
+			series[label.GetName()] = label.GetValue()
+		}
+		for i := 0; i+1 < len(extra); i += 2 {
+			series[extra[i]] = extra[i+1]
+		}
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, timeSeries(series, value, timestamp))
+	}
+
+	for _, family := range families {
+		name := family.GetName()
+		for _, m := range family.GetMetric() {
+			switch family.GetType() {
+			case dto.MetricType_COUNTER:
+				add(name, m.GetLabel(), m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				add(name, m.GetLabel(), m.GetGauge().GetValue())
+			case dto.MetricType_UNTYPED:
+				add(name, m.GetLabel(), m.GetUntyped().GetValue())
+			case dto.MetricType_SUMMARY:
+				for _, q := range m.GetSummary().GetQuantile() {
+					add(name, m.GetLabel(), q.GetValue(), "quantile", strconv.FormatFloat(q.GetQuantile(), 'g', -1, 64))
+				}
+				add(name+"_sum", m.GetLabel(), m.GetSummary().GetSampleSum())
+				add(name+"_count", m.GetLabel(), float64(m.GetSummary().GetSampleCount()))
+			case dto.MetricType_HISTOGRAM:
+				for _, b := range m.GetHistogram().GetBucket() {
+					add(name+"_bucket", m.GetLabel(), float64(b.GetCumulativeCount()), "le", strconv.FormatFloat(b.GetUpperBound(), 'g', -1, 64))
+				}
+				add(name+"_bucket", m.GetLabel(), float64(m.GetHistogram().GetSampleCount()), "le", "+Inf")
+				add(name+"_sum", m.GetLabel(), m.GetHistogram().GetSampleSum())
+				add(name+"_count", m.GetLabel(), float64(m.GetHistogram().GetSampleCount()))
+			}
+		}
+	}
+	return request
+}
+
+// timeSeries encodes a TimeSeries protobuf with a single sample.  Labels are sorted by name as remote_write requires.
+func timeSeries(labels map[string]string, value float64, timestamp int64) []byte {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var series []byte
+	for _, name := range names {
+		var label []byte
+		label = protowire.AppendTag(label, 1, protowire.BytesType)
+		label = protowire.AppendString(label, name)
+		label = protowire.AppendTag(label, 2, protowire.BytesType)
+		label = protowire.AppendString(label, labels[name])
+
+		series = protowire.AppendTag(series, 1, protowire.BytesType)
+		series = protowire.AppendBytes(series, label)
+	}
+
+	var sample []byte
+	sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+	sample = protowire.AppendFixed64(sample, math.Float64bits(value))
+	sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+	sample = protowire.AppendVarint(sample, uint64(timestamp))
+
+	series = protowire.AppendTag(series, 2, protowire.BytesType)
+	return protowire.AppendBytes(series, sample)
+}
+
+// snappyEncode frames src in the snappy block format remote_write expects, as uncompressed literals.  Metrics are
+// small, so this keeps the exporter free of a compression dependency.
+func snappyEncode(src []byte) []byte {
+	dst := protowire.AppendVarint(nil, uint64(len(src)))
+	for len(src) > 0 {
+		n := len(src)
+		if n > 65536 {
+			n = 65536
+		}
+
+		if n <= 60 {
+			dst = append(dst, byte(n-1)<<2)
+		} else {
+			// tag 61 is a literal whose length - 1 follows in two little endian bytes
+			dst = append(dst, 61<<2, byte(n-1), byte((n-1)>>8))
+		}
+		dst = append(dst, src[:n]...)
+		src = src[n:]
+	}
+	return dst
+}
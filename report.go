@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+	"github.com/joe-elliott/cert-exporter/src/outputs"
+)
+
+var reportFile string
+
+// writeReport writes the inventory as csv or json, for compliance exports and audit evidence
+func writeReport(w io.Writer, format string, entries []metrics.InventoryEntry) error {
+	if format != "csv" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(entries)
+	}
+
+	out := csv.NewWriter(w)
+	out.Write([]string{"namespace", "kind", "name", "key", "cn", "days_left", "issuer", "expired", "cluster", "node"})
+	for _, e := range entries {
+		out.Write([]string{e.Namespace, e.Kind, e.Name, e.Key, e.CN, strconv.Itoa(e.DaysLeft), e.Issuer, strconv.FormatBool(e.Expired), e.Cluster, e.Node})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// serveReport serves the inventory as json, or csv with ?format=csv
+func serveReport(w http.ResponseWriter, r *http.Request) {
+	entries, err := metrics.Inventory()
+	if err != nil {
+		glog.Errorf("Error gathering metrics: %v", err)
+		http.Error(w, "error gathering metrics", http.StatusInternalServerError)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="cert-exporter-report.csv"`)
+	default:
+		http.Error(w, "format must be json or csv", http.StatusBadRequest)
+		return
+	}
+
+	if err := writeReport(w, format, entries); err != nil {
+		glog.Errorf("Error writing report: %v", err)
+	}
+}
+
+// reportFileWriter replaces a file with the current inventory after every scan, as csv if its name ends in .csv and
+// json otherwise
+type reportFileWriter struct {
+	path string
+}
+
+func init() {
+	outputs.Register("report-file", func(settings map[string]string) (outputs.Output, error) {
+		if settings["path"] == "" {
+			return nil, errors.New("report-file needs a path")
+		}
+		return reportFileWriter{path: settings["path"]}, nil
+	})
+}
+
+// Write replaces the file with the current inventory.  The report is written to a temporary file first, so readers
+// never see a partial report.
+func (w reportFileWriter) Write() error {
+	entries, err := metrics.Inventory()
+	if err != nil {
+		return fmt.Errorf("gathering metrics: %w", err)
+	}
+
+	format := "json"
+	if strings.EqualFold(filepath.Ext(w.path), ".csv") {
+		format = "csv"
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(w.path), "."+filepath.Base(w.path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	err = tmp.Chmod(0644)
+	if err == nil {
+		err = writeReport(tmp, format, entries)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), w.path)
+	}
+	if err != nil {
+		return fmt.Errorf("%v: %w", w.path, err)
+	}
+	return nil
+}
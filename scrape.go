@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// scrapeEndpoint is the output Prometheus scrapes the results from, served on --prometheus-path
+type scrapeEndpoint struct {
+	handler http.Handler
+}
+
+func newScrapeEndpoint(exporterMetricsDisabled bool) *scrapeEndpoint {
+	handler := promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{})
+
+	if !exporterMetricsDisabled {
+		handler = promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, handler)
+	}
+	return &scrapeEndpoint{handler: handler}
+}
+
+// Write has nothing to send: Prometheus pulls the results, and every scrape gathers the current value of every metric
+func (e *scrapeEndpoint) Write() error {
+	return nil
+}
+
+// ServeHTTP serves every metric in the exposition format the scraper asks for
+func (e *scrapeEndpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,241 @@
+// Package certexporterv1 holds the messages and the server side of the certexporter.v1 gRPC inventory service defined
+// in proto/certexporter/v1/inventory.proto.  The messages are encoded with protowire rather than generated, so the
+// module does not depend on protoc or grpc-go; they must be kept in sync with the proto file.
+package certexporterv1
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// ListCertsRequest selects the certs returned by ListCerts
+type ListCertsRequest struct {
+	// Namespace restricts the certs to a namespace.  Empty lists every namespace.
+	Namespace string
+}
+
+// ListCertsResponse holds every cert selected by a ListCertsRequest
+type ListCertsResponse struct {
+	Certs []*Cert
+}
+
+// Cert mirrors metrics.InventoryEntry
+type Cert struct {
+	Kind      string
+	Namespace string
+	Name      string
+	Key       string
+	CN        string
+	DaysLeft  int64
+	Issuer    string
+	Cluster   string
+	Node      string
+}
+
+// WatchExpiriesRequest selects the certs streamed by WatchExpiries
+type WatchExpiriesRequest struct {
+	// Namespace restricts the events to a namespace.  Empty watches every namespace.
+	Namespace string
+	// WindowSeconds is how long before expiry certs are reported.  0 uses --warning-days.
+	WindowSeconds int64
+}
+
+// ExpiryEvent reports a cert expiring within the window of a WatchExpiriesRequest
+type ExpiryEvent struct {
+	Cert *Cert
+	// Expired is true once the cert is past its notAfter
+	Expired bool
+}
+
+// Marshal encodes m in the protobuf wire format
+func (m *ListCertsRequest) Marshal() []byte {
+	return appendString(nil, 1, m.Namespace)
+}
+
+// Unmarshal decodes m from the protobuf wire format
+func (m *ListCertsRequest) Unmarshal(b []byte) error {
+	*m = ListCertsRequest{}
+	return unmarshalFields(b, func(num protowire.Number, typ protowire.Type, value []byte, varint uint64) error {
+		if num == 1 && typ == protowire.BytesType {
+			m.Namespace = string(value)
+		}
+		return nil
+	})
+}
+
+// Marshal encodes m in the protobuf wire format
+func (m *ListCertsResponse) Marshal() []byte {
+	var b []byte
+	for _, cert := range m.Certs {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, cert.Marshal())
+	}
+	return b
+}
+
+// Unmarshal decodes m from the protobuf wire format
+func (m *ListCertsResponse) Unmarshal(b []byte) error {
+	*m = ListCertsResponse{}
+	return unmarshalFields(b, func(num protowire.Number, typ protowire.Type, value []byte, varint uint64) error {
+		if num == 1 && typ == protowire.BytesType {
+			cert := &Cert{}
+			if err := cert.Unmarshal(value); err != nil {
+				return err
+			}
+			m.Certs = append(m.Certs, cert)
+		}
+		return nil
+	})
+}
+
+// Marshal encodes m in the protobuf wire format
+func (m *Cert) Marshal() []byte {
+	var b []byte
+	b = appendString(b, 1, m.Kind)
+	b = appendString(b, 2, m.Namespace)
+	b = appendString(b, 3, m.Name)
+	b = appendString(b, 4, m.Key)
+	b = appendString(b, 5, m.CN)
+	b = appendVarint(b, 6, uint64(m.DaysLeft))
+	b = appendString(b, 7, m.Issuer)
+	b = appendString(b, 8, m.Cluster)
+	b = appendString(b, 9, m.Node)
+	return b
+}
+
+// Unmarshal decodes m from the protobuf wire format
+func (m *Cert) Unmarshal(b []byte) error {
+	*m = Cert{}
+	return unmarshalFields(b, func(num protowire.Number, typ protowire.Type, value []byte, varint uint64) error {
+		if typ == protowire.VarintType {
+			if num == 6 {
+				m.DaysLeft = int64(varint)
+			}
+			return nil
+		}
+		if typ != protowire.BytesType {
+			return nil
+		}
+
+		switch num {
+		case 1:
+			m.Kind = string(value)
+		case 2:
+			m.Namespace = string(value)
+		case 3:
+			m.Name = string(value)
+		case 4:
+			m.Key = string(value)
+		case 5:
+			m.CN = string(value)
+		case 7:
+			m.Issuer = string(value)
+		case 8:
+			m.Cluster = string(value)
+		case 9:
+			m.Node = string(value)
+		}
+		return nil
+	})
+}
+
+// Marshal encodes m in the protobuf wire format
+func (m *WatchExpiriesRequest) Marshal() []byte {
+	b := appendString(nil, 1, m.Namespace)
+	return appendVarint(b, 2, uint64(m.WindowSeconds))
+}
+
+// Unmarshal decodes m from the protobuf wire format
+func (m *WatchExpiriesRequest) Unmarshal(b []byte) error {
+	*m = WatchExpiriesRequest{}
+	return unmarshalFields(b, func(num protowire.Number, typ protowire.Type, value []byte, varint uint64) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			m.Namespace = string(value)
+		case num == 2 && typ == protowire.VarintType:
+			m.WindowSeconds = int64(varint)
+		}
+		return nil
+	})
+}
+
+// Marshal encodes m in the protobuf wire format
+func (m *ExpiryEvent) Marshal() []byte {
+	var b []byte
+	if m.Cert != nil {
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendBytes(b, m.Cert.Marshal())
+	}
+	if m.Expired {
+		b = appendVarint(b, 2, 1)
+	}
+	return b
+}
+
+// Unmarshal decodes m from the protobuf wire format
+func (m *ExpiryEvent) Unmarshal(b []byte) error {
+	*m = ExpiryEvent{}
+	return unmarshalFields(b, func(num protowire.Number, typ protowire.Type, value []byte, varint uint64) error {
+		switch {
+		case num == 1 && typ == protowire.BytesType:
+			m.Cert = &Cert{}
+			return m.Cert.Unmarshal(value)
+		case num == 2 && typ == protowire.VarintType:
+			m.Expired = varint != 0
+		}
+		return nil
+	})
+}
+
+// appendString appends a string field, leaving it out if it is empty like proto3 does
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+// appendVarint appends a varint field, leaving it out if it is 0 like proto3 does
+func appendVarint(b []byte, num protowire.Number, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+// unmarshalFields calls field with every field of b.  Length delimited fields are passed as value and varints as
+// varint; fields of other types are skipped, and unknown fields are left to field to ignore.
+func unmarshalFields(b []byte, field func(num protowire.Number, typ protowire.Type, value []byte, varint uint64) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return fmt.Errorf("invalid tag: %w", protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		var value []byte
+		var varint uint64
+		switch typ {
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			varint, n = protowire.ConsumeVarint(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return fmt.Errorf("invalid field %d: %w", num, protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		if typ == protowire.BytesType || typ == protowire.VarintType {
+			if err := field(num, typ, value, varint); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
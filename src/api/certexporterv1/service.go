@@ -0,0 +1,195 @@
+package certexporterv1
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxMessageSize bounds the requests read, like the default of grpc-go
+const maxMessageSize = 4 << 20
+
+// Code is a gRPC status code
+type Code uint32
+
+// The status codes returned by the inventory service
+const (
+	CodeOK                Code = 0
+	CodeCanceled          Code = 1
+	CodeUnknown           Code = 2
+	CodeInvalidArgument   Code = 3
+	CodeResourceExhausted Code = 8
+	CodeUnimplemented     Code = 12
+	CodeInternal          Code = 13
+)
+
+// StatusError is an error returned to the client with its gRPC status code
+type StatusError struct {
+	Code    Code
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("rpc error: code = %d desc = %s", e.Code, e.Message)
+}
+
+// Errorf returns an error the client receives with code
+func Errorf(code Code, format string, args ...interface{}) error {
+	return &StatusError{Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// InventoryServer implements the certexporter.v1.Inventory service
+type InventoryServer interface {
+	// ListCerts returns every cert currently exported
+	ListCerts(ctx context.Context, request *ListCertsRequest) (*ListCertsResponse, error)
+	// WatchExpiries sends the certs expiring within the window of request to stream until its context is done
+	WatchExpiries(request *WatchExpiriesRequest, stream ExpiryStream) error
+}
+
+// ExpiryStream sends the events of a WatchExpiries call
+type ExpiryStream interface {
+	Context() context.Context
+	Send(event *ExpiryEvent) error
+}
+
+// NewInventoryHandler serves server over gRPC.  The handler must be served over HTTP/2, e.g. with TLS or h2c.
+// Messages are never compressed, and compressed requests are rejected.
+func NewInventoryHandler(server InventoryServer) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || !isGRPC(r.Header.Get("Content-Type")) {
+			http.Error(w, "Only gRPC requests are served", http.StatusUnsupportedMediaType)
+			return
+		}
+		if r.ProtoMajor != 2 {
+			http.Error(w, "gRPC requires HTTP/2", http.StatusHTTPVersionNotSupported)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		err := serve(w, r, server)
+		writeStatus(w, err)
+	})
+}
+
+// isGRPC reports whether contentType is one of gRPC with the protobuf codec, but not of gRPC-Web
+func isGRPC(contentType string) bool {
+	return contentType == "application/grpc" || strings.HasPrefix(contentType, "application/grpc+proto") ||
+		strings.HasPrefix(contentType, "application/grpc;")
+}
+
+// serve calls the method of server named by the path of r
+func serve(w http.ResponseWriter, r *http.Request, server InventoryServer) error {
+	switch r.URL.Path {
+	case "/certexporter.v1.Inventory/ListCerts":
+		request := &ListCertsRequest{}
+		if err := readMessage(r.Body, request.Unmarshal); err != nil {
+			return err
+		}
+		response, err := server.ListCerts(r.Context(), request)
+		if err != nil {
+			return err
+		}
+		return writeMessage(w, response.Marshal())
+
+	case "/certexporter.v1.Inventory/WatchExpiries":
+		request := &WatchExpiriesRequest{}
+		if err := readMessage(r.Body, request.Unmarshal); err != nil {
+			return err
+		}
+		return server.WatchExpiries(request, expiryStream{w: w, ctx: r.Context()})
+
+	default:
+		return Errorf(CodeUnimplemented, "unknown method %v", r.URL.Path)
+	}
+}
+
+// expiryStream writes every event as a message of the response
+type expiryStream struct {
+	w   http.ResponseWriter
+	ctx context.Context
+}
+
+func (s expiryStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s expiryStream) Send(event *ExpiryEvent) error {
+	return writeMessage(s.w, event.Marshal())
+}
+
+// readMessage reads the single length-prefixed message of a request and decodes it with unmarshal
+func readMessage(body io.Reader, unmarshal func([]byte) error) error {
+	var prefix [5]byte
+	if _, err := io.ReadFull(body, prefix[:]); err != nil {
+		return Errorf(CodeInvalidArgument, "reading the request: %v", err)
+	}
+	if prefix[0] != 0 {
+		return Errorf(CodeUnimplemented, "compressed requests are not supported")
+	}
+	length := binary.BigEndian.Uint32(prefix[1:])
+	if length > maxMessageSize {
+		return Errorf(CodeResourceExhausted, "request of %d bytes exceeds the maximum of %d", length, maxMessageSize)
+	}
+
+	message := make([]byte, length)
+	if _, err := io.ReadFull(body, message); err != nil {
+		return Errorf(CodeInvalidArgument, "reading the request: %v", err)
+	}
+	if err := unmarshal(message); err != nil {
+		return Errorf(CodeInvalidArgument, "decoding the request: %v", err)
+	}
+	return nil
+}
+
+// writeMessage writes message length-prefixed and uncompressed, and flushes it to the client
+func writeMessage(w http.ResponseWriter, message []byte) error {
+	frame := make([]byte, 5, 5+len(message))
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(message)))
+	if _, err := w.Write(append(frame, message...)); err != nil {
+		return err
+	}
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// writeStatus sends the status of err in the trailers of the response
+func writeStatus(w http.ResponseWriter, err error) {
+	code, message := CodeOK, ""
+	var statusErr *StatusError
+	switch {
+	case err == nil:
+	case errors.As(err, &statusErr):
+		code, message = statusErr.Code, statusErr.Message
+	case errors.Is(err, context.Canceled):
+		code, message = CodeCanceled, err.Error()
+	default:
+		code, message = CodeUnknown, err.Error()
+	}
+
+	w.Header().Set("Grpc-Status", strconv.Itoa(int(code)))
+	if message != "" {
+		w.Header().Set("Grpc-Message", percentEncode(message))
+	}
+}
+
+// percentEncode escapes the bytes of a status message that are not printable ASCII, as gRPC requires
+func percentEncode(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c < ' ' || c > '~' || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,136 @@
+package appconfig
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Embedded is the config_source of certs embedded in the string fields of arbitrary YAML or JSON configs
+const Embedded = "embedded"
+
+// indexPattern matches the list indexes of a field or path, e.g. [0] or [*]
+var indexPattern = regexp.MustCompile(`\[([^]]*)\]`)
+
+// ParseEmbeddedCerts returns the PEM certs embedded in the string fields of a YAML or JSON config matching one of paths.
+// Paths are dot separated fields, e.g. server.tls.ca or clients[*].cert.  Every field and list index is matched as a
+// glob, and a ** field matches any number of fields, so **.ca matches every ca field.
+func ParseEmbeddedCerts(data []byte, paths []string) ([]CertReference, error) {
+	var doc interface{}
+	err := yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []CertReference
+	walkEmbedded("", doc, paths, &refs)
+	return refs, nil
+}
+
+func walkEmbedded(field string, node interface{}, paths []string, refs *[]CertReference) {
+	switch n := node.(type) {
+	case map[interface{}]interface{}:
+		keys := make([]string, 0, len(n))
+		values := map[string]interface{}{}
+		for k, v := range n {
+			keys = append(keys, fmt.Sprint(k))
+			values[fmt.Sprint(k)] = v
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			child := key
+			if field != "" {
+				child = field + "." + key
+			}
+			walkEmbedded(child, values[key], paths, refs)
+		}
+	case []interface{}:
+		for i, v := range n {
+			walkEmbedded(fmt.Sprintf("%v[%v]", field, i), v, paths, refs)
+		}
+	case string:
+		if !strings.Contains(n, "-----BEGIN CERTIFICATE-----") {
+			return
+		}
+		for _, p := range paths {
+			if matchFieldPath(splitFieldPath(p), splitFieldPath(field)) {
+				*refs = append(*refs, CertReference{Field: field, Inline: []byte(n)})
+				return
+			}
+		}
+	}
+}
+
+// splitFieldPath splits a field or path into its fields, turning list indexes into fields of their own
+func splitFieldPath(p string) []string {
+	return strings.Split(indexPattern.ReplaceAllString(p, ".$1"), ".")
+}
+
+func matchFieldPath(pattern, field []string) bool {
+	if len(pattern) == 0 {
+		return len(field) == 0
+	}
+
+	if pattern[0] == "**" {
+		for i := 0; i <= len(field); i++ {
+			if matchFieldPath(pattern[1:], field[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(field) == 0 {
+		return false
+	}
+	if ok, _ := filepath.Match(pattern[0], field[0]); !ok {
+		return false
+	}
+	return matchFieldPath(pattern[1:], field[1:])
+}
+
+// EmbeddedCertPath selects the embedded certs exported from the configs stored under the data keys matching Glob
+type EmbeddedCertPath struct {
+	Glob string
+	Path string
+}
+
+// ParseEmbeddedCertPaths parses comma separated <key glob>=<path> values, as given to --configmaps-embedded-cert-path
+// or the cert-exporter.io/embedded-cert-paths annotation
+func ParseEmbeddedCertPaths(values ...string) ([]EmbeddedCertPath, error) {
+	var paths []EmbeddedCertPath
+	for _, value := range values {
+		for _, entry := range strings.Split(value, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+
+			parts := strings.SplitN(entry, "=", 2)
+			if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+				return nil, fmt.Errorf("embedded cert path %q is not <key glob>=<path>", entry)
+			}
+			if _, err := filepath.Match(parts[0], ""); err != nil {
+				return nil, fmt.Errorf("embedded cert path %q: %w", entry, err)
+			}
+			paths = append(paths, EmbeddedCertPath{Glob: parts[0], Path: parts[1]})
+		}
+	}
+	return paths, nil
+}
+
+// EmbeddedPathsFor returns the paths of the embedded certs to export from the config stored under name
+func EmbeddedPathsFor(paths []EmbeddedCertPath, name string) []string {
+	var matched []string
+	for _, p := range paths {
+		if ok, _ := filepath.Match(p.Glob, name); ok {
+			matched = append(matched, p.Path)
+		}
+	}
+	return matched
+}
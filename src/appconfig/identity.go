@@ -0,0 +1,130 @@
+package appconfig
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// parseSAMLMetadata returns the certs of the X509Certificate elements of SAML metadata, e.g. the signing and encryption
+// certs of an IdP.  The field of a cert names its entity, role descriptor and key use, e.g.
+// https://idp.example.com IDPSSODescriptor.signing[0], or Signature for the cert the metadata itself is signed with.
+func parseSAMLMetadata(data []byte) ([]CertReference, error) {
+	var refs []CertReference
+	var entityID, role, use, field string
+	var inCert bool
+	var certData strings.Builder
+	counts := map[string]int{}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch {
+			case t.Name.Local == "EntityDescriptor":
+				entityID = attr(t, "entityID")
+			case strings.HasSuffix(t.Name.Local, "Descriptor") && t.Name.Local != "KeyDescriptor" && t.Name.Local != "EntitiesDescriptor":
+				role = t.Name.Local
+			case t.Name.Local == "KeyDescriptor":
+				use = attr(t, "use")
+				if use == "" {
+					use = "key"
+				}
+			case t.Name.Local == "X509Certificate":
+				field = strings.TrimSpace(entityID + " " + certContext(role, use))
+				inCert = true
+				certData.Reset()
+			}
+		case xml.CharData:
+			if inCert {
+				certData.Write(t)
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "EntityDescriptor":
+				entityID = ""
+			case role:
+				role = ""
+			case "KeyDescriptor":
+				use = ""
+			case "X509Certificate":
+				inCert = false
+				ref, err := derReference(fmt.Sprintf("%v[%v]", field, counts[field]), certData.String())
+				if err != nil {
+					return nil, err
+				}
+				counts[field]++
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs, nil
+}
+
+// certContext names where in SAML metadata a cert was found: the key use within a role descriptor, or else the
+// signature of the metadata
+func certContext(role, use string) string {
+	if use != "" {
+		return role + "." + use
+	}
+	return "Signature"
+}
+
+func attr(element xml.StartElement, name string) string {
+	for _, a := range element.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// jwks is a JSON Web Key Set, of which only the x5c chains of the keys hold certs
+type jwks struct {
+	Keys []struct {
+		X5C []string `json:"x5c"`
+	} `json:"keys"`
+}
+
+// parseJWKS returns the certs of the x5c chains of a JSON Web Key Set, e.g. the signing keys an OIDC provider publishes.
+// The field of a cert is its index in the set and in the chain, e.g. keys[0].x5c[0].
+func parseJWKS(data []byte) ([]CertReference, error) {
+	var set jwks
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	var refs []CertReference
+	for i, key := range set.Keys {
+		for j, cert := range key.X5C {
+			ref, err := derReference(fmt.Sprintf("keys[%v].x5c[%v]", i, j), cert)
+			if err != nil {
+				return nil, err
+			}
+			refs = append(refs, ref)
+		}
+	}
+	return refs, nil
+}
+
+// derReference returns the base64 encoded DER cert found at field as an inline PEM cert
+func derReference(field, encoded string) (CertReference, error) {
+	der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(encoded), ""))
+	if err != nil {
+		return CertReference{}, fmt.Errorf("%v: %w", field, err)
+	}
+	return CertReference{Field: field, Inline: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}, nil
+}
@@ -0,0 +1,198 @@
+package appconfig
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Supported application config formats
+const (
+	Prometheus   = "prometheus"
+	Alertmanager = "alertmanager"
+	Etcd         = "etcd"
+	HAProxy      = "haproxy"
+	SAMLMetadata = "saml-metadata"
+	JWKS         = "jwks"
+)
+
+// Formats lists every supported application config format
+var Formats = []string{Prometheus, Alertmanager, Etcd, HAProxy, SAMLMetadata, JWKS}
+
+// formatGlobs are the file names each format is usually stored under
+var formatGlobs = map[string][]string{
+	Prometheus:   {"prometheus.yml", "prometheus.yaml", "prometheus.yml.tmpl", "prometheus.yaml.tmpl"},
+	Alertmanager: {"alertmanager.yml", "alertmanager.yaml"},
+	Etcd:         {"etcd.conf.yml", "etcd.conf.yaml", "etcd.yml", "etcd.yaml"},
+	HAProxy:      {"haproxy.cfg", "*.haproxy.cfg"},
+	SAMLMetadata: {"metadata.xml", "*-metadata.xml", "*.metadata.xml", "FederationMetadata.xml"},
+	JWKS:         {"jwks.json", "*-jwks.json", "*.jwks.json"},
+}
+
+// yamlFileFields are the yaml fields holding the path to a cert, per format
+var yamlFileFields = map[string][]string{
+	Prometheus:   {"ca_file", "cert_file"},
+	Alertmanager: {"ca_file", "cert_file"},
+	Etcd:         {"cert-file", "trusted-ca-file"},
+}
+
+// yamlInlineFields are the fields of a tls_config block holding an inline PEM cert
+var yamlInlineFields = []string{"ca", "cert"}
+
+// haproxyFileKeywords are the bind and server keywords taking the path to a cert
+var haproxyFileKeywords = []string{"crt", "ca-file"}
+
+// CertReference is a certificate referenced from an application config, either by path or inline
+type CertReference struct {
+	// Field is where in the config the reference was found, e.g. scrape_configs[0].tls_config.ca_file
+	Field  string
+	Path   string
+	Inline []byte
+}
+
+// FormatOf returns the format of the config stored under name, or "" if it is not a supported format
+func FormatOf(name string) string {
+	for _, format := range Formats {
+		for _, glob := range formatGlobs[format] {
+			if match, _ := filepath.Match(glob, name); match {
+				return format
+			}
+		}
+	}
+	return ""
+}
+
+// ParseCertReferences returns every cert referenced from the provided config
+func ParseCertReferences(format string, data []byte) ([]CertReference, error) {
+	switch format {
+	case Prometheus, Alertmanager, Etcd:
+		return parseYAML(format, data)
+	case HAProxy:
+		return parseHAProxy(data), nil
+	case SAMLMetadata:
+		return parseSAMLMetadata(data)
+	case JWKS:
+		return parseJWKS(data)
+	}
+	return nil, fmt.Errorf("unsupported config format %v", format)
+}
+
+func parseYAML(format string, data []byte) ([]CertReference, error) {
+	var doc interface{}
+	err := yaml.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var refs []CertReference
+	walkYAML(format, "", doc, &refs)
+	return refs, nil
+}
+
+func walkYAML(format, field string, node interface{}, refs *[]CertReference) {
+	switch n := node.(type) {
+	case map[interface{}]interface{}:
+		keys := make([]string, 0, len(n))
+		values := map[string]interface{}{}
+		for k, v := range n {
+			keys = append(keys, fmt.Sprint(k))
+			values[fmt.Sprint(k)] = v
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			v := values[key]
+			child := key
+			if field != "" {
+				child = field + "." + key
+			}
+
+			if s, ok := v.(string); ok && s != "" {
+				if contains(yamlFileFields[format], key) {
+					*refs = append(*refs, CertReference{Field: child, Path: s})
+					continue
+				}
+				if contains(yamlInlineFields, key) && strings.HasSuffix(field, "tls_config") {
+					*refs = append(*refs, CertReference{Field: child, Inline: []byte(s)})
+					continue
+				}
+			}
+
+			walkYAML(format, child, v, refs)
+		}
+	case []interface{}:
+		for i, v := range n {
+			walkYAML(format, fmt.Sprintf("%v[%v]", field, i), v, refs)
+		}
+	}
+}
+
+func parseHAProxy(data []byte) []CertReference {
+	var refs []CertReference
+	var section, crtBase, caBase string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		words := strings.Fields(line)
+		if len(words) == 0 {
+			continue
+		}
+
+		// section headers are the only unindented lines
+		if line[0] != ' ' && line[0] != '\t' {
+			section = strings.Join(words, " ")
+			continue
+		}
+
+		switch words[0] {
+		case "crt-base":
+			if len(words) > 1 {
+				crtBase = words[1]
+			}
+			continue
+		case "ca-base":
+			if len(words) > 1 {
+				caBase = words[1]
+			}
+			continue
+		}
+
+		for i := 1; i < len(words)-1; i++ {
+			if !contains(haproxyFileKeywords, words[i]) {
+				continue
+			}
+
+			p := words[i+1]
+			base := crtBase
+			if words[i] == "ca-file" {
+				base = caBase
+			}
+			if base != "" && !path.IsAbs(p) {
+				p = path.Join(base, p)
+			}
+
+			refs = append(refs, CertReference{Field: fmt.Sprintf("%v/%v %v", section, words[0], words[i]), Path: p})
+		}
+	}
+
+	return refs
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
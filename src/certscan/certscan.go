@@ -0,0 +1,84 @@
+// Package certscan embeds the secret and configmap checkers of cert-exporter into other programs, e.g. an operator.
+// The embedding program injects its own kubernetes client and clock, so the checkers can be tested against the fake
+// clientset and a fake clock.
+//
+// Init must be called once before the first checker starts.  Checkers publish their metrics to the default prometheus
+// registerer, exactly like cert-exporter does.
+package certscan
+
+import (
+	"time"
+
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/clock"
+
+	"github.com/joe-elliott/cert-exporter/src/checkers"
+	"github.com/joe-elliott/cert-exporter/src/exporters"
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+// Options select the objects a checker scans and the data keys it exports
+type Options struct {
+	// Client is the kubernetes client the checker lists objects with.  It is used as it is, so it is not restricted to
+	// reads in read-only mode.
+	Client kubernetes.Interface
+	// Period is how often the checker scans
+	Period time.Duration
+	// Namespaces are the namespaces scanned.  Empty scans all namespaces.
+	Namespaces []string
+	// LabelSelectors select the objects scanned.  Empty scans all objects.
+	LabelSelectors []string
+	// AnnotationSelectors are annotation keys, or key=value where value may be a glob, the objects scanned must have
+	AnnotationSelectors []string
+	// IncludeGlobs select the data keys exported.  Empty exports all keys.
+	IncludeGlobs []string
+	// ExcludeGlobs drop data keys selected by IncludeGlobs
+	ExcludeGlobs []string
+}
+
+// Init registers the metrics of cert-exporter with the default prometheus registerer, without the metrics of the go
+// runtime and process if exporterMetricsDisabled is set
+func Init(exporterMetricsDisabled bool) {
+	metrics.Init(exporterMetricsDisabled)
+}
+
+// SetClock computes all expiry metrics against c instead of the real time
+func SetClock(c clock.PassiveClock) {
+	exporters.SetClock(c)
+}
+
+// SetRunOnce makes every checker run a single scan and return from StartChecking, e.g. in tests
+func SetRunOnce() {
+	checkers.SetRunOnce()
+}
+
+// NewSecretChecker returns a checker exporting the expiry of the certs in the secrets selected by o.  StartChecking
+// scans until the process exits.
+func NewSecretChecker(o Options) *checkers.PeriodicSecretChecker {
+	checker := checkers.NewSecretChecker(o.Period, o.LabelSelectors, includeGlobs(o), o.ExcludeGlobs, o.AnnotationSelectors, namespaces(o), "", &exporters.SecretExporter{}, nil)
+	checker.SetClient(o.Client)
+	return checker
+}
+
+// NewConfigMapChecker returns a checker exporting the expiry of the certs in the configmaps selected by o.
+// StartChecking scans until the process exits.
+func NewConfigMapChecker(o Options) *checkers.PeriodicConfigMapChecker {
+	checker := checkers.NewConfigMapChecker(o.Period, o.LabelSelectors, includeGlobs(o), o.ExcludeGlobs, o.AnnotationSelectors, namespaces(o), "", &exporters.ConfigMapExporter{}, nil)
+	checker.SetClient(o.Client)
+	return checker
+}
+
+func includeGlobs(o Options) []string {
+	if len(o.IncludeGlobs) == 0 {
+		return []string{"*"}
+	}
+	return o.IncludeGlobs
+}
+
+// namespaces returns the namespaces of o, or the empty namespace standing for all of them
+func namespaces(o Options) []string {
+	if len(o.Namespaces) == 0 {
+		return []string{""}
+	}
+	return o.Namespaces
+}
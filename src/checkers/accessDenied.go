@@ -0,0 +1,113 @@
+package checkers
+
+import (
+	"sync"
+
+	"github.com/golang/glog"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+// namespaceAccessReview makes the secret and configmap checkers ask the API server whether they may list in a
+// namespace before listing in it
+var namespaceAccessReview bool
+
+// EnableNamespaceAccessReview makes the secret and configmap checkers skip the namespaces a SelfSubjectAccessReview
+// does not allow them to list in, instead of sending list requests bound to be forbidden
+func EnableNamespaceAccessReview() {
+	namespaceAccessReview = true
+}
+
+// deniedNamespaces holds the namespaces every checker was last denied access to, so a namespace is only logged when it
+// becomes denied or accessible again
+var (
+	deniedMutex      sync.Mutex
+	deniedNamespaces = map[string]map[string]bool{}
+)
+
+// forbidden records that listing in namespace failed with err because the exporter lacks permission, and reports
+// whether it did.  Such namespaces are skipped quietly instead of failing the scan every cycle.  Lists across all
+// namespaces are never skipped.
+func (s *scan) forbidden(namespace string, err error) bool {
+	if namespace == "" || !apierrors.IsForbidden(err) {
+		return false
+	}
+
+	s.denyNamespace(namespace)
+	return true
+}
+
+func (s *scan) denyNamespace(namespace string) {
+	if s.denied == nil {
+		s.denied = map[string]bool{}
+	}
+	s.denied[namespace] = true
+}
+
+// allowedNamespaces returns the namespaces resource may be listed in, recording the others as denied, if
+// EnableNamespaceAccessReview was called.  Namespaces that could not be reviewed are kept.
+func (s *scan) allowedNamespaces(client kubernetes.Interface, resource string, namespaces []string) []string {
+	if !namespaceAccessReview {
+		return namespaces
+	}
+
+	allowed := make([]string, 0, len(namespaces))
+	for _, ns := range namespaces {
+		if ns == "" {
+			allowed = append(allowed, ns)
+			continue
+		}
+
+		review := &authorizationv1.SelfSubjectAccessReview{
+			Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+				ResourceAttributes: &authorizationv1.ResourceAttributes{
+					Namespace: ns,
+					Verb:      "list",
+					Resource:  resource,
+				},
+			},
+		}
+		ctx, cancel := RequestContext(s.ctx)
+		review, err := client.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+		cancel()
+		if err != nil {
+			glog.Errorf("Error reviewing access to %v in %v: %v", resource, ns, err)
+			s.recordError(ns, metrics.ReasonAPI)
+			allowed = append(allowed, ns)
+			continue
+		}
+
+		if !review.Status.Allowed {
+			s.denyNamespace(ns)
+			continue
+		}
+		allowed = append(allowed, ns)
+	}
+	return allowed
+}
+
+// publishDeniedNamespaces publishes the namespaces the last scan of the checker was denied access to and logs the
+// namespaces that became denied or accessible since the previous one
+func (s *scan) publishDeniedNamespaces() {
+	deniedMutex.Lock()
+	defer deniedMutex.Unlock()
+
+	previous := deniedNamespaces[s.checker]
+	for ns := range s.denied {
+		if !previous[ns] {
+			glog.Warningf("The %v checker is not allowed to list in namespace %v, skipping it until it is", s.checker, ns)
+		}
+		metrics.NamespaceAccessDenied.WithLabelValues(s.checker, ns).Set(1)
+	}
+	for ns := range previous {
+		if !s.denied[ns] {
+			glog.Infof("The %v checker is allowed to list in namespace %v again", s.checker, ns)
+			metrics.NamespaceAccessDenied.DeleteLabelValues(s.checker, ns)
+		}
+	}
+	deniedNamespaces[s.checker] = s.denied
+}
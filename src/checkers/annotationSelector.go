@@ -0,0 +1,38 @@
+package checkers
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+// matchesAnnotationSelectors reports whether annotations match any of the selectors, or if there are none.  A selector
+// is either a key the annotations must contain, or key=value where value is a glob the value of the annotation must
+// match, e.g. cert-exporter.io/enabled=true.
+func matchesAnnotationSelectors(annotations map[string]string, selectors []string) bool {
+	if len(selectors) == 0 {
+		return true
+	}
+
+	for _, selector := range selectors {
+		key, glob, hasValue := strings.Cut(selector, "=")
+		value, ok := annotations[key]
+		if !ok {
+			continue
+		}
+		if !hasValue {
+			return true
+		}
+
+		match, err := filepath.Match(glob, value)
+		if err != nil {
+			glog.Errorf("Error matching annotation selector %v to %v: %v", selector, value, err)
+			continue
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+package checkers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/joe-elliott/cert-exporter/src/exporters"
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+// certManagerCertificateResource is the Certificate custom resource of cert-manager
+var certManagerCertificateResource = schema.GroupVersionResource{Group: "cert-manager.io", Version: "v1", Resource: "certificates"}
+
+// certificateNameAnnotation is set by cert-manager on the secrets it issues to the name of their Certificate
+const certificateNameAnnotation = "cert-manager.io/certificate-name"
+
+// renewWithin is how long before expiry the secret checkers trigger the renewal of cert-manager certs.  0 disables it.
+var renewWithin time.Duration
+
+// EnableCertManagerRenewal makes the secret checkers trigger the renewal of every cert-manager Certificate whose secret
+// holds a cert expiring within within, unless a renewal is already in progress
+func EnableCertManagerRenewal(within time.Duration) {
+	renewWithin = within
+}
+
+// renewCertManagerCertificates triggers the renewal of the Certificates of the secrets expiring within renewWithin.
+// current holds the annotations of the scanned secrets by namespace/name.
+func renewCertManagerCertificates(currentScan *scan, client dynamic.Interface, expiries []exporters.ObjectExpiry, current map[string]map[string]string) {
+	for _, e := range expiries {
+		if e.NotAfter.Sub(exporters.Now()) >= renewWithin {
+			continue
+		}
+
+		name := current[e.Namespace+"/"+e.Name][certificateNameAnnotation]
+		if name == "" {
+			continue
+		}
+
+		renewed, err := renewCertManagerCertificate(client, e.Namespace, name)
+		if err != nil {
+			glog.Errorf("Error renewing Certificate %v/%v of secret %v: %v", e.Namespace, name, e.Name, err)
+			currentScan.recordError(e.Namespace, metrics.ReasonAPI)
+			continue
+		}
+		if renewed {
+			glog.Infof("Triggered the renewal of Certificate %v/%v, its secret %v expires at %v", e.Namespace, name, e.Name, e.NotAfter)
+			metrics.CertManagerRenewalsTotal.WithLabelValues(e.Namespace).Inc()
+		}
+	}
+}
+
+// renewCertManagerCertificate sets the Issuing condition of a Certificate like `cmctl renew` does, which makes
+// cert-manager reissue it.  It returns false if the Certificate is already being issued.
+func renewCertManagerCertificate(client dynamic.Interface, namespace, name string) (bool, error) {
+	certificates := client.Resource(certManagerCertificateResource).Namespace(namespace)
+
+	ctx, cancel := RequestContext(context.Background())
+	certificate, err := certificates.Get(ctx, name, metav1.GetOptions{})
+	cancel()
+	if err != nil {
+		return false, err
+	}
+
+	conditions, _, err := unstructured.NestedSlice(certificate.Object, "status", "conditions")
+	if err != nil {
+		return false, err
+	}
+	for _, c := range conditions {
+		condition, ok := c.(map[string]interface{})
+		if ok && condition["type"] == "Issuing" && condition["status"] == "True" {
+			return false, nil
+		}
+	}
+
+	conditions = append(conditions, map[string]interface{}{
+		"type":               "Issuing",
+		"status":             "True",
+		"reason":             "ManuallyTriggered",
+		"message":            fmt.Sprintf("Certificate re-issuance triggered by cert-exporter as it expires within %v", renewWithin),
+		"lastTransitionTime": time.Now().UTC().Format(time.RFC3339),
+		"observedGeneration": certificate.GetGeneration(),
+	})
+	err = unstructured.SetNestedSlice(certificate.Object, conditions, "status", "conditions")
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel = RequestContext(context.Background())
+	defer cancel()
+	_, err = certificates.UpdateStatus(ctx, certificate, metav1.UpdateOptions{})
+	return err == nil, err
+}
@@ -0,0 +1,165 @@
+package checkers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+// certificateReportResource is the CertificateReport custom resource, defined in helm/cert-exporter/crds
+var certificateReportResource = schema.GroupVersionResource{Group: "cert-exporter.io", Version: "v1alpha1", Resource: "certificatereports"}
+
+const (
+	// certificateReportName is the name of the single CertificateReport maintained in every namespace
+	certificateReportName = "cert-exporter"
+	// maxReportedProblems bounds the size of a report in namespaces with many failing certs
+	maxReportedProblems = 50
+)
+
+// CertificateReportWriter maintains a CertificateReport in every namespace holding certs found by the secret and
+// configmap checkers, so users can `kubectl get certificatereports` without access to Grafana
+type CertificateReportWriter struct {
+	warning time.Duration
+	client  dynamic.Interface
+
+	mutex sync.Mutex
+	// reported are the namespaces a report was written to, so it is emptied once their certs are gone
+	reported map[string]bool
+}
+
+// NewCertificateReportWriter is a factory method that returns a new CertificateReportWriter.  Certs expiring within
+// warning are reported as expiring.
+func NewCertificateReportWriter(kubeconfigPath string, warning time.Duration) *CertificateReportWriter {
+	config, err := buildConfig(kubeconfigPath, "")
+	if err != nil {
+		glog.Fatalf("Error building kubeconfig: %s", err.Error())
+	}
+	restrictToReads(config)
+
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		glog.Fatalf("dynamic.NewForConfig failed: %v", err)
+	}
+
+	return &CertificateReportWriter{warning: warning, client: client, reported: map[string]bool{}}
+}
+
+// Write updates the report of every namespace from the certs currently exported.  It is called after every scan.
+func (w *CertificateReportWriter) Write() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	entries, err := metrics.Inventory()
+	if err != nil {
+		glog.Errorf("Error listing certs: %v", err)
+		return
+	}
+
+	byNamespace := map[string][]metrics.InventoryEntry{}
+	for ns := range w.reported {
+		byNamespace[ns] = nil
+	}
+	for _, entry := range entries {
+		// certs of other clusters are not reported in the namespaces of this one, and certs of files, webhooks and
+		// other cluster scoped sources in none
+		if entry.Cluster != "" || entry.Namespace == "" {
+			continue
+		}
+		byNamespace[entry.Namespace] = append(byNamespace[entry.Namespace], entry)
+	}
+
+	for ns, nsEntries := range byNamespace {
+		err := w.writeReport(ns, certificateReportStatus(nsEntries, w.warning))
+		if err != nil {
+			metrics.RecordError("certificate-reports", ns, metrics.ReasonAPI)
+			glog.Errorf("Error writing the CertificateReport of %v: %v", ns, err)
+			continue
+		}
+		w.reported[ns] = true
+	}
+}
+
+func (w *CertificateReportWriter) writeReport(namespace string, status map[string]interface{}) error {
+	reports := w.client.Resource(certificateReportResource).Namespace(namespace)
+
+	ctx, cancel := RequestContext(context.Background())
+	report, err := reports.Get(ctx, certificateReportName, metav1.GetOptions{})
+	cancel()
+	if apierrors.IsNotFound(err) {
+		report = &unstructured.Unstructured{}
+		report.SetAPIVersion(certificateReportResource.GroupVersion().String())
+		report.SetKind("CertificateReport")
+		report.SetName(certificateReportName)
+		report.SetNamespace(namespace)
+		ctx, cancel = RequestContext(context.Background())
+		report, err = reports.Create(ctx, report, metav1.CreateOptions{})
+		cancel()
+	}
+	if err != nil {
+		return err
+	}
+
+	err = unstructured.SetNestedField(report.Object, status, "status")
+	if err != nil {
+		return err
+	}
+	ctx, cancel = RequestContext(context.Background())
+	defer cancel()
+	_, err = reports.UpdateStatus(ctx, report, metav1.UpdateOptions{})
+	return err
+}
+
+// certificateReportStatus summarizes the certs of a namespace: how many there are, how many expire within warning or
+// expired, the one expiring first and what needs attention
+func certificateReportStatus(entries []metrics.InventoryEntry, warning time.Duration) map[string]interface{} {
+	var expiring, expired int64
+	problems := []interface{}{}
+	var soonest *metrics.InventoryEntry
+
+	for i, entry := range entries {
+		if soonest == nil || entry.DaysLeft < soonest.DaysLeft {
+			soonest = &entries[i]
+		}
+
+		problem := ""
+		switch {
+		case entry.Expired:
+			expired++
+			problem = fmt.Sprintf("%v %v %v (CN %v) expired %v days ago", entry.Kind, entry.Name, entry.Key, entry.CN, -entry.DaysLeft)
+		case time.Duration(entry.DaysLeft)*24*time.Hour < warning:
+			expiring++
+			problem = fmt.Sprintf("%v %v %v (CN %v) expires in %v days", entry.Kind, entry.Name, entry.Key, entry.CN, entry.DaysLeft)
+		}
+		if problem != "" && len(problems) < maxReportedProblems {
+			problems = append(problems, problem)
+		}
+	}
+
+	status := map[string]interface{}{
+		"certificates": int64(len(entries)),
+		"expiring":     expiring,
+		"expired":      expired,
+		"problems":     problems,
+		"lastUpdated":  time.Now().UTC().Format(time.RFC3339),
+	}
+	if soonest != nil {
+		status["soonestExpiry"] = map[string]interface{}{
+			"kind":     soonest.Kind,
+			"name":     soonest.Name,
+			"key":      soonest.Key,
+			"cn":       soonest.CN,
+			"daysLeft": int64(soonest.DaysLeft),
+		}
+	}
+	return status
+}
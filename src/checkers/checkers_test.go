@@ -0,0 +1,86 @@
+package checkers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+func TestMain(m *testing.M) {
+	metrics.Init(false)
+	SetRunOnce()
+	SetListRetries(0, 0)
+	os.Exit(m.Run())
+}
+
+// selfSignedCert returns a PEM encoded self-signed cert of cn expiring in validFor
+func selfSignedCert(t *testing.T, cn string, validFor time.Duration) []byte {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(validFor),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+// gaugeValue returns the value of the series of the gauge name carrying every label of labels
+func gaugeValue(t *testing.T, name string, labels map[string]string) (float64, bool) {
+	t.Helper()
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			matched := 0
+			for _, label := range metric.GetLabel() {
+				if value, ok := labels[label.GetName()]; ok && value == label.GetValue() {
+					matched++
+				}
+			}
+			if matched == len(labels) {
+				return metric.GetGauge().GetValue(), true
+			}
+		}
+	}
+	return 0, false
+}
+
+// assertExpiresIn fails the test unless the series of the gauge name carrying labels expires in about validFor
+func assertExpiresIn(t *testing.T, name string, labels map[string]string, validFor time.Duration) {
+	t.Helper()
+
+	value, ok := gaugeValue(t, name, labels)
+	if !ok {
+		t.Fatalf("no series of %v with labels %v", name, labels)
+	}
+	if value > validFor.Seconds() || value < (validFor-time.Minute).Seconds() {
+		t.Errorf("%v%v = %v, want about %v", name, labels, value, validFor.Seconds())
+	}
+}
@@ -0,0 +1,79 @@
+package checkers
+
+import (
+	"github.com/golang/glog"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// defaultContext is the context of defaultKubeconfig used by every client of it not given a context of its own, and
+// impersonateUser and impersonateGroups the identity every client impersonates, if set
+var (
+	defaultKubeconfig string
+	defaultContext    string
+	impersonateUser   string
+	impersonateGroups []string
+)
+
+// SetKubeConfigOverrides makes every kubernetes client of kubeconfigPath use kubeContext instead of its current context,
+// unless it is given a context of its own, and every client impersonate user and groups, e.g. a read-only identity for
+// audits
+func SetKubeConfigOverrides(kubeconfigPath, kubeContext, user string, groups []string) {
+	defaultKubeconfig = kubeconfigPath
+	defaultContext = kubeContext
+	impersonateUser = user
+	impersonateGroups = groups
+}
+
+// BuildConfig builds the client config of kubeconfigPath with the overrides of SetKubeConfigOverrides
+func BuildConfig(kubeconfigPath string) (*rest.Config, error) {
+	return buildConfig(kubeconfigPath, "")
+}
+
+// buildConfig builds the client config of kubeconfigPath, using kubeContext instead of its current context if set.  An
+// empty kubeconfigPath uses the in-cluster config, or the default loading rules when a context is set.
+func buildConfig(kubeconfigPath, kubeContext string) (*rest.Config, error) {
+	if kubeContext == "" && kubeconfigPath == defaultKubeconfig {
+		kubeContext = defaultContext
+	}
+
+	var config *rest.Config
+	var err error
+	if kubeContext == "" {
+		config, err = clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+	} else {
+		loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+		loadingRules.ExplicitPath = kubeconfigPath
+		config, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{CurrentContext: kubeContext}).ClientConfig()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if impersonateUser != "" || len(impersonateGroups) > 0 {
+		config.Impersonate = rest.ImpersonationConfig{UserName: impersonateUser, Groups: impersonateGroups}
+	}
+	return config, nil
+}
+
+// newClient returns client if one was injected, or builds a client of kubeconfigPath that only reads in read-only mode.
+// Injected clients are used as they are.
+func newClient(client kubernetes.Interface, kubeconfigPath, kubeContext string) kubernetes.Interface {
+	if client != nil {
+		return client
+	}
+
+	config, err := buildConfig(kubeconfigPath, kubeContext)
+	if err != nil {
+		glog.Fatalf("Error building kubeconfig: %s", err.Error())
+	}
+	restrictToReads(config)
+
+	// creates the clientset
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		glog.Fatalf("kubernetes.NewForConfig failed: %v", err)
+	}
+	return clientset
+}
@@ -0,0 +1,47 @@
+package checkers
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// watchFiles makes the file checkers rescan as soon as the directories of their files change
+var watchFiles bool
+
+// watchSettleDelay lets the writer of a file finish, e.g. kubelet swapping every file of a secret volume, before the
+// files are scanned again
+const watchSettleDelay = time.Second
+
+// EnableFileWatching makes the cert and kubeconfig file checkers watch the directories of their globs and of the files
+// they found with inotify, and rescan right after a change instead of waiting for the next period
+func EnableFileWatching() {
+	watchFiles = true
+}
+
+// watchedDirs returns the directories to watch for the files matching globs: the directory of every match, so rotated
+// files are noticed, and the deepest directory without wildcards of every glob, so new files are
+func watchedDirs(globs, matches []string) []string {
+	set := map[string]bool{}
+	for _, match := range matches {
+		set[filepath.Dir(match)] = true
+	}
+	for _, glob := range globs {
+		set[globBaseDir(glob)] = true
+	}
+
+	dirs := make([]string, 0, len(set))
+	for dir := range set {
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// globBaseDir returns the deepest directory of glob that has no wildcards
+func globBaseDir(glob string) string {
+	dir := filepath.Dir(glob)
+	for strings.ContainsAny(dir, "*?[{\\") {
+		dir = filepath.Dir(dir)
+	}
+	return dir
+}
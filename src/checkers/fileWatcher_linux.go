@@ -0,0 +1,87 @@
+package checkers
+
+import (
+	"syscall"
+	"unsafe"
+
+	"github.com/golang/glog"
+)
+
+// watchedEvents are the inotify events telling that a file of a directory was written, replaced or removed
+const watchedEvents = syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO | syscall.IN_ATTRIB
+
+// dirWatcher signals changes to the files of a set of directories
+type dirWatcher struct {
+	fd      int
+	watches map[string]int
+	changes chan struct{}
+}
+
+// newDirWatcher starts an inotify instance watching no directory yet
+func newDirWatcher() (*dirWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &dirWatcher{fd: fd, watches: map[string]int{}, changes: make(chan struct{}, 1)}
+	go w.read()
+	return w, nil
+}
+
+// watch replaces the watched directories with dirs.  Directories that cannot be watched, e.g. because they do not exist
+// yet, are skipped until the next call.
+func (w *dirWatcher) watch(dirs []string) {
+	wanted := map[string]bool{}
+	for _, dir := range dirs {
+		wanted[dir] = true
+		if _, ok := w.watches[dir]; ok {
+			continue
+		}
+
+		wd, err := syscall.InotifyAddWatch(w.fd, dir, watchedEvents)
+		if err != nil {
+			glog.Infof("Not watching %v: %v", dir, err)
+			continue
+		}
+		w.watches[dir] = wd
+	}
+
+	for dir, wd := range w.watches {
+		if !wanted[dir] {
+			syscall.InotifyRmWatch(w.fd, uint32(wd))
+			delete(w.watches, dir)
+		}
+	}
+}
+
+// read signals every batch of events on changes, coalescing batches until the checker rescans
+func (w *dirWatcher) read() {
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := syscall.Read(w.fd, buf)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil || n <= 0 {
+			glog.Errorf("Error reading file events, falling back to polling: %v", err)
+			return
+		}
+
+		changed := false
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			if event.Mask&watchedEvents != 0 {
+				changed = true
+			}
+			offset += syscall.SizeofInotifyEvent + int(event.Len)
+		}
+
+		if changed {
+			select {
+			case w.changes <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
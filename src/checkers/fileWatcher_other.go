@@ -0,0 +1,18 @@
+//go:build !linux
+
+package checkers
+
+import (
+	"errors"
+)
+
+// dirWatcher is only implemented with inotify.  Elsewhere the file checkers keep polling.
+type dirWatcher struct {
+	changes chan struct{}
+}
+
+func newDirWatcher() (*dirWatcher, error) {
+	return nil, errors.New("watching files is only supported on Linux")
+}
+
+func (w *dirWatcher) watch(dirs []string) {}
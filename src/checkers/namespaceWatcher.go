@@ -0,0 +1,139 @@
+package checkers
+
+import (
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+// NamespaceWatcher keeps track of the namespaces matching include and exclude globs as they are created and deleted
+type NamespaceWatcher struct {
+	kubeconfigPath string
+	includeGlobs   []string
+	excludeGlobs   []string
+
+	mutex      sync.Mutex
+	namespaces map[string]bool
+}
+
+// NewNamespaceWatcher is a factory method that returns a new NamespaceWatcher
+func NewNamespaceWatcher(includeGlobs, excludeGlobs []string, kubeconfigPath string) *NamespaceWatcher {
+	return &NamespaceWatcher{
+		kubeconfigPath: kubeconfigPath,
+		includeGlobs:   includeGlobs,
+		excludeGlobs:   excludeGlobs,
+		namespaces:     map[string]bool{},
+	}
+}
+
+// StartWatching watches namespaces until the process exits.  It returns once the existing namespaces are known, so
+// checkers started afterwards scan them from their first cycle.
+func (w *NamespaceWatcher) StartWatching() {
+	config, err := buildConfig(w.kubeconfigPath, "")
+	if err != nil {
+		glog.Fatalf("Error building kubeconfig: %s", err.Error())
+	}
+	restrictToReads(config)
+
+	// creates the clientset
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		glog.Fatalf("kubernetes.NewForConfig failed: %v", err)
+	}
+
+	informer := informers.NewSharedInformerFactory(client, 0).Core().V1().Namespaces().Informer()
+	informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if ns, ok := obj.(*corev1.Namespace); ok {
+				w.add(ns.Name)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if ns, ok := obj.(*corev1.Namespace); ok {
+				w.remove(ns.Name)
+			}
+		},
+	})
+
+	stop := make(chan struct{})
+	go informer.Run(stop)
+	if !cache.WaitForCacheSync(stop, informer.HasSynced) {
+		glog.Fatalf("Error watching namespaces")
+	}
+}
+
+// Namespaces returns the currently existing namespaces matching the globs
+func (w *NamespaceWatcher) Namespaces() []string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	namespaces := make([]string, 0, len(w.namespaces))
+	for ns := range w.namespaces {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+func (w *NamespaceWatcher) add(ns string) {
+	if !w.matches(ns) {
+		return
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if !w.namespaces[ns] {
+		glog.Infof("Namespace %v added to the scanned namespaces", ns)
+		w.namespaces[ns] = true
+	}
+}
+
+func (w *NamespaceWatcher) remove(ns string) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.namespaces[ns] {
+		glog.Infof("Namespace %v removed from the scanned namespaces", ns)
+		delete(w.namespaces, ns)
+	}
+}
+
+func (w *NamespaceWatcher) matches(ns string) bool {
+	include := false
+	for _, glob := range w.includeGlobs {
+		match, err := filepath.Match(glob, ns)
+		if err != nil {
+			glog.Errorf("Error matching %v to %v: %v", glob, ns, err)
+			metrics.RecordError("namespace-watcher", "", metrics.ReasonGlob)
+			continue
+		}
+		if match {
+			include = true
+			break
+		}
+	}
+
+	for _, glob := range w.excludeGlobs {
+		match, err := filepath.Match(glob, ns)
+		if err != nil {
+			glog.Errorf("Error matching %v to %v: %v", glob, ns, err)
+			metrics.RecordError("namespace-watcher", "", metrics.ReasonGlob)
+			continue
+		}
+		if match {
+			return false
+		}
+	}
+
+	return include
+}
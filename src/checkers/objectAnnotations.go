@@ -0,0 +1,64 @@
+package checkers
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/joe-elliott/cert-exporter/src/exporters"
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+// Annotations written to the secrets and configmaps holding certs
+const (
+	notAfterAnnotation      = "cert-exporter.io/not-after"
+	daysRemainingAnnotation = "cert-exporter.io/days-remaining"
+)
+
+// annotateObjects makes the secret and configmap checkers write the expiry of the certs they find onto their objects
+var annotateObjects bool
+
+// EnableObjectAnnotations makes the secret and configmap checkers annotate every object holding certs with the soonest
+// notAfter of its certs and the days remaining until then
+func EnableObjectAnnotations() {
+	annotateObjects = true
+}
+
+// writeExpiryAnnotations patches the expiry annotations of every object whose annotations are out of date.  current
+// holds the annotations of the scanned objects by namespace/name, and patch applies a merge patch to an object.
+func writeExpiryAnnotations(currentScan *scan, expiries []exporters.ObjectExpiry, current map[string]map[string]string, patch func(ctx context.Context, namespace, name string, data []byte) error) {
+	for _, e := range expiries {
+		notAfter := e.NotAfter.UTC().Format(time.RFC3339)
+		daysRemaining := strconv.Itoa(int(math.Floor(e.NotAfter.Sub(exporters.Now()).Hours() / 24)))
+
+		annotations := current[e.Namespace+"/"+e.Name]
+		if annotations[notAfterAnnotation] == notAfter && annotations[daysRemainingAnnotation] == daysRemaining {
+			continue
+		}
+
+		data, err := json.Marshal(map[string]interface{}{
+			"metadata": map[string]interface{}{
+				"annotations": map[string]string{
+					notAfterAnnotation:      notAfter,
+					daysRemainingAnnotation: daysRemaining,
+				},
+			},
+		})
+		if err != nil {
+			glog.Errorf("Error encoding the annotations of %v/%v: %v", e.Namespace, e.Name, err)
+			continue
+		}
+
+		ctx, cancel := RequestContext(currentScan.ctx)
+		err = patch(ctx, e.Namespace, e.Name, data)
+		cancel()
+		if err != nil {
+			glog.Errorf("Error annotating %v/%v: %v", e.Namespace, e.Name, err)
+			currentScan.recordError(e.Namespace, metrics.ReasonAPI)
+		}
+	}
+}
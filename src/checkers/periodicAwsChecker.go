@@ -36,11 +36,13 @@ func NewAwsChecker(awsAccount, awsRegion string, awsSecrets []string, period tim
 
 // StartChecking starts the periodic file check.  Most likely you want to run this as an independent go routine.
 func (p *PeriodicAwsChecker) StartChecking() {
-	periodChannel := time.Tick(p.period)
+	waitInitialDelay()
+	ticker := newTicker(p.period)
 	for {
 		glog.Info("AWS Checker: Begin periodic check")
 
-		p.exporter.ResetMetrics()
+		currentScan := startScan("aws")
+		p.exporter.BeginCycle()
 
 		// Create a Session with a custom region
 		svc := secretsmanager.New(session.New(), aws.NewConfig().WithRegion(p.awsRegion))
@@ -56,7 +58,8 @@ func (p *PeriodicAwsChecker) StartChecking() {
 
 			if err != nil {
 				glog.Error("Error in GetSecretValue: ", err)
-				metrics.ErrorTotal.Inc()
+				currentScan.fail()
+				currentScan.recordError("", metrics.ReasonAPI)
 				continue
 			}
 
@@ -70,13 +73,19 @@ func (p *PeriodicAwsChecker) StartChecking() {
 					glog.Info("Exporting metrics from ", key)
 					err := p.exporter.ExportMetrics(value.(string), secretName, key)
 					if err != nil {
-						metrics.ErrorTotal.Inc()
+						currentScan.recordError("", exporters.ErrorReason(err))
 						glog.Error("Error exporting certificate metrics")
 					}
 				}
 			}
 		}
 
-		<-periodChannel
+		p.exporter.FinishCycle()
+		currentScan.finish()
+
+		if runOnce {
+			return
+		}
+		ticker.wait()
 	}
 }
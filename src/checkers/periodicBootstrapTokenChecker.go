@@ -0,0 +1,99 @@
+package checkers
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/joe-elliott/cert-exporter/src/exporters"
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+// bootstrapTokenNamespace is the namespace the API server reads bootstrap tokens from
+const bootstrapTokenNamespace = "kube-system"
+
+// PeriodicBootstrapTokenChecker is an object designed to check for the expiration of bootstrap tokens at a regular
+// interval
+type PeriodicBootstrapTokenChecker struct {
+	period         time.Duration
+	kubeconfigPath string
+	client         kubernetes.Interface
+	exporter       *exporters.BootstrapTokenExporter
+}
+
+// NewBootstrapTokenChecker is a factory method that returns a new PeriodicBootstrapTokenChecker
+func NewBootstrapTokenChecker(period time.Duration, kubeconfigPath string, e *exporters.BootstrapTokenExporter) *PeriodicBootstrapTokenChecker {
+	return &PeriodicBootstrapTokenChecker{
+		period:         period,
+		kubeconfigPath: kubeconfigPath,
+		exporter:       e,
+	}
+}
+
+// SetClient makes the checker use client instead of building one from its kubeconfig, e.g. the client of an operator
+// embedding it or a fake clientset in tests
+func (p *PeriodicBootstrapTokenChecker) SetClient(client kubernetes.Interface) {
+	p.client = client
+}
+
+// StartChecking starts the periodic bootstrap token check.  Most likely you want to run this as an independent go
+// routine.
+func (p *PeriodicBootstrapTokenChecker) StartChecking() {
+	client := newClient(p.client, p.kubeconfigPath, "")
+
+	waitInitialDelay()
+	ticker := newTicker(p.period)
+	for {
+		glog.Info("Begin periodic check")
+
+		currentScan := startScan("bootstrap-token")
+		p.exporter.BeginCycle()
+
+		var secrets *corev1.SecretList
+		err := currentScan.retryList("bootstrap tokens", func(ctx context.Context) (err error) {
+			secrets, err = client.CoreV1().Secrets(bootstrapTokenNamespace).List(ctx, metav1.ListOptions{
+				FieldSelector: "type=" + string(corev1.SecretTypeBootstrapToken),
+			})
+			return err
+		})
+		if err != nil {
+			glog.Errorf("Error requesting bootstrap tokens %v", err)
+			currentScan.fail()
+			currentScan.recordError(bootstrapTokenNamespace, metrics.ReasonAPI)
+			p.exporter.KeepSeries()
+		} else {
+			for _, secret := range secrets.Items {
+				p.checkToken(currentScan, secret)
+			}
+		}
+
+		p.exporter.FinishCycle()
+		currentScan.finish()
+
+		if runOnce {
+			return
+		}
+		ticker.wait()
+	}
+}
+
+// checkToken exports the expiration of a bootstrap token.  Tokens without an expiration never expire and are skipped.
+func (p *PeriodicBootstrapTokenChecker) checkToken(currentScan *scan, secret corev1.Secret) {
+	glog.Infof("Reviewing bootstrap token %v", secret.Name)
+
+	expiration, ok := secret.Data["expiration"]
+	if !ok || len(expiration) == 0 {
+		return
+	}
+
+	glog.Infof("Publishing %v metrics", secret.Name)
+	err := p.exporter.ExportMetrics(expiration, secret.Name, string(secret.Data["token-id"]))
+	if err != nil {
+		glog.Errorf("Error exporting bootstrap token %v", err)
+		currentScan.recordError(bootstrapTokenNamespace, metrics.ReasonParse)
+	}
+}
@@ -0,0 +1,40 @@
+package checkers
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/joe-elliott/cert-exporter/src/exporters"
+)
+
+func TestBootstrapTokenCheckerExportsExpirations(t *testing.T) {
+	expiration := time.Now().Add(12 * time.Hour).UTC().Format(time.RFC3339)
+	client := fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "bootstrap-token-abcdef", Namespace: bootstrapTokenNamespace},
+			Type:       corev1.SecretTypeBootstrapToken,
+			Data:       map[string][]byte{"token-id": []byte("abcdef"), "expiration": []byte(expiration)},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "bootstrap-token-forever", Namespace: bootstrapTokenNamespace},
+			Type:       corev1.SecretTypeBootstrapToken,
+			Data:       map[string][]byte{"token-id": []byte("forever")},
+		},
+	)
+
+	checker := NewBootstrapTokenChecker(time.Hour, "", &exporters.BootstrapTokenExporter{})
+	checker.SetClient(client)
+	checker.StartChecking()
+
+	assertExpiresIn(t, "cert_exporter_bootstrap_token_expires_in_seconds", map[string]string{
+		"token_id":    "abcdef",
+		"secret_name": "bootstrap-token-abcdef",
+	}, 12*time.Hour)
+	if _, ok := gaugeValue(t, "cert_exporter_bootstrap_token_expires_in_seconds", map[string]string{"token_id": "forever"}); ok {
+		t.Error("exported a token without an expiration")
+	}
+}
@@ -0,0 +1,122 @@
+package checkers
+
+import (
+	"context"
+	"time"
+
+	"github.com/golang/glog"
+	certificatesv1 "k8s.io/api/certificates/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/joe-elliott/cert-exporter/src/exporters"
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+// PeriodicCSRChecker is an object designed to check for CertificateSigningRequests at a regular interval
+type PeriodicCSRChecker struct {
+	period         time.Duration
+	labelSelectors []string
+	stuckAfter     time.Duration
+	kubeconfigPath string
+	client         kubernetes.Interface
+	exporter       *exporters.CSRExporter
+}
+
+// NewCSRChecker is a factory method that returns a new PeriodicCSRChecker.  Requests that stay Pending or Denied for
+// longer than stuckAfter are flagged as stuck.
+func NewCSRChecker(period time.Duration, labelSelectors []string, stuckAfter time.Duration, kubeconfigPath string, e *exporters.CSRExporter) *PeriodicCSRChecker {
+	return &PeriodicCSRChecker{
+		period:         period,
+		labelSelectors: labelSelectors,
+		stuckAfter:     stuckAfter,
+		kubeconfigPath: kubeconfigPath,
+		exporter:       e,
+	}
+}
+
+// SetClient makes the checker use client instead of building one from its kubeconfig, e.g. the client of an operator
+// embedding it or a fake clientset in tests
+func (p *PeriodicCSRChecker) SetClient(client kubernetes.Interface) {
+	p.client = client
+}
+
+// StartChecking starts the periodic CertificateSigningRequest check.  Most likely you want to run this as an
+// independent go routine.
+func (p *PeriodicCSRChecker) StartChecking() {
+	client := newClient(p.client, p.kubeconfigPath, "")
+
+	waitInitialDelay()
+	ticker := newTicker(p.period)
+	for {
+		glog.Info("Begin periodic check")
+
+		currentScan := startScan("csr")
+		p.exporter.BeginCycle()
+
+		labelSelectors := p.labelSelectors
+		if len(labelSelectors) == 0 {
+			labelSelectors = []string{""}
+		}
+		for _, labelSelector := range labelSelectors {
+			var csrs *certificatesv1.CertificateSigningRequestList
+			err := currentScan.retryList("certificatesigningrequests", func(ctx context.Context) (err error) {
+				csrs, err = client.CertificatesV1().CertificateSigningRequests().List(ctx, metav1.ListOptions{
+					LabelSelector: labelSelector,
+				})
+				return err
+			})
+			if err != nil {
+				glog.Errorf("Error requesting certificatesigningrequests %v", err)
+				currentScan.fail()
+				currentScan.recordError("", metrics.ReasonAPI)
+				p.exporter.KeepSeries()
+				continue
+			}
+
+			for _, csr := range csrs.Items {
+				p.checkCSR(currentScan, csr)
+			}
+		}
+
+		p.exporter.FinishCycle()
+		currentScan.finish()
+
+		if runOnce {
+			return
+		}
+		ticker.wait()
+	}
+}
+
+// checkCSR exports the cert issued for a CertificateSigningRequest, or whether it is stuck without one
+func (p *PeriodicCSRChecker) checkCSR(currentScan *scan, csr certificatesv1.CertificateSigningRequest) {
+	glog.Infof("Reviewing certificatesigningrequest %v", csr.Name)
+
+	if len(csr.Status.Certificate) > 0 {
+		glog.Infof("Publishing %v metrics", csr.Name)
+		err := p.exporter.ExportMetrics(csr.Status.Certificate, csr.Name, csr.Spec.SignerName)
+		if err != nil {
+			glog.Errorf("Error exporting certificatesigningrequest %v", err)
+			currentScan.recordError("", exporters.ErrorReason(err))
+		}
+		return
+	}
+
+	// requests that failed or were approved and are waiting for the signer are not stuck on a decision
+	state := "Pending"
+	since := csr.CreationTimestamp.Time
+	for _, condition := range csr.Status.Conditions {
+		switch condition.Type {
+		case certificatesv1.CertificateDenied:
+			state = "Denied"
+			if !condition.LastTransitionTime.IsZero() {
+				since = condition.LastTransitionTime.Time
+			}
+		case certificatesv1.CertificateApproved, certificatesv1.CertificateFailed:
+			return
+		}
+	}
+
+	p.exporter.ExportPendingMetrics(csr.Name, csr.Spec.SignerName, state, exporters.Now().Sub(since) > p.stuckAfter)
+}
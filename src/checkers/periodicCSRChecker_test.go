@@ -0,0 +1,41 @@
+package checkers
+
+import (
+	"testing"
+	"time"
+
+	certificatesv1 "k8s.io/api/certificates/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/joe-elliott/cert-exporter/src/exporters"
+)
+
+func TestCSRCheckerExportsIssuedAndStuckRequests(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&certificatesv1.CertificateSigningRequest{
+			ObjectMeta: metav1.ObjectMeta{Name: "kubelet-serving"},
+			Spec:       certificatesv1.CertificateSigningRequestSpec{SignerName: "kubernetes.io/kubelet-serving"},
+			Status:     certificatesv1.CertificateSigningRequestStatus{Certificate: selfSignedCert(t, "system:node:worker-1", 15*24*time.Hour)},
+		},
+		&certificatesv1.CertificateSigningRequest{
+			ObjectMeta: metav1.ObjectMeta{Name: "forgotten", CreationTimestamp: metav1.NewTime(time.Now().Add(-48 * time.Hour))},
+			Spec:       certificatesv1.CertificateSigningRequestSpec{SignerName: "example.com/signer"},
+		},
+	)
+
+	checker := NewCSRChecker(time.Hour, nil, 24*time.Hour, "", &exporters.CSRExporter{})
+	checker.SetClient(client)
+	checker.StartChecking()
+
+	assertExpiresIn(t, "cert_exporter_csr_expires_in_seconds", map[string]string{
+		"csr_name":    "kubelet-serving",
+		"signer_name": "kubernetes.io/kubelet-serving",
+		"cn":          "system:node:worker-1",
+	}, 15*24*time.Hour)
+
+	stuck, ok := gaugeValue(t, "cert_exporter_csr_stuck", map[string]string{"csr_name": "forgotten", "state": "Pending"})
+	if !ok || stuck != 1 {
+		t.Errorf("cert_exporter_csr_stuck of a request pending for 2 days = %v, %v, want 1", stuck, ok)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"time"
 
 	"github.com/golang/glog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
 
 	"github.com/bmatcuk/doublestar/v3"
 	"github.com/joe-elliott/cert-exporter/src/exporters"
@@ -17,6 +19,8 @@ type PeriodicCertChecker struct {
 	excludeCertGlobs []string
 	nodeName         string
 	exporter         exporters.Exporter
+	driftSecrets     []exporters.SecretDrift
+	kubeconfigPath   string
 }
 
 // NewCertChecker is a factory method that returns a new PeriodicCertChecker
@@ -30,36 +34,142 @@ func NewCertChecker(period time.Duration, includeCertGlobs, excludeCertGlobs []s
 	}
 }
 
+// SetDriftSecrets makes the checker compare the cert files matching a drift source with the secret they are mounted
+// from, read with kubeconfigPath
+func (p *PeriodicCertChecker) SetDriftSecrets(drifts []exporters.SecretDrift, kubeconfigPath string) {
+	p.driftSecrets = drifts
+	p.kubeconfigPath = kubeconfigPath
+}
+
 // StartChecking starts the periodic file check.  Most likely you want to run this as an independent go routine.
 func (p *PeriodicCertChecker) StartChecking() {
-	periodChannel := time.Tick(p.period)
+	var client kubernetes.Interface
+	if len(p.driftSecrets) > 0 {
+		config, err := buildConfig(p.kubeconfigPath, "")
+		if err != nil {
+			glog.Fatalf("Error building kubeconfig: %s", err.Error())
+		}
+		restrictToReads(config)
+
+		client, err = kubernetes.NewForConfig(config)
+		if err != nil {
+			glog.Fatalf("kubernetes.NewForConfig failed: %v", err)
+		}
+	}
+
+	var watcher *dirWatcher
+	if watchFiles {
+		var err error
+		watcher, err = newDirWatcher()
+		if err != nil {
+			glog.Errorf("Error watching files, only polling: %v", err)
+		}
+	}
+
+	waitInitialDelay()
+	ticker := newTicker(p.period)
 
 	for {
 		glog.Info("Begin periodic check")
 
-		p.exporter.ResetMetrics()
+		currentScan := startScan(p.name())
+		p.exporter.BeginCycle()
 
-		for _, match := range p.getMatches() {
+		secrets := map[string]map[string][]byte{}
+		matches := p.getMatches(currentScan)
+		for _, match := range matches {
 			glog.Infof("Publishing %v node metrics %v", p.nodeName, match)
 
 			err := p.exporter.ExportMetrics(match, p.nodeName)
 			if err != nil {
-				metrics.ErrorTotal.Inc()
+				currentScan.recordError("", exporters.ErrorReason(err))
 				glog.Errorf("Error on %v: %v", match, err)
+				continue
+			}
+
+			if client != nil {
+				p.exportDrift(currentScan, client, secrets, match)
 			}
 		}
 
-		<-periodChannel
+		p.exporter.FinishCycle()
+		currentScan.finish()
+
+		if runOnce {
+			return
+		}
+		if watcher == nil {
+			ticker.wait()
+			continue
+		}
+
+		watcher.watch(watchedDirs(p.includeCertGlobs, matches))
+		select {
+		case <-ticker.C():
+		case <-watcher.changes:
+			glog.Info("Files changed, rescanning")
+			time.Sleep(watchSettleDelay)
+			select {
+			case <-watcher.changes:
+			default:
+			}
+		}
+		ticker.Reset()
+	}
+}
+
+// exportDrift compares file with the secret of the first drift source matching it, if any.  secrets caches the data of
+// the secrets read during the cycle by namespace/name.
+func (p *PeriodicCertChecker) exportDrift(currentScan *scan, client kubernetes.Interface, secrets map[string]map[string][]byte, file string) {
+	certExporter, ok := p.exporter.(*exporters.CertExporter)
+	if !ok {
+		return
+	}
+	d, ok := exporters.SecretDriftFor(p.driftSecrets, file)
+	if !ok {
+		return
+	}
+
+	data, ok := secrets[d.Namespace+"/"+d.Name]
+	if !ok {
+		ctx, cancel := RequestContext(currentScan.ctx)
+		secret, err := client.CoreV1().Secrets(d.Namespace).Get(ctx, d.Name, metav1.GetOptions{})
+		cancel()
+		if err != nil {
+			glog.Errorf("Error requesting secret %v/%v: %v", d.Namespace, d.Name, err)
+			currentScan.recordError(d.Namespace, metrics.ReasonAPI)
+			return
+		}
+		data = secret.Data
+		secrets[d.Namespace+"/"+d.Name] = data
+	}
+
+	err := certExporter.ExportDriftMetrics(file, p.nodeName, d, data)
+	if err != nil {
+		glog.Errorf("Error comparing %v with secret %v/%v: %v", file, d.Namespace, d.Name, err)
+		currentScan.recordError(d.Namespace, exporters.ErrorReason(err))
+	}
+}
+
+// name tells the kubeconfig and kubelet checkers apart from the cert file checker in the scan metrics
+func (p *PeriodicCertChecker) name() string {
+	if _, ok := p.exporter.(*exporters.KubeConfigExporter); ok {
+		return "kubeconfig"
+	}
+	if _, ok := p.exporter.(*exporters.KubeletExporter); ok {
+		return "kubelet"
 	}
+	return "cert"
 }
 
-func (p *PeriodicCertChecker) getMatches() []string {
+func (p *PeriodicCertChecker) getMatches(currentScan *scan) []string {
 	set := map[string]bool{}
 	for _, includeGlob := range p.includeCertGlobs {
 		matches, err := doublestar.Glob(includeGlob)
 		if err != nil {
-			metrics.ErrorTotal.Inc()
+			currentScan.recordError("", metrics.ReasonGlob)
 			glog.Errorf("Glob failed on %v: %v", includeGlob, err)
+			currentScan.fail()
 			continue
 		}
 		for _, match := range matches {
@@ -70,8 +180,9 @@ func (p *PeriodicCertChecker) getMatches() []string {
 	for _, excludeGlob := range p.excludeCertGlobs {
 		matches, err := doublestar.Glob(excludeGlob)
 		if err != nil {
-			metrics.ErrorTotal.Inc()
+			currentScan.recordError("", metrics.ReasonGlob)
 			glog.Errorf("Glob failed on %v: %v", excludeGlob, err)
+			currentScan.fail()
 			continue
 		}
 		for _, match := range matches {
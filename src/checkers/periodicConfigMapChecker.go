@@ -4,15 +4,17 @@ import (
 	"context"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/golang/glog"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
 
+	"github.com/joe-elliott/cert-exporter/src/appconfig"
 	"github.com/joe-elliott/cert-exporter/src/exporters"
 	"github.com/joe-elliott/cert-exporter/src/metrics"
 )
@@ -22,15 +24,24 @@ type PeriodicConfigMapChecker struct {
 	period                     time.Duration
 	labelSelectors             []string
 	kubeconfigPath             string
+	client                     kubernetes.Interface
+	kubeContext                string
 	annotationSelectors        []string
 	namespaces                 []string
+	namespaceWatcher           *NamespaceWatcher
 	exporter                   *exporters.ConfigMapExporter
 	includeConfigMapsDataGlobs []string
 	excludeConfigMapsDataGlobs []string
+	configExtractors           []string
+	embeddedCertPaths          []appconfig.EmbeddedCertPath
 }
 
+// embeddedCertPathsAnnotation lists, like --configmaps-embedded-cert-path, the certs embedded in the configs stored in a
+// configmap
+const embeddedCertPathsAnnotation = "cert-exporter.io/embedded-cert-paths"
+
 // NewConfigMapChecker is a factory method that returns a new PeriodicConfigMapChecker
-func NewConfigMapChecker(period time.Duration, labelSelectors, includeConfigMapsDataGlobs, excludeConfigMapsDataGlobs, annotationSelectors, namespaces []string, kubeconfigPath string, e *exporters.ConfigMapExporter) *PeriodicConfigMapChecker {
+func NewConfigMapChecker(period time.Duration, labelSelectors, includeConfigMapsDataGlobs, excludeConfigMapsDataGlobs, annotationSelectors, namespaces []string, kubeconfigPath string, e *exporters.ConfigMapExporter, configExtractors []string) *PeriodicConfigMapChecker {
 	return &PeriodicConfigMapChecker{
 		period:                     period,
 		labelSelectors:             labelSelectors,
@@ -40,23 +51,38 @@ func NewConfigMapChecker(period time.Duration, labelSelectors, includeConfigMaps
 		exporter:                   e,
 		includeConfigMapsDataGlobs: includeConfigMapsDataGlobs,
 		excludeConfigMapsDataGlobs: excludeConfigMapsDataGlobs,
+		configExtractors:           configExtractors,
 	}
 }
 
+// SetClient makes the checker use client instead of building one from its kubeconfig, e.g. the client of an operator
+// embedding it or a fake clientset in tests
+func (p *PeriodicConfigMapChecker) SetClient(client kubernetes.Interface) {
+	p.client = client
+}
+
+// SetKubeContext makes the checker scan the cluster of a context of its kubeconfig instead of the current one
+func (p *PeriodicConfigMapChecker) SetKubeContext(kubeContext string) {
+	p.kubeContext = kubeContext
+}
+
+// SetNamespaceWatcher makes the checker scan the namespaces known to w instead of a fixed list
+func (p *PeriodicConfigMapChecker) SetNamespaceWatcher(w *NamespaceWatcher) {
+	p.namespaceWatcher = w
+}
+
+// SetEmbeddedCertPaths exports the certs embedded in the YAML or JSON configs stored under the keys matching the globs
+func (p *PeriodicConfigMapChecker) SetEmbeddedCertPaths(paths []appconfig.EmbeddedCertPath) {
+	p.embeddedCertPaths = paths
+}
+
 // StartChecking starts the periodic file check.  Most likely you want to run this as an independent go routine.
 func (p *PeriodicConfigMapChecker) StartChecking() {
-	config, err := clientcmd.BuildConfigFromFlags("", p.kubeconfigPath)
-	if err != nil {
-		glog.Fatalf("Error building kubeconfig: %s", err.Error())
-	}
-
-	// creates the clientset
-	client, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		glog.Fatalf("kubernetes.NewForConfig failed: %v", err)
-	}
+	var err error
+	client := newClient(p.client, p.kubeconfigPath, p.kubeContext)
 
-	periodChannel := time.Tick(p.period)
+	waitInitialDelay()
+	ticker := newTicker(p.period)
 
 	if strings.Join(p.namespaces, ", ") != "" {
 		glog.Infof("Scan configMaps in %v", strings.Join(p.namespaces, ", "))
@@ -64,55 +90,79 @@ func (p *PeriodicConfigMapChecker) StartChecking() {
 	for {
 		glog.Info("Begin periodic check")
 
-		p.exporter.ResetMetrics()
+		currentScan := startScan(checkerName("configmap", p.exporter.Profile, p.exporter.Cluster))
+		p.exporter.BeginCycle()
+
+		namespaces := p.namespaces
+		if p.namespaceWatcher != nil {
+			namespaces = p.namespaceWatcher.Namespaces()
+		}
+		currentScan.trackCoverage(namespaces)
+		namespaces = currentScan.allowedNamespaces(client, "configmaps", namespaces)
 
 		var configMaps []corev1.ConfigMap
-		for _, ns := range p.namespaces {
+		for _, ns := range namespaces {
 			if len(p.labelSelectors) > 0 {
 				for _, labelSelector := range p.labelSelectors {
 					var c *corev1.ConfigMapList
-					c, err = client.CoreV1().ConfigMaps(ns).List(context.TODO(), metav1.ListOptions{
-						LabelSelector: labelSelector,
+					err = currentScan.retryList("configMaps in "+ns, func(ctx context.Context) (err error) {
+						c, err = client.CoreV1().ConfigMaps(ns).List(ctx, metav1.ListOptions{
+							LabelSelector: labelSelector,
+						})
+						return err
 					})
 					if err != nil {
+						if currentScan.forbidden(ns, err) {
+							continue
+						}
 						glog.Errorf("Error requesting configMaps %v", err)
-						metrics.ErrorTotal.Inc()
+						currentScan.fail()
+						currentScan.recordError(ns, metrics.ReasonAPI)
+						currentScan.listFailed(ns)
 						continue
 					}
 					configMaps = append(configMaps, c.Items...)
 				}
 			} else {
 				var c *corev1.ConfigMapList
-				c, err = client.CoreV1().ConfigMaps(ns).List(context.TODO(), metav1.ListOptions{})
+				err = currentScan.retryList("configMaps in "+ns, func(ctx context.Context) (err error) {
+					c, err = client.CoreV1().ConfigMaps(ns).List(ctx, metav1.ListOptions{})
+					return err
+				})
 				if err != nil {
+					if currentScan.forbidden(ns, err) {
+						continue
+					}
 					glog.Errorf("Error requesting configMaps %v", err)
-					metrics.ErrorTotal.Inc()
+					currentScan.fail()
+					currentScan.recordError(ns, metrics.ReasonAPI)
+					currentScan.listFailed(ns)
 					continue
 				}
 				configMaps = append(configMaps, c.Items...)
 			}
 		}
 
+		// a stable order keeps the same certs under the --max-series-per-namespace quota every cycle
+		sort.Slice(configMaps, func(i, j int) bool {
+			if configMaps[i].Namespace != configMaps[j].Namespace {
+				return configMaps[i].Namespace < configMaps[j].Namespace
+			}
+			return configMaps[i].Name < configMaps[j].Name
+		})
+
+		// a configMap matched by several label selectors is listed once per selector but only processed once
+		configMaps = uniqueConfigMaps(configMaps)
+
 		for _, configMap := range configMaps {
 			include, exclude := false, false
 			glog.Infof("Reviewing configMap %v in %v", configMap.GetName(), configMap.GetNamespace())
 
-			if len(p.annotationSelectors) > 0 {
-				matches := false
-				annotations := configMap.GetAnnotations()
-				for _, selector := range p.annotationSelectors {
-					_, ok := annotations[selector]
-					if ok {
-						matches = true
-						break
-					}
-				}
-
-				if !matches {
-					continue
-				}
+			if !matchesAnnotationSelectors(configMap.GetAnnotations(), p.annotationSelectors) {
+				continue
 			}
 			glog.Infof("Annotations matched. Parsing configMap.")
+			currentScan.scannedObject(configMap.Namespace)
 
 			combinedMap := make(map[string][]byte)
 			for key, value := range configMap.Data {
@@ -123,14 +173,31 @@ func (p *PeriodicConfigMapChecker) StartChecking() {
 				combinedMap[key] = value
 			}
 
-			for name, data := range combinedMap {
+			embeddedCertPaths := p.embeddedCertPaths
+			if value, ok := configMap.GetAnnotations()[embeddedCertPathsAnnotation]; ok {
+				annotated, err := appconfig.ParseEmbeddedCertPaths(value)
+				if err != nil {
+					glog.Errorf("Error parsing %v of configMap %v/%v: %v", embeddedCertPathsAnnotation, configMap.Namespace, configMap.Name, err)
+					currentScan.recordError(configMap.Namespace, metrics.ReasonParse)
+				}
+				embeddedCertPaths = append(append([]appconfig.EmbeddedCertPath{}, embeddedCertPaths...), annotated...)
+			}
+
+			names := make([]string, 0, len(combinedMap))
+			for name := range combinedMap {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			for _, name := range names {
+				data := combinedMap[name]
 				include, exclude = false, false
 
 				for _, glob := range p.includeConfigMapsDataGlobs {
 					include, err = filepath.Match(glob, name)
 					if err != nil {
 						glog.Errorf("Error matching %v to %v: %v", glob, name, err)
-						metrics.ErrorTotal.Inc()
+						currentScan.recordError(configMap.Namespace, metrics.ReasonGlob)
 						continue
 					}
 
@@ -143,7 +210,7 @@ func (p *PeriodicConfigMapChecker) StartChecking() {
 					exclude, err = filepath.Match(glob, name)
 					if err != nil {
 						glog.Errorf("Error matching %v to %v: %v", glob, name, err)
-						metrics.ErrorTotal.Inc()
+						currentScan.recordError(configMap.Namespace, metrics.ReasonGlob)
 						continue
 					}
 
@@ -154,26 +221,48 @@ func (p *PeriodicConfigMapChecker) StartChecking() {
 
 				if include && !exclude {
 					glog.Infof("Publishing %v/%v metrics %v", configMap.Name, configMap.Namespace, name)
+					currentScan.scannedDataKey(configMap.Namespace)
+
+					if paths := appconfig.EmbeddedPathsFor(embeddedCertPaths, name); len(paths) > 0 {
+						err = p.exporter.ExportEmbeddedMetrics(paths, name, configMap.Name, configMap.Namespace, combinedMap, configMap.GetLabels(), configMap.GetAnnotations())
+						if err != nil {
+							glog.Errorf("Error exporting certs embedded in configMap %v", err)
+							currentScan.failedDataKey(configMap.Namespace, exporters.ErrorReason(err))
+							p.exporter.ExportParseError(name, configMap.Name, configMap.Namespace, err)
+						}
+						continue
+					}
+
+					if format := appconfig.FormatOf(name); format != "" && p.extractorEnabled(format) {
+						err = p.exporter.ExportConfigMetrics(format, name, configMap.Name, configMap.Namespace, combinedMap, configMap.GetLabels(), configMap.GetAnnotations())
+						if err != nil {
+							glog.Errorf("Error exporting certs referenced from configMap %v", err)
+							currentScan.failedDataKey(configMap.Namespace, exporters.ErrorReason(err))
+							p.exporter.ExportParseError(name, configMap.Name, configMap.Namespace, err)
+						}
+						continue
+					}
 
 					// Try to get password from a secret with name secret-name-password and "key.password" as key
 
 					passwordKey := strings.TrimSuffix(name, path.Ext(name)) + ".password"
-					password, err := getPasswordFromSecret(client, configMap.Namespace, configMap.Name+"-password", passwordKey)
+					password, err := getPasswordFromSecret(currentScan.ctx, client, configMap.Namespace, configMap.Name+"-password", passwordKey)
 					if err != nil {
 						glog.Infof("Password not present in possible expected secret")
 					}
 
 					if password == "" {
-						password, err = getPasswordFromSecret(client, configMap.Namespace, configMap.Name+"-password", name+".password")
+						password, err = getPasswordFromSecret(currentScan.ctx, client, configMap.Namespace, configMap.Name+"-password", name+".password")
 						if err != nil {
 							glog.Infof("Password not present in possible expected secret")
 						}
 					}
 
-					err = p.exporter.ExportMetrics(data, name, configMap.Name, configMap.Namespace, password, configMap.GetLabels())
+					err = p.exporter.ExportMetrics(data, name, configMap.Name, configMap.Namespace, password, configMap.GetLabels(), configMap.GetAnnotations())
 					if err != nil {
 						glog.Errorf("Error exporting configMap %v", err)
-						metrics.ErrorTotal.Inc()
+						currentScan.failedDataKey(configMap.Namespace, exporters.ErrorReason(err))
+						p.exporter.ExportParseError(name, configMap.Name, configMap.Namespace, err)
 					}
 				} else {
 					glog.Infof("Ignoring %v. Does not match %v or matches %v.", name, p.includeConfigMapsDataGlobs, p.excludeConfigMapsDataGlobs)
@@ -181,6 +270,49 @@ func (p *PeriodicConfigMapChecker) StartChecking() {
 			}
 		}
 
-		<-periodChannel
+		for _, ns := range currentScan.unlistedNamespaces() {
+			p.exporter.KeepNamespace(ns)
+		}
+		p.exporter.FinishCycle()
+		currentScan.parsedCerts(p.exporter.CertsParsed())
+
+		if annotateObjects {
+			current := map[string]map[string]string{}
+			for _, configMap := range configMaps {
+				current[configMap.Namespace+"/"+configMap.Name] = configMap.GetAnnotations()
+			}
+			writeExpiryAnnotations(currentScan, p.exporter.ObjectExpiries(), current, func(ctx context.Context, namespace, name string, data []byte) error {
+				_, err := client.CoreV1().ConfigMaps(namespace).Patch(ctx, name, types.MergePatchType, data, metav1.PatchOptions{})
+				return err
+			})
+		}
+
+		currentScan.finish()
+
+		if runOnce {
+			return
+		}
+		ticker.wait()
+	}
+}
+
+func (p *PeriodicConfigMapChecker) extractorEnabled(format string) bool {
+	for _, e := range p.configExtractors {
+		if e == format {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueConfigMaps drops the duplicates from configMaps sorted by namespace and name
+func uniqueConfigMaps(configMaps []corev1.ConfigMap) []corev1.ConfigMap {
+	unique := configMaps[:0]
+	for _, configMap := range configMaps {
+		if n := len(unique); n > 0 && unique[n-1].Namespace == configMap.Namespace && unique[n-1].Name == configMap.Name {
+			continue
+		}
+		unique = append(unique, configMap)
 	}
+	return unique
 }
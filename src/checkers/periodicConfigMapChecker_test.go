@@ -0,0 +1,37 @@
+package checkers
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/joe-elliott/cert-exporter/src/exporters"
+)
+
+func TestConfigMapCheckerExportsCerts(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "trust-bundle", Namespace: "configmap-test"},
+		Data:       map[string]string{"ca.crt": string(selfSignedCert(t, "root.example", 365*24*time.Hour))},
+		BinaryData: map[string][]byte{"intermediate.crt": selfSignedCert(t, "intermediate.example", 90*24*time.Hour)},
+	})
+
+	checker := NewConfigMapChecker(time.Hour, nil, []string{"*.crt"}, nil, nil, []string{"configmap-test"}, "", &exporters.ConfigMapExporter{}, nil)
+	checker.SetClient(client)
+	checker.StartChecking()
+
+	assertExpiresIn(t, "cert_exporter_configmap_expires_in_seconds", map[string]string{
+		"configmap_namespace": "configmap-test",
+		"configmap_name":      "trust-bundle",
+		"key_name":            "ca.crt",
+		"cn":                  "root.example",
+	}, 365*24*time.Hour)
+	assertExpiresIn(t, "cert_exporter_configmap_expires_in_seconds", map[string]string{
+		"configmap_namespace": "configmap-test",
+		"configmap_name":      "trust-bundle",
+		"key_name":            "intermediate.crt",
+		"cn":                  "intermediate.example",
+	}, 90*24*time.Hour)
+}
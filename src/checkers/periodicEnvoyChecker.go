@@ -0,0 +1,163 @@
+package checkers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/joe-elliott/cert-exporter/src/exporters"
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+// envoyRequestTimeout bounds every request to an Envoy admin interface
+const envoyRequestTimeout = 10 * time.Second
+
+// PeriodicEnvoyChecker is an object designed to check for the certs Envoy proxies hold in memory at a regular interval
+type PeriodicEnvoyChecker struct {
+	period         time.Duration
+	adminURLs      []string
+	labelSelectors []string
+	namespaces     []string
+	adminPort      int
+	kubeconfigPath string
+	exporter       *exporters.EnvoyExporter
+	httpClient     *http.Client
+}
+
+// NewEnvoyChecker is a factory method that returns a new PeriodicEnvoyChecker.  It checks the Envoys listening at
+// adminURLs and, when labelSelectors are given, the admin port of every running pod they select in namespaces.
+func NewEnvoyChecker(period time.Duration, adminURLs, labelSelectors, namespaces []string, adminPort int, kubeconfigPath string, e *exporters.EnvoyExporter) *PeriodicEnvoyChecker {
+	return &PeriodicEnvoyChecker{
+		period:         period,
+		adminURLs:      adminURLs,
+		labelSelectors: labelSelectors,
+		namespaces:     namespaces,
+		adminPort:      adminPort,
+		kubeconfigPath: kubeconfigPath,
+		exporter:       e,
+		httpClient:     &http.Client{Timeout: envoyRequestTimeout},
+	}
+}
+
+// StartChecking starts the periodic Envoy check.  Most likely you want to run this as an independent go routine.
+func (p *PeriodicEnvoyChecker) StartChecking() {
+	var client kubernetes.Interface
+	if len(p.labelSelectors) > 0 {
+		config, err := buildConfig(p.kubeconfigPath, "")
+		if err != nil {
+			glog.Fatalf("Error building kubeconfig: %s", err.Error())
+		}
+		restrictToReads(config)
+
+		client, err = kubernetes.NewForConfig(config)
+		if err != nil {
+			glog.Fatalf("kubernetes.NewForConfig failed: %v", err)
+		}
+	}
+
+	waitInitialDelay()
+	ticker := newTicker(p.period)
+	for {
+		glog.Info("Begin periodic check")
+
+		currentScan := startScan("envoy")
+		p.exporter.BeginCycle()
+
+		for _, adminURL := range p.adminURLs {
+			p.checkEnvoy(currentScan, strings.TrimSuffix(adminURL, "/"), "", "")
+		}
+		if client != nil {
+			for _, pod := range p.listPods(currentScan, client) {
+				adminURL := "http://" + net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(p.adminPort))
+				p.checkEnvoy(currentScan, adminURL, pod.Name, pod.Namespace)
+			}
+		}
+
+		for _, ns := range currentScan.unlistedNamespaces() {
+			p.exporter.KeepNamespace(ns)
+		}
+		p.exporter.FinishCycle()
+		currentScan.finish()
+
+		if runOnce {
+			return
+		}
+		ticker.wait()
+	}
+}
+
+// listPods returns the running pods with an IP matching any label selector.  Failures are recorded on the scan.
+func (p *PeriodicEnvoyChecker) listPods(currentScan *scan, client kubernetes.Interface) []corev1.Pod {
+	var pods []corev1.Pod
+	for _, ns := range p.namespaces {
+		for _, labelSelector := range p.labelSelectors {
+			var list *corev1.PodList
+			err := currentScan.retryList("pods in "+ns, func(ctx context.Context) (err error) {
+				list, err = client.CoreV1().Pods(ns).List(ctx, metav1.ListOptions{
+					LabelSelector: labelSelector,
+				})
+				return err
+			})
+			if err != nil {
+				glog.Errorf("Error requesting pods %v", err)
+				currentScan.fail()
+				currentScan.recordError(ns, metrics.ReasonAPI)
+				currentScan.listFailed(ns)
+				continue
+			}
+
+			for _, pod := range list.Items {
+				if pod.Status.Phase == corev1.PodRunning && pod.Status.PodIP != "" {
+					pods = append(pods, pod)
+				}
+			}
+		}
+	}
+	return pods
+}
+
+// checkEnvoy exports the certs listed by the /certs endpoint of the admin interface at adminURL
+func (p *PeriodicEnvoyChecker) checkEnvoy(currentScan *scan, adminURL, podName, podNamespace string) {
+	glog.Infof("Reviewing the certs of Envoy %v", adminURL)
+
+	body, err := p.getCerts(adminURL)
+	if err != nil {
+		glog.Errorf("Error requesting the certs of Envoy %v: %v", adminURL, err)
+		currentScan.recordError(podNamespace, metrics.ReasonAPI)
+		return
+	}
+
+	target := adminURL
+	if podName != "" {
+		target = podNamespace + "/" + podName
+	}
+	err = p.exporter.ExportMetrics(body, target, podName, podNamespace)
+	if err != nil {
+		glog.Errorf("Error exporting Envoy %v", err)
+		currentScan.recordError(podNamespace, metrics.ReasonParse)
+	}
+}
+
+// getCerts returns the body of the /certs endpoint of the admin interface at adminURL
+func (p *PeriodicEnvoyChecker) getCerts(adminURL string) ([]byte, error) {
+	resp, err := p.httpClient.Get(adminURL + "/certs")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %v", resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
@@ -0,0 +1,180 @@
+package checkers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/joe-elliott/cert-exporter/src/exporters"
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+// gatewayResource is the Gateway resource of the Gateway API
+var gatewayResource = schema.GroupVersionResource{Group: "gateway.networking.k8s.io", Version: "v1", Resource: "gateways"}
+
+// PeriodicGatewayChecker is an object designed to check for the certs of Gateway API gateways at a regular interval
+type PeriodicGatewayChecker struct {
+	period              time.Duration
+	labelSelectors      []string
+	annotationSelectors []string
+	namespaces          []string
+	kubeconfigPath      string
+	exporter            *exporters.GatewayExporter
+}
+
+// NewGatewayChecker is a factory method that returns a new PeriodicGatewayChecker
+func NewGatewayChecker(period time.Duration, labelSelectors, annotationSelectors, namespaces []string, kubeconfigPath string, e *exporters.GatewayExporter) *PeriodicGatewayChecker {
+	return &PeriodicGatewayChecker{
+		period:              period,
+		labelSelectors:      labelSelectors,
+		annotationSelectors: annotationSelectors,
+		namespaces:          namespaces,
+		kubeconfigPath:      kubeconfigPath,
+		exporter:            e,
+	}
+}
+
+// StartChecking starts the periodic gateway check.  Most likely you want to run this as an independent go routine.
+func (p *PeriodicGatewayChecker) StartChecking() {
+	config, err := buildConfig(p.kubeconfigPath, "")
+	if err != nil {
+		glog.Fatalf("Error building kubeconfig: %s", err.Error())
+	}
+	restrictToReads(config)
+
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		glog.Fatalf("dynamic.NewForConfig failed: %v", err)
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		glog.Fatalf("kubernetes.NewForConfig failed: %v", err)
+	}
+
+	waitInitialDelay()
+	ticker := newTicker(p.period)
+	if strings.Join(p.namespaces, ", ") != "" {
+		glog.Infof("Scan gateways in %v", strings.Join(p.namespaces, ", "))
+	}
+	for {
+		glog.Info("Begin periodic check")
+
+		currentScan := startScan("gateway")
+		p.exporter.BeginCycle()
+
+		// listeners of several gateways often share a wildcard cert
+		secrets := map[string]*corev1.Secret{}
+		for _, ns := range p.namespaces {
+			for _, gateway := range p.listGateways(currentScan, dynamicClient, ns) {
+				p.checkGateway(currentScan, client, secrets, gateway)
+			}
+		}
+
+		for _, ns := range currentScan.unlistedNamespaces() {
+			p.exporter.KeepNamespace(ns)
+		}
+		p.exporter.FinishCycle()
+		currentScan.finish()
+
+		if runOnce {
+			return
+		}
+		ticker.wait()
+	}
+}
+
+// listGateways returns the gateways of ns matching any label selector.  Failures are recorded on the scan.
+func (p *PeriodicGatewayChecker) listGateways(currentScan *scan, client dynamic.Interface, ns string) []unstructured.Unstructured {
+	labelSelectors := p.labelSelectors
+	if len(labelSelectors) == 0 {
+		labelSelectors = []string{""}
+	}
+
+	var gateways []unstructured.Unstructured
+	for _, labelSelector := range labelSelectors {
+		var list *unstructured.UnstructuredList
+		err := currentScan.retryList("gateways in "+ns, func(ctx context.Context) (err error) {
+			list, err = client.Resource(gatewayResource).Namespace(ns).List(ctx, metav1.ListOptions{
+				LabelSelector: labelSelector,
+			})
+			return err
+		})
+		if err != nil {
+			glog.Errorf("Error requesting gateways %v", err)
+			currentScan.fail()
+			currentScan.recordError(ns, metrics.ReasonAPI)
+			currentScan.listFailed(ns)
+			continue
+		}
+		gateways = append(gateways, list.Items...)
+	}
+	return gateways
+}
+
+// checkGateway exports the certs of the secrets the listeners of a gateway matching the annotation selectors reference.
+// secrets caches the secrets read during the cycle by namespace/name.
+func (p *PeriodicGatewayChecker) checkGateway(currentScan *scan, client kubernetes.Interface, secrets map[string]*corev1.Secret, gateway unstructured.Unstructured) {
+	glog.Infof("Reviewing gateway %v in %v", gateway.GetName(), gateway.GetNamespace())
+
+	if !matchesAnnotationSelectors(gateway.GetAnnotations(), p.annotationSelectors) {
+		return
+	}
+
+	listeners, _, _ := unstructured.NestedSlice(gateway.Object, "spec", "listeners")
+	for _, l := range listeners {
+		listener, ok := l.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, _, _ := unstructured.NestedString(listener, "name")
+		hostname, _, _ := unstructured.NestedString(listener, "hostname")
+		refs, _, _ := unstructured.NestedSlice(listener, "tls", "certificateRefs")
+
+		for _, r := range refs {
+			ref, ok := r.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			group, _, _ := unstructured.NestedString(ref, "group")
+			kind, _, _ := unstructured.NestedString(ref, "kind")
+			if group != "" || (kind != "" && kind != "Secret") {
+				glog.Infof("Ignoring certificateRef of listener %v of gateway %v/%v to a %v %v", name, gateway.GetNamespace(), gateway.GetName(), group, kind)
+				continue
+			}
+			secretName, _, _ := unstructured.NestedString(ref, "name")
+			secretNamespace, _, _ := unstructured.NestedString(ref, "namespace")
+			if secretNamespace == "" {
+				secretNamespace = gateway.GetNamespace()
+			}
+
+			secret, ok := secrets[secretNamespace+"/"+secretName]
+			if !ok {
+				var err error
+				ctx, cancel := RequestContext(currentScan.ctx)
+				secret, err = client.CoreV1().Secrets(secretNamespace).Get(ctx, secretName, metav1.GetOptions{})
+				cancel()
+				if err != nil {
+					glog.Errorf("Error requesting secret %v/%v of gateway %v/%v: %v", secretNamespace, secretName, gateway.GetNamespace(), gateway.GetName(), err)
+					currentScan.recordError(gateway.GetNamespace(), metrics.ReasonAPI)
+					continue
+				}
+				secrets[secretNamespace+"/"+secretName] = secret
+			}
+
+			glog.Infof("Publishing %v/%v metrics %v", gateway.GetName(), gateway.GetNamespace(), name)
+			err := p.exporter.ExportMetrics(secret.Data[corev1.TLSCertKey], gateway.GetName(), gateway.GetNamespace(), name, hostname, secretName, secretNamespace)
+			if err != nil {
+				glog.Errorf("Error exporting gateway %v", err)
+				currentScan.recordError(gateway.GetNamespace(), exporters.ErrorReason(err))
+			}
+		}
+	}
+}
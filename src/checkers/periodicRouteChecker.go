@@ -0,0 +1,139 @@
+package checkers
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+
+	"github.com/joe-elliott/cert-exporter/src/exporters"
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+// routeResource is the Route resource of OpenShift
+var routeResource = schema.GroupVersionResource{Group: "route.openshift.io", Version: "v1", Resource: "routes"}
+
+// routeCertFields are the fields of spec.tls of a route holding PEM certs
+var routeCertFields = []string{"certificate", "caCertificate", "destinationCACertificate"}
+
+// PeriodicRouteChecker is an object designed to check for the certs of OpenShift routes at a regular interval
+type PeriodicRouteChecker struct {
+	period              time.Duration
+	labelSelectors      []string
+	annotationSelectors []string
+	namespaces          []string
+	kubeconfigPath      string
+	exporter            *exporters.RouteExporter
+}
+
+// NewRouteChecker is a factory method that returns a new PeriodicRouteChecker
+func NewRouteChecker(period time.Duration, labelSelectors, annotationSelectors, namespaces []string, kubeconfigPath string, e *exporters.RouteExporter) *PeriodicRouteChecker {
+	return &PeriodicRouteChecker{
+		period:              period,
+		labelSelectors:      labelSelectors,
+		annotationSelectors: annotationSelectors,
+		namespaces:          namespaces,
+		kubeconfigPath:      kubeconfigPath,
+		exporter:            e,
+	}
+}
+
+// StartChecking starts the periodic route check.  Most likely you want to run this as an independent go routine.
+func (p *PeriodicRouteChecker) StartChecking() {
+	config, err := buildConfig(p.kubeconfigPath, "")
+	if err != nil {
+		glog.Fatalf("Error building kubeconfig: %s", err.Error())
+	}
+	restrictToReads(config)
+
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		glog.Fatalf("dynamic.NewForConfig failed: %v", err)
+	}
+
+	waitInitialDelay()
+	ticker := newTicker(p.period)
+	if strings.Join(p.namespaces, ", ") != "" {
+		glog.Infof("Scan routes in %v", strings.Join(p.namespaces, ", "))
+	}
+	for {
+		glog.Info("Begin periodic check")
+
+		currentScan := startScan("route")
+		p.exporter.BeginCycle()
+
+		for _, ns := range p.namespaces {
+			for _, route := range p.listRoutes(currentScan, client, ns) {
+				p.checkRoute(currentScan, route)
+			}
+		}
+
+		for _, ns := range currentScan.unlistedNamespaces() {
+			p.exporter.KeepNamespace(ns)
+		}
+		p.exporter.FinishCycle()
+		currentScan.finish()
+
+		if runOnce {
+			return
+		}
+		ticker.wait()
+	}
+}
+
+// listRoutes returns the routes of ns matching any label selector.  Failures are recorded on the scan.
+func (p *PeriodicRouteChecker) listRoutes(currentScan *scan, client dynamic.Interface, ns string) []unstructured.Unstructured {
+	labelSelectors := p.labelSelectors
+	if len(labelSelectors) == 0 {
+		labelSelectors = []string{""}
+	}
+
+	var routes []unstructured.Unstructured
+	for _, labelSelector := range labelSelectors {
+		var list *unstructured.UnstructuredList
+		err := currentScan.retryList("routes in "+ns, func(ctx context.Context) (err error) {
+			list, err = client.Resource(routeResource).Namespace(ns).List(ctx, metav1.ListOptions{
+				LabelSelector: labelSelector,
+			})
+			return err
+		})
+		if err != nil {
+			glog.Errorf("Error requesting routes %v", err)
+			currentScan.fail()
+			currentScan.recordError(ns, metrics.ReasonAPI)
+			currentScan.listFailed(ns)
+			continue
+		}
+		routes = append(routes, list.Items...)
+	}
+	return routes
+}
+
+// checkRoute exports the certs of the spec.tls fields of a route matching the annotation selectors
+func (p *PeriodicRouteChecker) checkRoute(currentScan *scan, route unstructured.Unstructured) {
+	glog.Infof("Reviewing route %v in %v", route.GetName(), route.GetNamespace())
+
+	if !matchesAnnotationSelectors(route.GetAnnotations(), p.annotationSelectors) {
+		return
+	}
+
+	host, _, _ := unstructured.NestedString(route.Object, "spec", "host")
+	for _, field := range routeCertFields {
+		pem, _, err := unstructured.NestedString(route.Object, "spec", "tls", field)
+		if err != nil || pem == "" {
+			continue
+		}
+
+		glog.Infof("Publishing %v/%v metrics %v", route.GetName(), route.GetNamespace(), field)
+		err = p.exporter.ExportMetrics([]byte(pem), field, route.GetName(), route.GetNamespace(), host)
+		if err != nil {
+			glog.Errorf("Error exporting route %v", err)
+			currentScan.recordError(route.GetNamespace(), exporters.ErrorReason(err))
+		}
+	}
+}
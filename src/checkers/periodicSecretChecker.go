@@ -5,17 +5,20 @@ import (
 	"errors"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/golang/glog"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
 
 	"github.com/joe-elliott/cert-exporter/src/exporters"
 	"github.com/joe-elliott/cert-exporter/src/metrics"
+	"github.com/joe-elliott/cert-exporter/src/tracing"
 )
 
 // PeriodicSecretChecker is an object designed to check for files on disk at a regular interval
@@ -23,12 +26,16 @@ type PeriodicSecretChecker struct {
 	period                  time.Duration
 	labelSelectors          []string
 	kubeconfigPath          string
+	kubeContext             string
+	client                  kubernetes.Interface
 	annotationSelectors     []string
 	namespaces              []string
+	namespaceWatcher        *NamespaceWatcher
 	exporter                *exporters.SecretExporter
 	includeSecretsDataGlobs []string
 	excludeSecretsDataGlobs []string
 	includeSecretsTypes     []string
+	excludeSecretsTypes     []string
 }
 
 // NewSecretChecker is a factory method that returns a new PeriodicSecretChecker
@@ -46,8 +53,31 @@ func NewSecretChecker(period time.Duration, labelSelectors, includeSecretsDataGl
 	}
 }
 
-func getPasswordFromSecret(client kubernetes.Interface, namespace, secretName, passwordKey string) (string, error) {
-	secret, err := client.CoreV1().Secrets(namespace).Get(context.TODO(), secretName, metav1.GetOptions{})
+// SetClient makes the checker use client instead of building one from its kubeconfig, e.g. the client of an operator
+// embedding it or a fake clientset in tests
+func (p *PeriodicSecretChecker) SetClient(client kubernetes.Interface) {
+	p.client = client
+}
+
+// SetKubeContext makes the checker scan the cluster of a context of its kubeconfig instead of the current one
+func (p *PeriodicSecretChecker) SetKubeContext(kubeContext string) {
+	p.kubeContext = kubeContext
+}
+
+// SetExcludeSecretsTypes makes the checker ignore the secrets of the given types
+func (p *PeriodicSecretChecker) SetExcludeSecretsTypes(types []string) {
+	p.excludeSecretsTypes = types
+}
+
+// SetNamespaceWatcher makes the checker scan the namespaces known to w instead of a fixed list
+func (p *PeriodicSecretChecker) SetNamespaceWatcher(w *NamespaceWatcher) {
+	p.namespaceWatcher = w
+}
+
+func getPasswordFromSecret(ctx context.Context, client kubernetes.Interface, namespace, secretName, passwordKey string) (string, error) {
+	ctx, cancel := RequestContext(ctx)
+	defer cancel()
+	secret, err := client.CoreV1().Secrets(namespace).Get(ctx, secretName, metav1.GetOptions{})
 	if err != nil {
 		return "", err
 	}
@@ -60,101 +90,190 @@ func getPasswordFromSecret(client kubernetes.Interface, namespace, secretName, p
 	return string(password), nil
 }
 
-// StartChecking starts the periodic file check.  Most likely you want to run this as an independent go routine.
-func (p *PeriodicSecretChecker) StartChecking() {
-	config, err := clientcmd.BuildConfigFromFlags("", p.kubeconfigPath)
+// getPasswordForSecretKey looks up the password protecting the data key name of secret.  An empty string is returned if none is found.
+func getPasswordForSecretKey(ctx context.Context, client kubernetes.Interface, secret corev1.Secret, name string) string {
+	// Try to get password from same secret assuming "password" as key - JITBundleSecret
+	password, err := getPasswordFromSecret(ctx, client, secret.Namespace, secret.Name, "password")
 	if err != nil {
-		glog.Fatalf("Error building kubeconfig: %s", err.Error())
+		glog.Infof("Password not present within secret %v", secret.Name)
 	}
 
-	// creates the clientset
-	client, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		glog.Fatalf("kubernetes.NewForConfig failed: %v", err)
+	// Try to get password from another secret with name secret-name-password and "key.password" as key - Generic JIT
+	passwordKey := strings.TrimSuffix(name, path.Ext(name)) + ".password"
+	if password == "" {
+		password, err = getPasswordFromSecret(ctx, client, secret.Namespace, secret.Name+"-password", passwordKey)
+		if err != nil {
+			glog.Infof("Password not present in possible expected secret for secret %v", secret.Name)
+		}
+	}
+
+	if password == "" {
+		password, err = getPasswordFromSecret(ctx, client, secret.Namespace, secret.Name+"-password", name+".password")
+		if err != nil {
+			glog.Infof("Password not present in possible expected secret for secret %v", secret.Name)
+		}
+	}
+
+	return password
+}
+
+// StartChecking starts the periodic file check.  Most likely you want to run this as an independent go routine.
+func (p *PeriodicSecretChecker) StartChecking() {
+	var err error
+	client := newClient(p.client, p.kubeconfigPath, p.kubeContext)
+
+	// cert-manager certificates are custom resources, so renewing them needs a dynamic client of the kubeconfig even
+	// if a client was injected
+	var dynamicClient dynamic.Interface
+	if renewWithin > 0 {
+		config, err := buildConfig(p.kubeconfigPath, p.kubeContext)
+		if err != nil {
+			glog.Fatalf("Error building kubeconfig: %s", err.Error())
+		}
+		restrictToReads(config)
+
+		dynamicClient, err = dynamic.NewForConfig(config)
+		if err != nil {
+			glog.Fatalf("dynamic.NewForConfig failed: %v", err)
+		}
 	}
 
-	periodChannel := time.Tick(p.period)
+	var rotations rotationTracker
+	waitInitialDelay()
+	ticker := newTicker(p.period)
 	if strings.Join(p.namespaces, ", ") != "" {
 		glog.Infof("Scan secrets in %v", strings.Join(p.namespaces, ", "))
 	}
 	for {
 		glog.Info("Begin periodic check")
 
-		p.exporter.ResetMetrics()
+		currentScan := startScan(checkerName("secret", p.exporter.Profile, p.exporter.Cluster))
+		p.exporter.BeginCycle()
+
+		namespaces := p.namespaces
+		if p.namespaceWatcher != nil {
+			namespaces = p.namespaceWatcher.Namespaces()
+		}
+		currentScan.trackCoverage(namespaces)
+		namespaces = currentScan.allowedNamespaces(client, "secrets", namespaces)
 
 		var secrets []corev1.Secret
-		for _, ns := range p.namespaces {
+		for _, ns := range namespaces {
 			if len(p.labelSelectors) > 0 {
 				for _, labelSelector := range p.labelSelectors {
 					var s *corev1.SecretList
-					s, err = client.CoreV1().Secrets(ns).List(context.TODO(), metav1.ListOptions{
-						LabelSelector: labelSelector,
+					_, span := tracing.Start(currentScan.ctx, "list secrets", "namespace", ns, "label_selector", labelSelector)
+					err = currentScan.retryList("secrets in "+ns, func(ctx context.Context) (err error) {
+						s, err = client.CoreV1().Secrets(ns).List(ctx, metav1.ListOptions{
+							LabelSelector: labelSelector,
+						})
+						return err
 					})
+					span.RecordError(err)
+					span.End()
 					if err != nil {
+						if currentScan.forbidden(ns, err) {
+							continue
+						}
 						glog.Errorf("Error requesting secrets %v", err)
-						metrics.ErrorTotal.Inc()
+						currentScan.fail()
+						currentScan.recordError(ns, metrics.ReasonAPI)
+						currentScan.listFailed(ns)
 						continue
 					}
 					secrets = append(secrets, s.Items...)
 				}
 			} else {
 				var s *corev1.SecretList
-				s, err = client.CoreV1().Secrets(ns).List(context.TODO(), metav1.ListOptions{})
+				_, span := tracing.Start(currentScan.ctx, "list secrets", "namespace", ns)
+				err = currentScan.retryList("secrets in "+ns, func(ctx context.Context) (err error) {
+					s, err = client.CoreV1().Secrets(ns).List(ctx, metav1.ListOptions{})
+					return err
+				})
+				span.RecordError(err)
+				span.End()
 				if err != nil {
+					if currentScan.forbidden(ns, err) {
+						continue
+					}
 					glog.Errorf("Error requesting secrets %v", err)
-					metrics.ErrorTotal.Inc()
+					currentScan.fail()
+					currentScan.recordError(ns, metrics.ReasonAPI)
+					currentScan.listFailed(ns)
 					continue
 				}
 				secrets = append(secrets, s.Items...)
 			}
 		}
 
+		// a stable order keeps the same certs under the --max-series-per-namespace quota every cycle
+		sort.Slice(secrets, func(i, j int) bool {
+			if secrets[i].Namespace != secrets[j].Namespace {
+				return secrets[i].Namespace < secrets[j].Namespace
+			}
+			return secrets[i].Name < secrets[j].Name
+		})
+
+		// a secret matched by several label selectors is listed once per selector but only processed once
+		secrets = uniqueSecrets(secrets)
+
 		for _, secret := range secrets {
 			include, exclude := false, false
 			// If you want only a certain type of cert
-			if len(p.includeSecretsTypes) > 0 {
-				exclude = false
-				for _, t := range p.includeSecretsTypes {
-					if string(secret.Type) == t {
-						include = true
-					}
-					if include {
-						continue
-					}
-				}
-				if !include {
-					glog.Infof("Ignoring secret %s in %s because %s is not included in your secret-include-types %v", secret.GetName(), secret.GetNamespace(), secret.Type, p.includeSecretsTypes)
-					continue
-				}
+			if len(p.includeSecretsTypes) > 0 && !matchesAnyType(currentScan, secret, p.includeSecretsTypes) {
+				glog.Infof("Ignoring secret %s in %s because %s is not included in your secret-include-types %v", secret.GetName(), secret.GetNamespace(), secret.Type, p.includeSecretsTypes)
+				continue
+			}
+			if matchesAnyType(currentScan, secret, p.excludeSecretsTypes) {
+				glog.Infof("Ignoring secret %s in %s because %s is excluded by your secret-exclude-types %v", secret.GetName(), secret.GetNamespace(), secret.Type, p.excludeSecretsTypes)
+				continue
 			}
 
 			glog.Infof("Reviewing secret %v in %v", secret.GetName(), secret.GetNamespace())
 
-			if len(p.annotationSelectors) > 0 {
-				matches := false
-				annotations := secret.GetAnnotations()
-				for _, selector := range p.annotationSelectors {
-					_, ok := annotations[selector]
-					if ok {
-						matches = true
-						break
+			if !matchesAnnotationSelectors(secret.GetAnnotations(), p.annotationSelectors) {
+				continue
+			}
+			glog.Infof("Annotations matched. Parsing Secret.")
+			currentScan.scannedObject(secret.Namespace)
+			secretCtx, secretSpan := tracing.Start(currentScan.ctx, "parse secret", "namespace", secret.Namespace, "name", secret.Name)
+
+			if tlsSecretMode && secret.Type == corev1.SecretTypeTLS {
+				for _, name := range []string{corev1.TLSCertKey, corev1.TLSPrivateKeyKey, "ca.crt"} {
+					if secret.Data[name] != nil {
+						currentScan.scannedDataKey(secret.Namespace)
 					}
 				}
 
-				if !matches {
-					continue
+				glog.Infof("Publishing %v/%v metrics as a TLS secret", secret.Name, secret.Namespace)
+				_, exportSpan := tracing.Start(secretCtx, "export", "key", corev1.TLSCertKey)
+				err = p.exporter.ExportTLSSecretMetrics(secret.Data, secret.Name, secret.Namespace, secret.GetLabels(), secret.GetAnnotations())
+				exportSpan.RecordError(err)
+				exportSpan.End()
+				if err != nil {
+					glog.Errorf("Error exporting secret %v", err)
+					currentScan.failedDataKey(secret.Namespace, exporters.ErrorReason(err))
+					p.exporter.ExportParseError(corev1.TLSCertKey, secret.Name, secret.Namespace, err)
 				}
+				secretSpan.End()
+				continue
 			}
-			glog.Infof("Annotations matched. Parsing Secret.")
 
-			for name, bytes := range secret.Data {
+			names := make([]string, 0, len(secret.Data))
+			for name := range secret.Data {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			for _, name := range names {
+				bytes := secret.Data[name]
 				include, exclude = false, false
 
 				for _, glob := range p.includeSecretsDataGlobs {
 					include, err = filepath.Match(glob, name)
 					if err != nil {
 						glog.Errorf("Error matching %v to %v: %v", glob, name, err)
-						metrics.ErrorTotal.Inc()
+						currentScan.recordError(secret.Namespace, metrics.ReasonGlob)
 						continue
 					}
 
@@ -167,7 +286,7 @@ func (p *PeriodicSecretChecker) StartChecking() {
 					exclude, err = filepath.Match(glob, name)
 					if err != nil {
 						glog.Errorf("Error matching %v to %v: %v", glob, name, err)
-						metrics.ErrorTotal.Inc()
+						currentScan.recordError(secret.Namespace, metrics.ReasonGlob)
 						continue
 					}
 
@@ -178,40 +297,104 @@ func (p *PeriodicSecretChecker) StartChecking() {
 
 				if include && !exclude {
 					glog.Infof("Publishing %v/%v metrics %v", secret.Name, secret.Namespace, name)
+					currentScan.scannedDataKey(secret.Namespace)
 
-					// Try to get password from same secret assuming "password" as key - JITBundleSecret
-					password, err := getPasswordFromSecret(client, secret.Namespace, secret.Name, "password")
-					if err != nil {
-						glog.Infof("Password not present within secret %v", secret.Name)
-					}
-
-					// Try to get password from another secret with name secret-name-password and "key.password" as key - Generic JIT
-					passwordKey := strings.TrimSuffix(name, path.Ext(name)) + ".password"
-					if password == "" {
-						password, err = getPasswordFromSecret(client, secret.Namespace, secret.Name+"-password", passwordKey)
-						if err != nil {
-							glog.Infof("Password not present in possible expected secret for secret %v", secret.Name)
-						}
-					}
+					passwordCtx, passwordSpan := tracing.Start(secretCtx, "password lookup", "key", name)
+					password := getPasswordForSecretKey(passwordCtx, client, secret, name)
+					passwordSpan.End()
 
-					if password == "" {
-						password, err = getPasswordFromSecret(client, secret.Namespace, secret.Name+"-password", name+".password")
-						if err != nil {
-							glog.Infof("Password not present in possible expected secret for secret %v", secret.Name)
-						}
+					_, exportSpan := tracing.Start(secretCtx, "export", "key", name)
+					if exporters.IsPrivateKey(bytes) {
+						err = p.exporter.ExportKeyPairMetrics(bytes, name, secret.Name, secret.Namespace, password, secret.Data, secret.GetLabels())
+					} else if exporters.IsArchive(bytes) {
+						err = p.exporter.ExportArchiveMetrics(bytes, name, secret.Name, secret.Namespace, password, secret.GetLabels(), secret.GetAnnotations())
+					} else if exporters.IsKubeConfig(bytes) {
+						err = p.exporter.ExportKubeConfigMetrics(bytes, name, secret.Name, secret.Namespace, secret.GetLabels(), secret.GetAnnotations())
+					} else if exporters.IsJWT(bytes) {
+						err = p.exporter.ExportJWTMetrics(bytes, name, secret.Name, secret.Namespace, secret.GetLabels(), secret.GetAnnotations())
+					} else if exporters.IsPGPKey(bytes) {
+						err = p.exporter.ExportPGPKeyMetrics(bytes, name, secret.Name, secret.Namespace, secret.GetLabels(), secret.GetAnnotations())
+					} else {
+						err = p.exporter.ExportMetrics(bytes, name, secret.Name, secret.Namespace, password, secret.GetLabels(), secret.GetAnnotations())
 					}
-
-					err = p.exporter.ExportMetrics(bytes, name, secret.Name, secret.Namespace, password, secret.GetLabels())
+					exportSpan.RecordError(err)
+					exportSpan.End()
 					if err != nil {
 						glog.Errorf("Error exporting secret %v", err)
-						metrics.ErrorTotal.Inc()
+						currentScan.failedDataKey(secret.Namespace, exporters.ErrorReason(err))
+						p.exporter.ExportParseError(name, secret.Name, secret.Namespace, err)
 					}
 				} else {
 					glog.Infof("Ignoring %v. Does not match %v or matches %v.", name, p.includeSecretsDataGlobs, p.excludeSecretsDataGlobs)
 				}
 			}
+			secretSpan.End()
 		}
 
-		<-periodChannel
+		for _, ns := range currentScan.unlistedNamespaces() {
+			p.exporter.KeepNamespace(ns)
+		}
+		p.exporter.FinishCycle()
+		currentScan.parsedCerts(p.exporter.CertsParsed())
+
+		current := map[string]map[string]string{}
+		for _, secret := range secrets {
+			current[secret.Namespace+"/"+secret.Name] = secret.GetAnnotations()
+		}
+		if annotateObjects {
+			writeExpiryAnnotations(currentScan, p.exporter.ObjectExpiries(), current, func(ctx context.Context, namespace, name string, data []byte) error {
+				_, err := client.CoreV1().Secrets(namespace).Patch(ctx, name, types.MergePatchType, data, metav1.PatchOptions{})
+				return err
+			})
+		}
+		if renewWithin > 0 {
+			renewCertManagerCertificates(currentScan, dynamicClient, p.exporter.ObjectExpiries(), current)
+		}
+		if restartOnRotation {
+			restartRotatedWorkloads(currentScan, client, rotations.rotated(p.exporter.ObjectExpiries()))
+		}
+
+		currentScan.finish()
+
+		if runOnce {
+			return
+		}
+		ticker.wait()
+	}
+}
+
+// matchesAnyType reports whether the type of secret matches any of the globs, e.g. kubernetes.io/*.  Unlike for paths,
+// `*` also matches a `/`, so `*` selects every type and `example.com/*` selects example.com/cert/v1 too.
+func matchesAnyType(currentScan *scan, secret corev1.Secret, globs []string) bool {
+	for _, glob := range globs {
+		match, err := filepath.Match(typePattern(glob), typePattern(string(secret.Type)))
+		if err != nil {
+			glog.Errorf("Error matching %v to %v: %v", glob, secret.Type, err)
+			currentScan.recordError(secret.Namespace, metrics.ReasonGlob)
+			continue
+		}
+
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// typePattern replaces the slashes of a secret type or type glob by a byte filepath.Match does not treat as a
+// separator, so wildcards match across them
+func typePattern(s string) string {
+	return strings.ReplaceAll(s, "/", "\x00")
+}
+
+// uniqueSecrets drops the duplicates from secrets sorted by namespace and name
+func uniqueSecrets(secrets []corev1.Secret) []corev1.Secret {
+	unique := secrets[:0]
+	for _, secret := range secrets {
+		if n := len(unique); n > 0 && unique[n-1].Namespace == secret.Namespace && unique[n-1].Name == secret.Name {
+			continue
+		}
+		unique = append(unique, secret)
 	}
+	return unique
 }
@@ -0,0 +1,96 @@
+package checkers
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+
+	"github.com/joe-elliott/cert-exporter/src/exporters"
+)
+
+func TestSecretCheckerExportsCerts(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-tls", Namespace: "secret-test"},
+			Type:       corev1.SecretTypeOpaque,
+			Data:       map[string][]byte{"tls.crt": selfSignedCert(t, "web.example", 30*24*time.Hour)},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "notes", Namespace: "secret-test"},
+			Type:       corev1.SecretTypeOpaque,
+			Data:       map[string][]byte{"readme.txt": []byte("not a cert")},
+		},
+	)
+
+	checker := NewSecretChecker(time.Hour, nil, []string{"*.crt"}, nil, nil, []string{"secret-test"}, "", &exporters.SecretExporter{}, nil)
+	checker.SetClient(client)
+	checker.StartChecking()
+
+	assertExpiresIn(t, "cert_exporter_secret_expires_in_seconds", map[string]string{
+		"secret_namespace": "secret-test",
+		"secret_name":      "web-tls",
+		"key_name":         "tls.crt",
+		"cn":               "web.example",
+	}, 30*24*time.Hour)
+	if _, ok := gaugeValue(t, "cert_exporter_secret_expires_in_seconds", map[string]string{"secret_name": "notes"}); ok {
+		t.Error("exported a series of a key not matching the include globs")
+	}
+}
+
+func TestSecretCheckerKeepsSeriesOfUnlistedNamespaces(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "api-tls", Namespace: "secret-unlisted"},
+		Data:       map[string][]byte{"tls.crt": selfSignedCert(t, "api.example", 10*24*time.Hour)},
+	})
+
+	checker := NewSecretChecker(time.Hour, nil, []string{"*"}, nil, nil, []string{"secret-unlisted"}, "", &exporters.SecretExporter{}, nil)
+	checker.SetClient(client)
+	checker.StartChecking()
+
+	labels := map[string]string{"secret_namespace": "secret-unlisted", "secret_name": "api-tls"}
+	if _, ok := gaugeValue(t, "cert_exporter_secret_expires_in_seconds", labels); !ok {
+		t.Fatal("the secret was not exported")
+	}
+
+	client.PrependReactor("list", "secrets", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("connection refused")
+	})
+	checker.StartChecking()
+
+	if _, ok := gaugeValue(t, "cert_exporter_secret_expires_in_seconds", labels); !ok {
+		t.Error("the series of a namespace that could not be listed were deleted")
+	}
+}
+
+func TestSecretCheckerFiltersTypes(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "ingress-tls", Namespace: "secret-types"},
+			Type:       corev1.SecretTypeTLS,
+			Data:       map[string][]byte{"tls.crt": selfSignedCert(t, "ingress.example", 60*24*time.Hour)},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "release", Namespace: "secret-types"},
+			Type:       "helm.sh/release.v1",
+			Data:       map[string][]byte{"tls.crt": selfSignedCert(t, "release.example", 60*24*time.Hour)},
+		},
+	)
+
+	checker := NewSecretChecker(time.Hour, nil, []string{"*"}, nil, nil, []string{"secret-types"}, "", &exporters.SecretExporter{}, []string{"*"})
+	checker.SetExcludeSecretsTypes([]string{"helm.sh/*"})
+	checker.SetClient(client)
+	checker.StartChecking()
+
+	if _, ok := gaugeValue(t, "cert_exporter_secret_expires_in_seconds", map[string]string{"secret_name": "ingress-tls"}); !ok {
+		t.Error("a kubernetes.io/tls secret was not included by the type glob *")
+	}
+	if _, ok := gaugeValue(t, "cert_exporter_secret_expires_in_seconds", map[string]string{"secret_name": "release"}); ok {
+		t.Error("a helm.sh/release.v1 secret was not excluded by the type glob helm.sh/*")
+	}
+}
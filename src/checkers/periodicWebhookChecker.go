@@ -8,7 +8,6 @@ import (
 	v1 "k8s.io/api/admissionregistration/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
 
 	"github.com/joe-elliott/cert-exporter/src/exporters"
 	"github.com/joe-elliott/cert-exporter/src/metrics"
@@ -24,6 +23,7 @@ type PeriodicWebhookChecker struct {
 	period              time.Duration
 	labelSelectors      []string
 	kubeconfigPath      string
+	client              kubernetes.Interface
 	annotationSelectors []string
 	exporter            *exporters.WebhookExporter
 }
@@ -39,39 +39,54 @@ func NewWebhookChecker(period time.Duration, labelSelectors, annotationSelectors
 	}
 }
 
+// SetClient makes the checker use client instead of building one from its kubeconfig, e.g. the client of an operator
+// embedding it or a fake clientset in tests
+func (p *PeriodicWebhookChecker) SetClient(client kubernetes.Interface) {
+	p.client = client
+}
+
 // StartChecking starts the periodic file check.  Most likely you want to run this as an independent go routine.
 func (p *PeriodicWebhookChecker) StartChecking() {
-	config, err := clientcmd.BuildConfigFromFlags("", p.kubeconfigPath)
-	if err != nil {
-		glog.Fatalf("Error building kubeconfig: %s", err.Error())
-	}
+	client := newClient(p.client, p.kubeconfigPath, "")
 
-	// creates the clientset
-	client, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		glog.Fatalf("kubernetes.NewForConfig failed: %v", err)
-	}
-
-	periodChannel := time.Tick(p.period)
+	waitInitialDelay()
+	ticker := newTicker(p.period)
 
 	for {
 		glog.Info("Begin periodic check")
 
-		p.exporter.ResetMetrics()
-		p.checkMutatingWebhook(client)
-		p.checkValidatingWebhook(client)
-		<-periodChannel
+		currentScan := startScan("webhook")
+		p.exporter.BeginCycle()
+		if err := p.checkMutatingWebhook(currentScan, client); err != nil {
+			currentScan.fail()
+			p.exporter.KeepSeries()
+		}
+		if err := p.checkValidatingWebhook(currentScan, client); err != nil {
+			currentScan.fail()
+			p.exporter.KeepSeries()
+		}
+		p.exporter.FinishCycle()
+		currentScan.finish()
+
+		if runOnce {
+			return
+		}
+		ticker.wait()
 	}
 }
 
-func (p *PeriodicWebhookChecker) checkMutatingWebhook(client kubernetes.Interface) {
+// checkMutatingWebhook exports the CA bundles of the mutating webhooks.  It returns the error listing them, if any.
+func (p *PeriodicWebhookChecker) checkMutatingWebhook(currentScan *scan, client kubernetes.Interface) error {
 	var configs []v1.MutatingWebhookConfiguration
 	var err error
 	if len(p.labelSelectors) > 0 {
 		for _, labelSelector := range p.labelSelectors {
 			var m *v1.MutatingWebhookConfigurationList
-			m, err = client.AdmissionregistrationV1().MutatingWebhookConfigurations().List(context.TODO(), metav1.ListOptions{
-				LabelSelector: labelSelector,
+			err = currentScan.retryList("mutatingwebhookconfigurations", func(ctx context.Context) (err error) {
+				m, err = client.AdmissionregistrationV1().MutatingWebhookConfigurations().List(ctx, metav1.ListOptions{
+					LabelSelector: labelSelector,
+				})
+				return err
 			})
 			if err != nil {
 				break
@@ -81,7 +96,10 @@ func (p *PeriodicWebhookChecker) checkMutatingWebhook(client kubernetes.Interfac
 		}
 	} else {
 		var m *v1.MutatingWebhookConfigurationList
-		m, err = client.AdmissionregistrationV1().MutatingWebhookConfigurations().List(context.TODO(), metav1.ListOptions{})
+		err = currentScan.retryList("mutatingwebhookconfigurations", func(ctx context.Context) (err error) {
+			m, err = client.AdmissionregistrationV1().MutatingWebhookConfigurations().List(ctx, metav1.ListOptions{})
+			return err
+		})
 		if err == nil {
 			configs = m.Items
 		}
@@ -89,26 +107,14 @@ func (p *PeriodicWebhookChecker) checkMutatingWebhook(client kubernetes.Interfac
 
 	if err != nil {
 		glog.Errorf("Error requesting mutatingwebhookconfiguration %v", err)
-		metrics.ErrorTotal.Inc()
-		return
+		metrics.RecordError("webhook", "", metrics.ReasonAPI)
+		return err
 	}
 
 	for _, configuration := range configs {
 		glog.Infof("Reviewing mutatingwebhookconfiguration %v", configuration.GetName())
-		if len(p.annotationSelectors) > 0 {
-			matches := false
-			annotations := configuration.GetAnnotations()
-			for _, selector := range p.annotationSelectors {
-				_, ok := annotations[selector]
-				if ok {
-					matches = true
-					break
-				}
-			}
-
-			if !matches {
-				continue
-			}
+		if !matchesAnnotationSelectors(configuration.GetAnnotations(), p.annotationSelectors) {
+			continue
 		}
 		glog.Infof("Annotations matched. Parsing mutatingwebhookconfiguration.")
 
@@ -118,23 +124,29 @@ func (p *PeriodicWebhookChecker) checkMutatingWebhook(client kubernetes.Interfac
 				err = p.exporter.ExportMetrics(admissionReviewVersions.ClientConfig.CABundle, mutatingWebhookConfigurationType, configuration.Name, admissionReviewVersions.Name)
 				if err != nil {
 					glog.Errorf("Error exporting mutatingwebhookconfiguration %v", err)
-					metrics.ErrorTotal.Inc()
+					metrics.RecordError("webhook", "", exporters.ErrorReason(err))
 				}
 			} else {
 				glog.Infof("Ignoring %v. Does not contains CABundle cert", configuration.Name)
 			}
 		}
 	}
+
+	return nil
 }
 
-func (p *PeriodicWebhookChecker) checkValidatingWebhook(client kubernetes.Interface) {
+// checkValidatingWebhook exports the CA bundles of the validating webhooks.  It returns the error listing them, if any.
+func (p *PeriodicWebhookChecker) checkValidatingWebhook(currentScan *scan, client kubernetes.Interface) error {
 	var configs []v1.ValidatingWebhookConfiguration
 	var err error
 	if len(p.labelSelectors) > 0 {
 		for _, labelSelector := range p.labelSelectors {
 			var v *v1.ValidatingWebhookConfigurationList
-			v, err = client.AdmissionregistrationV1().ValidatingWebhookConfigurations().List(context.TODO(), metav1.ListOptions{
-				LabelSelector: labelSelector,
+			err = currentScan.retryList("validatingwebhookconfigurations", func(ctx context.Context) (err error) {
+				v, err = client.AdmissionregistrationV1().ValidatingWebhookConfigurations().List(ctx, metav1.ListOptions{
+					LabelSelector: labelSelector,
+				})
+				return err
 			})
 			if err != nil {
 				break
@@ -144,7 +156,10 @@ func (p *PeriodicWebhookChecker) checkValidatingWebhook(client kubernetes.Interf
 		}
 	} else {
 		var v *v1.ValidatingWebhookConfigurationList
-		v, err = client.AdmissionregistrationV1().ValidatingWebhookConfigurations().List(context.TODO(), metav1.ListOptions{})
+		err = currentScan.retryList("validatingwebhookconfigurations", func(ctx context.Context) (err error) {
+			v, err = client.AdmissionregistrationV1().ValidatingWebhookConfigurations().List(ctx, metav1.ListOptions{})
+			return err
+		})
 		if err == nil {
 			configs = v.Items
 		}
@@ -152,26 +167,14 @@ func (p *PeriodicWebhookChecker) checkValidatingWebhook(client kubernetes.Interf
 
 	if err != nil {
 		glog.Errorf("Error requesting validatingwebhookconfiguration %v", err)
-		metrics.ErrorTotal.Inc()
-		return
+		metrics.RecordError("webhook", "", metrics.ReasonAPI)
+		return err
 	}
 
 	for _, configuration := range configs {
 		glog.Infof("Reviewing validatingwebhookconfiguration %v", configuration.GetName())
-		if len(p.annotationSelectors) > 0 {
-			matches := false
-			annotations := configuration.GetAnnotations()
-			for _, selector := range p.annotationSelectors {
-				_, ok := annotations[selector]
-				if ok {
-					matches = true
-					break
-				}
-			}
-
-			if !matches {
-				continue
-			}
+		if !matchesAnnotationSelectors(configuration.GetAnnotations(), p.annotationSelectors) {
+			continue
 		}
 		glog.Infof("Annotations matched. Parsing validatingwebhookconfiguration.")
 
@@ -181,11 +184,13 @@ func (p *PeriodicWebhookChecker) checkValidatingWebhook(client kubernetes.Interf
 				err = p.exporter.ExportMetrics(admissionReviewVersions.ClientConfig.CABundle, validatingWebhookConfigurationType, configuration.Name, admissionReviewVersions.Name)
 				if err != nil {
 					glog.Errorf("Error exporting validatingwebhookconfiguration %v", err)
-					metrics.ErrorTotal.Inc()
+					metrics.RecordError("webhook", "", exporters.ErrorReason(err))
 				}
 			} else {
 				glog.Infof("Ignoring %v. Does not contains CABundle cert", configuration.Name)
 			}
 		}
 	}
+
+	return nil
 }
@@ -0,0 +1,46 @@
+package checkers
+
+import (
+	"testing"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/joe-elliott/cert-exporter/src/exporters"
+)
+
+func TestWebhookCheckerExportsCABundles(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&admissionregistrationv1.MutatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "injector"},
+			Webhooks: []admissionregistrationv1.MutatingWebhook{{
+				Name:         "inject.example.com",
+				ClientConfig: admissionregistrationv1.WebhookClientConfig{CABundle: selfSignedCert(t, "injector-ca", 20*24*time.Hour)},
+			}},
+		},
+		&admissionregistrationv1.ValidatingWebhookConfiguration{
+			ObjectMeta: metav1.ObjectMeta{Name: "policy"},
+			Webhooks: []admissionregistrationv1.ValidatingWebhook{{
+				Name:         "validate.example.com",
+				ClientConfig: admissionregistrationv1.WebhookClientConfig{CABundle: selfSignedCert(t, "policy-ca", 40*24*time.Hour)},
+			}},
+		},
+	)
+
+	checker := NewWebhookChecker(time.Hour, nil, nil, "", &exporters.WebhookExporter{})
+	checker.SetClient(client)
+	checker.StartChecking()
+
+	assertExpiresIn(t, "cert_exporter_webhook_expires_in_seconds", map[string]string{
+		"webhook_name":                  "injector",
+		"admission_review_version_name": "inject.example.com",
+		"cn":                            "injector-ca",
+	}, 20*24*time.Hour)
+	assertExpiresIn(t, "cert_exporter_webhook_expires_in_seconds", map[string]string{
+		"webhook_name":                  "policy",
+		"admission_review_version_name": "validate.example.com",
+		"cn":                            "policy-ca",
+	}, 40*24*time.Hour)
+}
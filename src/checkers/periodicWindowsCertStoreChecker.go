@@ -0,0 +1,71 @@
+package checkers
+
+import (
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/joe-elliott/cert-exporter/src/exporters"
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+// PeriodicWindowsCertStoreChecker is an object designed to check for the certs of the LocalMachine certificate stores
+// of a Windows node at a regular interval
+type PeriodicWindowsCertStoreChecker struct {
+	period   time.Duration
+	stores   []string
+	nodeName string
+	exporter *exporters.WindowsCertStoreExporter
+}
+
+// NewWindowsCertStoreChecker is a factory method that returns a new PeriodicWindowsCertStoreChecker.  It checks the
+// LocalMachine stores named by stores, e.g. My and Root.
+func NewWindowsCertStoreChecker(period time.Duration, stores []string, nodeName string, e *exporters.WindowsCertStoreExporter) *PeriodicWindowsCertStoreChecker {
+	return &PeriodicWindowsCertStoreChecker{
+		period:   period,
+		stores:   stores,
+		nodeName: nodeName,
+		exporter: e,
+	}
+}
+
+// StartChecking starts the periodic Windows certificate store check.  Most likely you want to run this as an
+// independent go routine.
+func (p *PeriodicWindowsCertStoreChecker) StartChecking() {
+	waitInitialDelay()
+	ticker := newTicker(p.period)
+	for {
+		glog.Info("Begin periodic check")
+
+		currentScan := startScan("windows")
+		p.exporter.BeginCycle()
+
+		for _, store := range p.stores {
+			glog.Infof("Reviewing the LocalMachine\\%v certificate store", store)
+
+			certs, err := readCertStore(store)
+			if err != nil {
+				glog.Errorf("Error reading the LocalMachine\\%v certificate store: %v", store, err)
+				currentScan.fail()
+				currentScan.recordError("", metrics.ReasonAPI)
+				continue
+			}
+
+			for _, cert := range certs {
+				err = p.exporter.ExportMetrics(cert, store, p.nodeName)
+				if err != nil {
+					glog.Errorf("Error exporting cert %v", err)
+					currentScan.recordError("", metrics.ReasonParse)
+				}
+			}
+		}
+
+		p.exporter.FinishCycle()
+		currentScan.finish()
+
+		if runOnce {
+			return
+		}
+		ticker.wait()
+	}
+}
@@ -0,0 +1,39 @@
+package checkers
+
+import (
+	"fmt"
+	"net/http"
+
+	"k8s.io/client-go/rest"
+)
+
+// readOnly makes every kubernetes client refuse requests that could modify the cluster
+var readOnly bool
+
+// SetReadOnly guarantees no checker sends anything but get, list and watch requests to the kubernetes API
+func SetReadOnly() {
+	readOnly = true
+}
+
+// restrictToReads wraps the transport of config so that, in read-only mode, only reads reach the API server
+func restrictToReads(config *rest.Config) {
+	if !readOnly {
+		return
+	}
+
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return readOnlyRoundTripper{rt}
+	})
+}
+
+type readOnlyRoundTripper struct {
+	next http.RoundTripper
+}
+
+// RoundTrip only passes on GET and HEAD requests, which get, list and watch are all made of
+func (r readOnlyRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return nil, fmt.Errorf("read-only mode: refusing %v %v", req.Method, req.URL.Path)
+	}
+	return r.next.RoundTrip(req)
+}
@@ -0,0 +1,24 @@
+package checkers
+
+import (
+	"context"
+	"time"
+)
+
+// requestTimeout bounds every request sent to the kubernetes API, so a hung connection fails the request instead of
+// stalling the scan.  0 disables the deadline.
+var requestTimeout = 30 * time.Second
+
+// SetRequestTimeout fails every request to the kubernetes API not answered within timeout.  0 disables the deadline.
+func SetRequestTimeout(timeout time.Duration) {
+	requestTimeout = timeout
+}
+
+// RequestContext returns a context for a single request to the kubernetes API, cancelled with ctx or once the request
+// timeout passes.  The returned cancel function must be called once the request is done.
+func RequestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if requestTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, requestTimeout)
+}
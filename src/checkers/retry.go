@@ -0,0 +1,62 @@
+package checkers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// listRetries is how many times a failed list request is retried within a scan, waiting listBackoff before the first
+// retry and twice as long before every further one, up to maxListBackoff
+var (
+	listRetries    = 3
+	listBackoff    = time.Second
+	maxListBackoff = 30 * time.Second
+)
+
+// SetListRetries retries list requests failing with transient errors up to retries times within the same scan, waiting
+// backoff before the first retry and doubling the wait every time.  0 retries disables retrying.
+func SetListRetries(retries int, backoff time.Duration) {
+	listRetries = retries
+	listBackoff = backoff
+}
+
+// retryList calls list until it succeeds, fails with an error retrying cannot fix or runs out of retries, and returns
+// its last error.  Every attempt gets a context with its own request timeout.
+func (s *scan) retryList(what string, list func(ctx context.Context) error) error {
+	backoff := listBackoff
+	for retry := 0; ; retry++ {
+		ctx, cancel := RequestContext(s.ctx)
+		err := list(ctx)
+		cancel()
+		if err == nil || retry >= listRetries || !transient(err) {
+			return err
+		}
+
+		glog.Warningf("Error listing %v, retrying in %v: %v", what, backoff, err)
+		metrics.ListRetriesTotal.WithLabelValues(s.checker).Inc()
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > maxListBackoff {
+			backoff = maxListBackoff
+		}
+	}
+}
+
+// transient reports whether a request failing with err may succeed when retried.  Errors without a response, e.g.
+// timeouts and refused connections, are transient, as are throttling and server errors.  Other API errors, e.g.
+// forbidden, fail the same way again.
+func transient(err error) bool {
+	var status apierrors.APIStatus
+	if !errors.As(err, &status) {
+		return true
+	}
+
+	code := status.Status().Code
+	return code >= http.StatusInternalServerError || code == http.StatusTooManyRequests
+}
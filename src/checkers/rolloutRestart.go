@@ -0,0 +1,144 @@
+package checkers
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/joe-elliott/cert-exporter/src/exporters"
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+// restartOnRotationAnnotation opts a Deployment or StatefulSet into being restarted when a secret it mounts rotates
+const restartOnRotationAnnotation = "cert-exporter.io/restart-on-rotation"
+
+// restartedAtAnnotation is the pod template annotation `kubectl rollout restart` sets to trigger a rollout
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+// restartOnRotation makes the secret checkers restart the opted-in workloads mounting a rotated secret
+var restartOnRotation bool
+
+// EnableRolloutRestarts makes the secret checkers restart, like `kubectl rollout restart`, every Deployment and
+// StatefulSet annotated with cert-exporter.io/restart-on-rotation: "true" that mounts a secret whose certs rotated
+func EnableRolloutRestarts() {
+	restartOnRotation = true
+}
+
+// rotationTracker detects rotated secrets by remembering the soonest notAfter of every secret between cycles
+type rotationTracker struct {
+	notAfters map[string]time.Time
+}
+
+// rotated returns the secrets whose soonest notAfter changed since the previous cycle, by namespace.  Secrets seen for
+// the first time are not rotated.
+func (r *rotationTracker) rotated(expiries []exporters.ObjectExpiry) map[string]map[string]bool {
+	rotated := map[string]map[string]bool{}
+	notAfters := make(map[string]time.Time, len(expiries))
+	for _, e := range expiries {
+		key := e.Namespace + "/" + e.Name
+		notAfters[key] = e.NotAfter
+		if previous, ok := r.notAfters[key]; ok && !previous.Equal(e.NotAfter) {
+			if rotated[e.Namespace] == nil {
+				rotated[e.Namespace] = map[string]bool{}
+			}
+			rotated[e.Namespace][e.Name] = true
+		}
+	}
+	r.notAfters = notAfters
+	return rotated
+}
+
+// restartRotatedWorkloads restarts the opted-in Deployments and StatefulSets mounting one of the rotated secrets
+func restartRotatedWorkloads(currentScan *scan, client kubernetes.Interface, rotated map[string]map[string]bool) {
+	for ns, secrets := range rotated {
+		ctx, cancel := RequestContext(currentScan.ctx)
+		deployments, err := client.AppsV1().Deployments(ns).List(ctx, metav1.ListOptions{})
+		cancel()
+		if err != nil {
+			glog.Errorf("Error requesting deployments %v", err)
+			currentScan.recordError(ns, metrics.ReasonAPI)
+		} else {
+			for _, d := range deployments.Items {
+				if d.Annotations[restartOnRotationAnnotation] != "true" || !mountsAny(d.Spec.Template.Spec, secrets) {
+					continue
+				}
+				restartWorkload(currentScan, ns, "Deployment", d.Name, func(ctx context.Context, data []byte) error {
+					_, err := client.AppsV1().Deployments(ns).Patch(ctx, d.Name, types.MergePatchType, data, metav1.PatchOptions{})
+					return err
+				})
+			}
+		}
+
+		ctx, cancel = RequestContext(currentScan.ctx)
+		statefulSets, err := client.AppsV1().StatefulSets(ns).List(ctx, metav1.ListOptions{})
+		cancel()
+		if err != nil {
+			glog.Errorf("Error requesting statefulsets %v", err)
+			currentScan.recordError(ns, metrics.ReasonAPI)
+		} else {
+			for _, s := range statefulSets.Items {
+				if s.Annotations[restartOnRotationAnnotation] != "true" || !mountsAny(s.Spec.Template.Spec, secrets) {
+					continue
+				}
+				restartWorkload(currentScan, ns, "StatefulSet", s.Name, func(ctx context.Context, data []byte) error {
+					_, err := client.AppsV1().StatefulSets(ns).Patch(ctx, s.Name, types.MergePatchType, data, metav1.PatchOptions{})
+					return err
+				})
+			}
+		}
+	}
+}
+
+// mountsAny reports whether a pod mounts one of the secrets as a volume, directly or projected
+func mountsAny(pod corev1.PodSpec, secrets map[string]bool) bool {
+	for _, volume := range pod.Volumes {
+		if volume.Secret != nil && secrets[volume.Secret.SecretName] {
+			return true
+		}
+		if volume.Projected == nil {
+			continue
+		}
+		for _, source := range volume.Projected.Sources {
+			if source.Secret != nil && secrets[source.Secret.Name] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// restartWorkload sets the restartedAt annotation of the pod template of a workload, patch applying a merge patch to it
+func restartWorkload(currentScan *scan, namespace, kind, name string, patch func(ctx context.Context, data []byte) error) {
+	data, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"template": map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]string{
+						restartedAtAnnotation: time.Now().Format(time.RFC3339),
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		glog.Errorf("Error encoding the restart of %v %v/%v: %v", kind, namespace, name, err)
+		return
+	}
+
+	ctx, cancel := RequestContext(currentScan.ctx)
+	defer cancel()
+	err = patch(ctx, data)
+	if err != nil {
+		glog.Errorf("Error restarting %v %v/%v: %v", kind, namespace, name, err)
+		currentScan.recordError(namespace, metrics.ReasonAPI)
+		return
+	}
+	glog.Infof("Restarted %v %v/%v as a secret it mounts rotated", kind, namespace, name)
+	metrics.RolloutRestartsTotal.WithLabelValues(namespace, kind).Inc()
+}
@@ -0,0 +1,95 @@
+package checkers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/joe-elliott/cert-exporter/src/exporters"
+)
+
+// rotationHistoryKey is the key of the configmap data holding the rotation history
+const rotationHistoryKey = "rotations.json"
+
+// ConfigMapRotationStore persists the rotation history of every cert as JSON in a configmap
+type ConfigMapRotationStore struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+}
+
+// NewConfigMapRotationStore is a factory method that returns a new ConfigMapRotationStore keeping the history in the
+// configmap name of namespace, which is created if it does not exist
+func NewConfigMapRotationStore(kubeconfigPath, namespace, name string) *ConfigMapRotationStore {
+	config, err := buildConfig(kubeconfigPath, "")
+	if err != nil {
+		glog.Fatalf("Error building kubeconfig: %s", err.Error())
+	}
+	restrictToReads(config)
+
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		glog.Fatalf("kubernetes.NewForConfig failed: %v", err)
+	}
+
+	return &ConfigMapRotationStore{client: client, namespace: namespace, name: name}
+}
+
+// Load returns the rotation history, empty if the configmap does not exist yet
+func (s *ConfigMapRotationStore) Load() (map[string]exporters.RotationRecord, error) {
+	ctx, cancel := RequestContext(context.Background())
+	defer cancel()
+	cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	records := map[string]exporters.RotationRecord{}
+	if data, ok := cm.Data[rotationHistoryKey]; ok {
+		if err := json.Unmarshal([]byte(data), &records); err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+// Save replaces the rotation history stored in the configmap
+func (s *ConfigMapRotationStore) Save(records map[string]exporters.RotationRecord) error {
+	data, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := RequestContext(context.Background())
+	cm, err := s.client.CoreV1().ConfigMaps(s.namespace).Get(ctx, s.name, metav1.GetOptions{})
+	cancel()
+
+	ctx, cancel = RequestContext(context.Background())
+	defer cancel()
+	if apierrors.IsNotFound(err) {
+		cm = &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: s.name, Namespace: s.namespace},
+			Data:       map[string]string{rotationHistoryKey: string(data)},
+		}
+		_, err = s.client.CoreV1().ConfigMaps(s.namespace).Create(ctx, cm, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if cm.Data == nil {
+		cm.Data = map[string]string{}
+	}
+	cm.Data[rotationHistoryKey] = string(data)
+	_, err = s.client.CoreV1().ConfigMaps(s.namespace).Update(ctx, cm, metav1.UpdateOptions{})
+	return err
+}
@@ -0,0 +1,9 @@
+package checkers
+
+// runOnce makes every checker return from StartChecking after its first check
+var runOnce bool
+
+// SetRunOnce makes StartChecking run a single check and return instead of checking periodically
+func SetRunOnce() {
+	runOnce = true
+}
@@ -0,0 +1,147 @@
+package checkers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+	"github.com/joe-elliott/cert-exporter/src/tracing"
+)
+
+// scan measures a single check of a checker and records whether it could read everything it scans
+type scan struct {
+	checker string
+	start   time.Time
+	failed  bool
+
+	// ctx carries the span of the scan, the parent of the spans of its steps
+	ctx  context.Context
+	span *tracing.Span
+
+	// objects, dataKeys and certs count what the scan covered per namespace, when tracked
+	objects  map[string]int
+	dataKeys map[string]int
+	certs    map[string]int
+	// failedKeys counts the data keys that could not be parsed per reason, when coverage is tracked
+	failedKeys map[string]int
+	// denied holds the namespaces the scan was not allowed to list in
+	denied map[string]bool
+	// unlisted holds the namespaces the scan failed to list in, the empty namespace for lists across all of them
+	unlisted map[string]bool
+}
+
+// scanListeners are called after every scan of every checker
+var scanListeners []func()
+
+// OnScanFinished calls f after every scan of every checker, once the metrics of the scan are up to date.  Scans of
+// different checkers may finish concurrently.
+func OnScanFinished(f func()) {
+	scanListeners = append(scanListeners, f)
+}
+
+func startScan(checker string) *scan {
+	ctx, span := tracing.Start(context.Background(), "scan", "checker", checker)
+	return &scan{checker: checker, start: time.Now(), ctx: ctx, span: span}
+}
+
+// fail marks the scan as failed.  Errors about a single cert do not fail a scan, only errors reading its source do.
+func (s *scan) fail() {
+	s.failed = true
+}
+
+// trackCoverage makes the scan publish the objects, data keys and certs it covered per namespace.  Namespaces scanned
+// explicitly are published even if nothing is found in them, so a selector dropping coverage shows up as zero.
+func (s *scan) trackCoverage(namespaces []string) {
+	s.objects, s.dataKeys, s.certs = map[string]int{}, map[string]int{}, map[string]int{}
+	s.failedKeys = map[string]int{}
+	for _, ns := range namespaces {
+		if ns != "" {
+			s.objects[ns], s.dataKeys[ns], s.certs[ns] = 0, 0, 0
+		}
+	}
+}
+
+func (s *scan) scannedObject(namespace string) {
+	s.objects[namespace]++
+}
+
+func (s *scan) scannedDataKey(namespace string) {
+	s.dataKeys[namespace]++
+}
+
+func (s *scan) parsedCerts(certs map[string]int) {
+	for ns, n := range certs {
+		s.certs[ns] += n
+	}
+}
+
+// listFailed records that listing in namespace failed even after retries
+func (s *scan) listFailed(namespace string) {
+	if s.unlisted == nil {
+		s.unlisted = map[string]bool{}
+	}
+	s.unlisted[namespace] = true
+}
+
+// unlistedNamespaces returns the namespaces the scan could not list in, because it failed or was not allowed to.  Their
+// objects keep the series of the last scan that listed them.
+func (s *scan) unlistedNamespaces() []string {
+	var namespaces []string
+	for ns := range s.unlisted {
+		namespaces = append(namespaces, ns)
+	}
+	for ns := range s.denied {
+		if !s.unlisted[ns] {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+// recordError counts an error of the checker.  namespace is empty for errors not related to a namespace.
+func (s *scan) recordError(namespace, reason string) {
+	metrics.RecordError(s.checker, namespace, reason)
+}
+
+// failedDataKey counts a data key that matched the include and exclude globs but could not be exported as an error of
+// the checker
+func (s *scan) failedDataKey(namespace, reason string) {
+	s.recordError(namespace, reason)
+	if s.failedKeys != nil {
+		s.failedKeys[reason]++
+	}
+}
+
+func (s *scan) finish() {
+	metrics.ScanFinished(s.checker, time.Since(s.start), s.failed)
+	if s.failed {
+		s.span.RecordError(errors.New("the checker could not read everything it scans"))
+	}
+	s.span.End()
+	if s.objects != nil {
+		metrics.SetScanCoverage(s.checker, s.objects, s.dataKeys, s.certs)
+
+		dataKeys := 0
+		for _, n := range s.dataKeys {
+			dataKeys += n
+		}
+		metrics.SetDataKeysFailed(s.checker, s.failedKeys, dataKeys)
+	}
+	s.publishDeniedNamespaces()
+
+	for _, f := range scanListeners {
+		f()
+	}
+}
+
+// checkerName names a checker of the secret or configmap checkers after its config profile and cluster, if any
+func checkerName(checker, profile, cluster string) string {
+	if profile != "" {
+		checker += ":" + profile
+	}
+	if cluster != "" {
+		checker += "@" + cluster
+	}
+	return checker
+}
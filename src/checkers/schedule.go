@@ -0,0 +1,79 @@
+package checkers
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// initialDelay postpones the first scan of every checker and periodJitter adds a random delay, up to its value, to the
+// first scan and to every period, so exporters restarted together do not scan in lockstep
+var (
+	initialDelay time.Duration
+	periodJitter time.Duration
+
+	jitterMutex  sync.Mutex
+	jitterSource = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// SetSchedule postpones the first scan of every checker by initial and adds a random delay of up to jitter to the
+// first scan and to every period
+func SetSchedule(initial, jitter time.Duration) {
+	initialDelay = initial
+	periodJitter = jitter
+}
+
+// jitter returns a random delay of up to periodJitter
+func jitter() time.Duration {
+	if periodJitter <= 0 {
+		return 0
+	}
+
+	jitterMutex.Lock()
+	defer jitterMutex.Unlock()
+	return time.Duration(jitterSource.Int63n(int64(periodJitter)))
+}
+
+// waitInitialDelay blocks until the first scan of a checker is due
+func waitInitialDelay() {
+	delay := initialDelay + jitter()
+	if delay > 0 {
+		glog.Infof("Delaying the first scan by %v", delay)
+		time.Sleep(delay)
+	}
+}
+
+// ticker fires once a period plus jitter after it was created or last reset.  Unlike time.Tick, it can be reset, e.g.
+// to postpone the periodic scan after a scan triggered otherwise.
+type ticker struct {
+	period time.Duration
+	timer  *time.Timer
+}
+
+func newTicker(period time.Duration) *ticker {
+	return &ticker{period: period, timer: time.NewTimer(period + jitter())}
+}
+
+// C delivers the tick.  Reset must be called after receiving from it to schedule the next one.
+func (t *ticker) C() <-chan time.Time {
+	return t.timer.C
+}
+
+// Reset schedules the next tick a period plus jitter from now, dropping a pending one
+func (t *ticker) Reset() {
+	if !t.timer.Stop() {
+		select {
+		case <-t.timer.C:
+		default:
+		}
+	}
+	t.timer.Reset(t.period + jitter())
+}
+
+// wait blocks until the next tick and schedules the one after it
+func (t *ticker) wait() {
+	<-t.timer.C
+	t.Reset()
+}
@@ -0,0 +1,11 @@
+package checkers
+
+// tlsSecretMode makes the secret checkers export kubernetes.io/tls secrets as a whole instead of matching their data keys
+// against the include and exclude globs
+var tlsSecretMode bool
+
+// EnableTLSSecretMode exports the tls.crt, tls.key and ca.crt of every kubernetes.io/tls secret by their role, with
+// dedicated metrics
+func EnableTLSSecretMode() {
+	tlsSecretMode = true
+}
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package checkers
+
+import (
+	"errors"
+)
+
+// readCertStore is only implemented with the CryptoAPI of Windows
+func readCertStore(store string) ([][]byte, error) {
+	return nil, errors.New("reading certificate stores is only supported on Windows")
+}
@@ -0,0 +1,51 @@
+package checkers
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+// constants of the CryptoAPI missing from syscall, see wincrypt.h and winerror.h
+const (
+	certStoreProvSystemW        = 10
+	certSystemStoreLocalMachine = 0x20000
+	certStoreOpenExistingFlag   = 0x4000
+	certStoreReadOnlyFlag       = 0x8000
+
+	cryptENotFound syscall.Errno = 0x80092004
+)
+
+// readCertStore returns the DER encoded certs of the LocalMachine system store named store, e.g. My or Root.  The store
+// is opened read-only and must exist.
+func readCertStore(store string) ([][]byte, error) {
+	name, err := syscall.UTF16PtrFromString(store)
+	if err != nil {
+		return nil, err
+	}
+
+	handle, err := syscall.CertOpenStore(certStoreProvSystemW, 0, 0, certSystemStoreLocalMachine|certStoreOpenExistingFlag|certStoreReadOnlyFlag, uintptr(unsafe.Pointer(name)))
+	if err != nil {
+		return nil, err
+	}
+	defer syscall.CertCloseStore(handle, 0)
+
+	var certs [][]byte
+	var ctx *syscall.CertContext
+	for {
+		ctx, err = syscall.CertEnumCertificatesInStore(handle, ctx)
+		if ctx == nil {
+			// the enumeration ends with CRYPT_E_NOT_FOUND, or ERROR_NO_MORE_FILES for some stores
+			var errno syscall.Errno
+			if errors.As(err, &errno) && (errno == cryptENotFound || errno == syscall.ERROR_NO_MORE_FILES) {
+				return certs, nil
+			}
+			return nil, err
+		}
+
+		// the context is freed by the next call, so the cert is copied
+		if ctx.EncodingType&syscall.X509_ASN_ENCODING != 0 {
+			certs = append(certs, append([]byte(nil), unsafe.Slice(ctx.EncodedCert, ctx.Length)...))
+		}
+	}
+}
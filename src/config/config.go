@@ -0,0 +1,180 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"gopkg.in/yaml.v2"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Config is the content of the file passed with --config
+type Config struct {
+	Profiles     []Profile     `yaml:"profiles"`
+	TrustBundles []TrustBundle `yaml:"trustBundles"`
+	Clusters     []Cluster     `yaml:"clusters"`
+	Outputs      []Output      `yaml:"outputs"`
+}
+
+// Profile is a named set of checkers running next to the ones configured with flags, with their own selectors,
+// namespaces, period and copied labels.  Metrics of a profile are labeled with its name.
+type Profile struct {
+	Name          string        `yaml:"name"`
+	PollingPeriod time.Duration `yaml:"pollingPeriod"`
+	CopyLabels    []string      `yaml:"copyLabels"`
+	Secrets       *Selection    `yaml:"secrets"`
+	ConfigMaps    *Selection    `yaml:"configMaps"`
+}
+
+// Selection selects the objects a checker of a profile scans and the data keys it exports
+type Selection struct {
+	// LabelSelectors are selector strings objects match any of.  Load appends the selector compiled from MatchLabels
+	// and MatchExpressions, if any.
+	LabelSelectors []string `yaml:"labelSelectors"`
+	// MatchLabels and MatchExpressions select objects like the label selectors of kubernetes objects do.  An object
+	// must match all of them.
+	MatchLabels         map[string]string     `yaml:"matchLabels"`
+	MatchExpressions    []SelectorRequirement `yaml:"matchExpressions"`
+	AnnotationSelectors []string              `yaml:"annotationSelectors"`
+	Namespaces          []string              `yaml:"namespaces"`
+	IncludeGlobs        []string              `yaml:"includeGlobs"`
+	ExcludeGlobs        []string              `yaml:"excludeGlobs"`
+	IncludeTypes        []string              `yaml:"includeTypes"`
+	ExcludeTypes        []string              `yaml:"excludeTypes"`
+}
+
+// SelectorRequirement is a matchExpressions entry.  Operator is In, NotIn, Exists or DoesNotExist.
+type SelectorRequirement struct {
+	Key      string   `yaml:"key"`
+	Operator string   `yaml:"operator"`
+	Values   []string `yaml:"values"`
+}
+
+// Cluster is another cluster whose secrets and configmaps are scanned from this instance.  Metrics of a cluster are labeled
+// with its name.
+type Cluster struct {
+	Name string `yaml:"name"`
+	// Kubeconfig is the path of the kubeconfig to reach the cluster with.  Empty uses --kubeconfig.
+	Kubeconfig string `yaml:"kubeconfig"`
+	// Context is the context of the kubeconfig to use.  Empty uses its current context.
+	Context       string        `yaml:"context"`
+	PollingPeriod time.Duration `yaml:"pollingPeriod"`
+	Secrets       *Selection    `yaml:"secrets"`
+	ConfigMaps    *Selection    `yaml:"configMaps"`
+}
+
+// TrustBundle selects the CAs chains of secret and configmap certs are verified against with --verify-chains.  The first
+// bundle matching both the namespace and the annotations of an object is used.
+type TrustBundle struct {
+	// Namespaces are globs of the namespaces the bundle applies to.  Empty matches every namespace.
+	Namespaces []string `yaml:"namespaces"`
+	// Annotations must all be set to the given values on the object.  Empty matches every object.
+	Annotations map[string]string `yaml:"annotations"`
+	// CABundle is the PEM file holding the CAs
+	CABundle string `yaml:"caBundle"`
+}
+
+// Output is an output registered by name the results of every scan are written to, next to the ones configured with
+// flags
+type Output struct {
+	Name string `yaml:"name"`
+	// Settings are passed to the factory of the output, e.g. the url of remote-write
+	Settings map[string]string `yaml:"settings"`
+}
+
+// Load reads and validates the config file
+func Load(file string) (*Config, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Config{}
+	err = yaml.UnmarshalStrict(data, c)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]bool{}
+	for _, p := range c.Profiles {
+		if p.Name == "" {
+			return nil, fmt.Errorf("every profile needs a name")
+		}
+		if names[p.Name] {
+			return nil, fmt.Errorf("profile %v is defined twice", p.Name)
+		}
+		names[p.Name] = true
+
+		if p.Secrets == nil && p.ConfigMaps == nil {
+			return nil, fmt.Errorf("profile %v scans neither secrets nor configMaps", p.Name)
+		}
+		if err := compileSelections(p.Secrets, p.ConfigMaps); err != nil {
+			return nil, fmt.Errorf("profile %v: %v", p.Name, err)
+		}
+	}
+
+	clusters := map[string]bool{}
+	for _, cluster := range c.Clusters {
+		if cluster.Name == "" {
+			return nil, fmt.Errorf("every cluster needs a name")
+		}
+		if clusters[cluster.Name] {
+			return nil, fmt.Errorf("cluster %v is defined twice", cluster.Name)
+		}
+		clusters[cluster.Name] = true
+
+		if cluster.Kubeconfig == "" && cluster.Context == "" {
+			return nil, fmt.Errorf("cluster %v sets neither kubeconfig nor context", cluster.Name)
+		}
+		if cluster.Secrets == nil && cluster.ConfigMaps == nil {
+			return nil, fmt.Errorf("cluster %v scans neither secrets nor configMaps", cluster.Name)
+		}
+		if err := compileSelections(cluster.Secrets, cluster.ConfigMaps); err != nil {
+			return nil, fmt.Errorf("cluster %v: %v", cluster.Name, err)
+		}
+	}
+
+	for i, b := range c.TrustBundles {
+		if b.CABundle == "" {
+			return nil, fmt.Errorf("trust bundle %d has no caBundle", i)
+		}
+		if len(b.Namespaces) == 0 && len(b.Annotations) == 0 {
+			return nil, fmt.Errorf("trust bundle %v selects neither namespaces nor annotations", b.CABundle)
+		}
+	}
+
+	for i, o := range c.Outputs {
+		if o.Name == "" {
+			return nil, fmt.Errorf("output %d has no name", i)
+		}
+	}
+
+	return c, nil
+}
+
+// compileSelections appends the selector string of the matchLabels and matchExpressions of every selection to its label
+// selectors
+func compileSelections(selections ...*Selection) error {
+	for _, s := range selections {
+		if s == nil || len(s.MatchLabels)+len(s.MatchExpressions) == 0 {
+			continue
+		}
+
+		labelSelector := &metav1.LabelSelector{MatchLabels: s.MatchLabels}
+		for _, r := range s.MatchExpressions {
+			labelSelector.MatchExpressions = append(labelSelector.MatchExpressions, metav1.LabelSelectorRequirement{
+				Key:      r.Key,
+				Operator: metav1.LabelSelectorOperator(r.Operator),
+				Values:   r.Values,
+			})
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+		if err != nil {
+			return fmt.Errorf("invalid matchLabels or matchExpressions: %v", err)
+		}
+		s.LabelSelectors = append(s.LabelSelectors, selector.String())
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package exporters
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// archiveMaxBytes bounds the uncompressed size of the members read from an archive.  0 disables archive expansion.
+var archiveMaxBytes int64
+
+// EnableArchiveExpansion exports the certs of the members of .tar, .tar.gz and .zip archives found in secret data,
+// reading at most maxBytes uncompressed bytes per archive
+func EnableArchiveExpansion(maxBytes int64) {
+	archiveMaxBytes = maxBytes
+}
+
+// archiveMember is a regular file of an archive
+type archiveMember struct {
+	path string
+	data []byte
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zipMagic  = []byte("PK\x03\x04")
+	tarMagic  = []byte("ustar")
+)
+
+// IsArchive returns true if archive expansion is enabled and the provided bytes are a tar, gzipped tar or zip archive
+func IsArchive(data []byte) bool {
+	if archiveMaxBytes <= 0 {
+		return false
+	}
+	return bytes.HasPrefix(data, gzipMagic) || bytes.HasPrefix(data, zipMagic) || isTar(data)
+}
+
+// isTar checks for the magic of the POSIX and GNU tar formats at the end of the first header
+func isTar(data []byte) bool {
+	return len(data) >= 262 && bytes.Equal(data[257:262], tarMagic)
+}
+
+// readArchive returns the regular files of a tar, gzipped tar or zip archive.  Archives inside the archive are not
+// expanded.
+func readArchive(data []byte) ([]archiveMember, error) {
+	budget := archiveMaxBytes
+	if bytes.HasPrefix(data, zipMagic) {
+		return readZip(data, &budget)
+	}
+
+	r := io.Reader(bytes.NewReader(data))
+	if bytes.HasPrefix(data, gzipMagic) {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return readTar(r, &budget)
+}
+
+func readTar(r io.Reader, budget *int64) ([]archiveMember, error) {
+	var members []archiveMember
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return members, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+
+		data, err := readBounded(tr, budget)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive member %v: %w", header.Name, err)
+		}
+		members = append(members, archiveMember{path: header.Name, data: data})
+	}
+}
+
+func readZip(data []byte, budget *int64) ([]archiveMember, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	var members []archiveMember
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open archive member %v: %w", f.Name, err)
+		}
+		data, err := readBounded(rc, budget)
+		rc.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive member %v: %w", f.Name, err)
+		}
+		members = append(members, archiveMember{path: f.Name, data: data})
+	}
+	return members, nil
+}
+
+// readBounded reads r, failing once more than budget bytes were read.  The declared sizes of archive members and gzip
+// streams are not trusted.
+func readBounded(r io.Reader, budget *int64) ([]byte, error) {
+	limit := *budget
+	data, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, errors.New("data is larger than the uncompressed size limit")
+	}
+	*budget -= int64(len(data))
+	return data, nil
+}
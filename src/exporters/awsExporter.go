@@ -16,12 +16,21 @@ func (c *AwsExporter) ExportMetrics(file, secretName, key string) error {
 	}
 
 	for _, metric := range metricCollection {
-		metrics.AwsCertExpirySeconds.WithLabelValues(secretName, key, file, metric.issuer, metric.cn).Set(metric.durationUntilExpiry)
+		setSeries(sourceAws, objectKey("", secretName), metrics.AwsCertExpirySeconds, metric.durationUntilExpiry, metric.labelValues(secretName, key, file, metric.issuer, metric.cn)...)
+		exportCommonMetrics(certSource{source: sourceAws, name: secretName, key: key}, metric)
 	}
 
 	return nil
 }
 
-func (c *AwsExporter) ResetMetrics() {
-	metrics.AwsCertExpirySeconds.Reset()
-}
\ No newline at end of file
+// BeginCycle is called before the AWS secrets of a cycle are exported.  Metrics are not reset, so scrapes during a scan
+// still see every series of the previous one.
+func (c *AwsExporter) BeginCycle() {
+	beginSeriesCycle(sourceAws)
+}
+
+// FinishCycle is called once every AWS secret of a cycle has been exported.  It deletes the series of AWS secrets
+// that disappeared or no longer hold the cert they exported.
+func (c *AwsExporter) FinishCycle() {
+	deleteStaleSeries(sourceAws)
+}
@@ -0,0 +1,44 @@
+package exporters
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+const sourceBootstrapToken = "bootstrap-token"
+
+// BootstrapTokenExporter exports the expiration of the bootstrap tokens nodes join the cluster with
+type BootstrapTokenExporter struct {
+}
+
+// ExportMetrics exports the expiration of a bootstrap token secret, the RFC 3339 timestamp of its expiration key
+func (c *BootstrapTokenExporter) ExportMetrics(expiration []byte, secretName, tokenID string) error {
+	expiresAt, err := time.Parse(time.RFC3339, string(expiration))
+	if err != nil {
+		return fmt.Errorf("failed to parse the expiration of bootstrap token %v: %w", tokenID, err)
+	}
+
+	setSeries(sourceBootstrapToken, objectKey("", secretName), metrics.BootstrapTokenExpirySeconds, expiresAt.Sub(Now()).Seconds(), tokenID, secretName)
+	setSeries(sourceBootstrapToken, objectKey("", secretName), metrics.BootstrapTokenExpirationTimestamp, float64(expiresAt.Unix()), tokenID, secretName)
+	return nil
+}
+
+// BeginCycle is called before the bootstrap tokens of a cycle are exported.  Metrics are not reset, so scrapes during a
+// scan still see every series of the previous one.
+func (c *BootstrapTokenExporter) BeginCycle() {
+	beginSeriesCycle(sourceBootstrapToken)
+}
+
+// KeepSeries keeps every series of the exporter until the next cycle, because the bootstrap tokens could not be listed
+// during this one.  It must be called before FinishCycle.
+func (c *BootstrapTokenExporter) KeepSeries() {
+	keepNamespaceSeries(sourceBootstrapToken, "")
+}
+
+// FinishCycle is called once every bootstrap token of a cycle has been exported.  It deletes the series of the tokens
+// that were deleted, e.g. by the token cleaner once expired.
+func (c *BootstrapTokenExporter) FinishCycle() {
+	deleteStaleSeries(sourceBootstrapToken)
+}
@@ -0,0 +1,33 @@
+package exporters
+
+import (
+	"sort"
+
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+// maxCertsPerKey caps the certs exported per data key.  0 means unlimited.
+var maxCertsPerKey int
+
+// SetMaxCertsPerKey exports only the n soonest expiring certs of every data key, e.g. of a truststore holding hundreds
+// of CAs.  0 means unlimited.
+func SetMaxCertsPerKey(n int) {
+	maxCertsPerKey = n
+}
+
+// capCerts returns the maxCertsPerKey soonest expiring of the certs parsed from a data key, counting the others as over
+// the limit.  The bundle of every cert is left untouched, so chains are still verified against all certs of the key.
+func capCerts(source, namespace string, metricCollection []certMetric) []certMetric {
+	if maxCertsPerKey <= 0 || len(metricCollection) <= maxCertsPerKey {
+		return metricCollection
+	}
+
+	soonest := make([]certMetric, len(metricCollection))
+	copy(soonest, metricCollection)
+	sort.SliceStable(soonest, func(i, j int) bool {
+		return soonest[i].notAfter < soonest[j].notAfter
+	})
+
+	metrics.CertsOverLimitTotal.WithLabelValues(source, namespace).Add(float64(len(soonest) - maxCertsPerKey))
+	return soonest[:maxCertsPerKey]
+}
@@ -6,24 +6,40 @@ import (
 
 // CertExporter exports PEM file certs
 type CertExporter struct {
+	// Passwords are the sources of the passwords of PKCS12 and JKS files.  Files no source matches are opened without one.
+	Passwords []FilePassword
 }
 
 // ExportMetrics exports the provided PEM file
 func (c *CertExporter) ExportMetrics(file, nodeName string) error {
-	metricCollection, err := secondsToExpiryFromCertAsFile(file)
+	password, err := passwordForFile(c.Passwords, file)
+	if err != nil {
+		return err
+	}
+
+	metricCollection, err := secondsToExpiryFromCertAsFile(file, password)
 	if err != nil {
 		return err
 	}
 
 	for _, metric := range metricCollection {
-		metrics.CertExpirySeconds.WithLabelValues(file, metric.issuer, metric.cn, nodeName).Set(metric.durationUntilExpiry)
-		metrics.CertNotAfterTimestamp.WithLabelValues(file, metric.issuer, metric.cn, nodeName).Set(metric.notAfter)
+		setSeries(sourceFile, objectKey("", file), metrics.CertExpirySeconds, metric.durationUntilExpiry, metric.fileLabelValues(file, file, metric.issuer, metric.cn, nodeName)...)
+		setSeries(sourceFile, objectKey("", file), metrics.CertNotAfterTimestamp, metric.notAfter, metric.fileLabelValues(file, file, metric.issuer, metric.cn, nodeName)...)
+		setSeries(sourceFile, objectKey("", file), metrics.CertNotBeforeTimestamp, metric.notBefore, metric.fileLabelValues(file, file, metric.issuer, metric.cn, nodeName)...)
+		exportCommonMetrics(certSource{source: sourceFile, name: file}, metric)
 	}
 
 	return nil
 }
 
-func (c *CertExporter) ResetMetrics() {
-	metrics.CertExpirySeconds.Reset()
-	metrics.CertNotAfterTimestamp.Reset()
-}
\ No newline at end of file
+// BeginCycle is called before the files of a cycle are exported.  Metrics are not reset, so scrapes during a scan
+// still see every series of the previous one.
+func (c *CertExporter) BeginCycle() {
+	beginSeriesCycle(sourceFile)
+}
+
+// FinishCycle is called once every file of a cycle has been exported.  It deletes the series of files
+// that disappeared or no longer hold the cert they exported.
+func (c *CertExporter) FinishCycle() {
+	deleteStaleSeries(sourceFile)
+}
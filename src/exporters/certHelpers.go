@@ -1,9 +1,13 @@
 package exporters
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/pem"
@@ -11,23 +15,224 @@ import (
 
 	"github.com/golang/glog"
 	"github.com/lwithers/minijks/jks"
+	"k8s.io/utils/clock"
 	"software.sslmate.com/src/go-pkcs12"
+
+	"github.com/joe-elliott/cert-exporter/src/metrics"
 )
 
+// errIncorrectPassword is wrapped by the errors of certs and keys that could not be decrypted with the provided password
+var errIncorrectPassword = errors.New("incorrect password")
+
+// ErrorReason classifies an error returned while exporting a cert or key for cert_exporter_errors_total
+func ErrorReason(err error) string {
+	if errors.Is(err, errIncorrectPassword) || errors.Is(err, x509.IncorrectPasswordError) || errors.Is(err, pkcs12.ErrIncorrectPassword) || errors.Is(err, pkcs12.ErrDecryption) {
+		return metrics.ReasonPassword
+	}
+	return metrics.ReasonParse
+}
+
 type certMetric struct {
 	durationUntilExpiry float64
 	notAfter            float64
+	notBefore           float64
 	issuer              string
 	cn                  string
+	cert                *x509.Certificate
+	// bundle holds every cert parsed from the same data, including cert
+	bundle []*x509.Certificate
+}
+
+// optionalCertLabels compute the values of the optional labels added to every expiry metric, in the order they were added
+var optionalCertLabels []func(cert *x509.Certificate) string
+
+func addCertLabel(name string, value func(cert *x509.Certificate) string) {
+	metrics.AddCertLabel(name)
+	optionalCertLabels = append(optionalCertLabels, value)
 }
 
-func secondsToExpiryFromCertAsFile(file string) ([]certMetric, error) {
+// EnableSerialLabel labels every expiry metric with the hex serial number of the cert, so rotations show up as new
+// series.  It must be called before metrics.Init.
+func EnableSerialLabel() {
+	addCertLabel("serial", func(cert *x509.Certificate) string {
+		return cert.SerialNumber.Text(16)
+	})
+}
+
+// EnableFingerprintLabel labels every expiry metric with the hex SHA-256 fingerprint of the DER encoded cert.  It must be
+// called before metrics.Init.
+func EnableFingerprintLabel() {
+	addCertLabel("fingerprint", func(cert *x509.Certificate) string {
+		return fmt.Sprintf("%x", sha256.Sum256(cert.Raw))
+	})
+}
+
+// EnableSubjectLabel labels every expiry metric with the full subject distinguished name of the cert, e.g.
+// CN=api,OU=payments,O=Example.  It must be called before metrics.Init.
+func EnableSubjectLabel() {
+	addCertLabel("subject", func(cert *x509.Certificate) string {
+		return cert.Subject.String()
+	})
+}
+
+// copiedLabelKeys and copiedAnnotationKeys are the keys of the secret and configmap labels and annotations copied onto
+// their metrics
+var (
+	copiedLabelKeys      = []string{"serviceline"}
+	copiedAnnotationKeys []string
+)
+
+// SetCopiedLabels copies the values of the provided secret and configmap labels and annotations onto their metrics.  Keys
+// are sanitized into valid label names.  It must be called before metrics.Init.
+func SetCopiedLabels(labelKeys, annotationKeys []string) error {
+	names := make([]string, 0, len(labelKeys)+len(annotationKeys))
+	for _, key := range append(append([]string{}, labelKeys...), annotationKeys...) {
+		names = append(names, metrics.SanitizeLabelName(key))
+	}
+
+	err := metrics.SetCopiedLabels(names)
+	if err != nil {
+		return err
+	}
+
+	copiedLabelKeys = labelKeys
+	copiedAnnotationKeys = annotationKeys
+	return nil
+}
+
+// profileLabelEnabled labels the secret and configmap metrics with the profile of the checker that exported them
+var profileLabelEnabled = false
+
+// EnableProfileLabel labels the secret and configmap metrics with the config profile that exported them.  It must be
+// called before metrics.Init.
+func EnableProfileLabel() {
+	metrics.EnableProfileLabel()
+	profileLabelEnabled = true
+}
+
+// clusterLabelEnabled labels the secret and configmap metrics with the cluster of the checker that exported them
+var clusterLabelEnabled = false
+
+// EnableClusterLabel labels the secret and configmap metrics with the cluster they were found in, empty for the cluster
+// cert-exporter runs in unless its name is set.  It must be called before metrics.Init.
+func EnableClusterLabel() {
+	metrics.EnableClusterLabel()
+	clusterLabelEnabled = true
+}
+
+// localClusterName is the cluster label of the certs found in the cluster cert-exporter runs in
+var localClusterName string
+
+// SetClusterName labels every metric with the cluster cert-exporter runs in.  It must be called before metrics.Init.
+func SetClusterName(name string) {
+	metrics.SetClusterName(name)
+	localClusterName = name
+}
+
+// copiedLabelValues returns the profile and cluster, when labeled, and the values of the copied labels and annotations
+// of an object.  Labels the object does not have, or that are not in copyLabels, are empty.  A nil copyLabels copies
+// every label.
+func copiedLabelValues(profile, cluster string, copyLabels []string, labels, annotations map[string]string) []string {
+	values := make([]string, 0, len(copiedLabelKeys)+len(copiedAnnotationKeys)+2)
+	if profileLabelEnabled {
+		values = append(values, profile)
+	}
+	if clusterLabelEnabled {
+		if cluster == "" {
+			cluster = localClusterName
+		}
+		values = append(values, cluster)
+	}
+	for _, key := range copiedLabelKeys {
+		if copyLabels != nil && !contains(copyLabels, key) {
+			values = append(values, "")
+			continue
+		}
+		values = append(values, labels[key])
+	}
+	for _, key := range copiedAnnotationKeys {
+		values = append(values, annotations[key])
+	}
+	return values
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// labelValues returns the provided label values followed by the values of the optional cert labels
+func (m certMetric) labelValues(values ...string) []string {
+	values = append([]string{}, values...)
+	for _, value := range optionalCertLabels {
+		values = append(values, value(m.cert))
+	}
+	return values
+}
+
+// objectLabelValues returns the provided label values followed by the labels copied from the secret or configmap the
+// cert was found in and the values of the optional cert labels
+func (m certMetric) objectLabelValues(objectLabels []string, values ...string) []string {
+	return m.labelValues(append(values, objectLabels...)...)
+}
+
+// timeOffset shifts the time expiry metrics are computed against.  It is only set for fire drills.
+var timeOffset time.Duration
+
+// expiryClock is the clock expiry metrics are computed against
+var expiryClock clock.PassiveClock = clock.RealClock{}
+
+// SetClock computes all expiry metrics against c instead of the real time, e.g. a fake clock in tests of programs
+// embedding the checkers.  It must be called before SetPretendNow.
+func SetClock(c clock.PassiveClock) {
+	expiryClock = c
+}
+
+// SetPretendNow computes all expiry metrics as if the current time were t.  The clock keeps ticking from t onwards.
+func SetPretendNow(t time.Time) {
+	timeOffset = t.Sub(expiryClock.Now())
+}
+
+// TimeOffset returns how far the clock used for expiry metrics is shifted from the real time
+func TimeOffset() time.Duration {
+	return timeOffset
+}
+
+// Now returns the time expiry metrics are computed against
+func Now() time.Time {
+	return expiryClock.Now().Add(timeOffset)
+}
+
+func secondsToExpiryFromCertAsFile(file, password string) ([]certMetric, error) {
 	certBytes, err := ioutil.ReadFile(file)
 	if err != nil {
 		return []certMetric{}, err
 	}
 
-	return secondsToExpiryFromCertAsBytes(certBytes, "")
+	return secondsToExpiryFromCertAsBytes(certBytes, password)
+}
+
+// ParseCertificateFile returns every certificate in a PEM, PKCS12 or JKS file
+func ParseCertificateFile(file, password string) ([]*x509.Certificate, error) {
+	certBytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics, err := secondsToExpiryFromCertAsBytes(certBytes, password)
+	if err != nil {
+		return nil, err
+	}
+
+	certs := make([]*x509.Certificate, 0, len(metrics))
+	for _, metric := range metrics {
+		certs = append(certs, metric.cert)
+	}
+	return certs, nil
 }
 
 func secondsToExpiryFromCertAsBase64String(s string) ([]certMetric, error) {
@@ -39,32 +244,160 @@ func secondsToExpiryFromCertAsBase64String(s string) ([]certMetric, error) {
 	return secondsToExpiryFromCertAsBytes(certBytes, "")
 }
 
+// doubleBase64Enabled makes data that is neither PEM, PKCS12 nor JKS be base64 decoded and parsed once more
+var doubleBase64Enabled = false
+
+// EnableDoubleBase64 tolerates certs that were base64 encoded once more before being stored, e.g. by operators writing
+// base64 into the data of a secret
+func EnableDoubleBase64() {
+	doubleBase64Enabled = true
+}
+
+// candidatePasswords are tried in order when a PKCS12 or JKS bundle cannot be opened with its own password
+var candidatePasswords []string
+
+// SetCandidatePasswords configures the passwords tried in order when a PKCS12 or JKS bundle cannot be opened with the
+// password looked up for it, e.g. "changeit" and the empty password
+func SetCandidatePasswords(passwords []string) {
+	candidatePasswords = passwords
+}
+
 func secondsToExpiryFromCertAsBytes(certBytes []byte, password string) ([]certMetric, error) {
+	if isGzip(certBytes) {
+		decompressed, err := gunzip(certBytes)
+		if err != nil {
+			return nil, err
+		}
+		certBytes = decompressed
+	}
+
+	certMetrics, err := parseWithCandidatePasswords(certBytes, password)
+	if err == nil || !doubleBase64Enabled || ErrorReason(err) == metrics.ReasonPassword {
+		return certMetrics, err
+	}
+
+	decoded, decodeErr := base64.StdEncoding.DecodeString(string(bytes.Join(bytes.Fields(certBytes), nil)))
+	if decodeErr != nil {
+		return nil, err
+	}
+	return parseWithCandidatePasswords(decoded, password)
+}
+
+// parseWithCandidatePasswords parses the certs with password, then with every candidate password in turn for as long as
+// the password is wrong.  Every candidate tried is counted by result, so bad password conventions can be cleaned up.
+func parseWithCandidatePasswords(certBytes []byte, password string) ([]certMetric, error) {
+	certMetrics, err := parseCertificateBytes(certBytes, password)
+	for i, candidate := range candidatePasswords {
+		if err == nil || ErrorReason(err) != metrics.ReasonPassword {
+			break
+		}
+		if candidate == password {
+			continue
+		}
+
+		certMetrics, err = parseCertificateBytes(certBytes, candidate)
+		if err == nil {
+			metrics.CandidatePasswordAttempts.WithLabelValues(strconv.Itoa(i), "success").Inc()
+		} else {
+			metrics.CandidatePasswordAttempts.WithLabelValues(strconv.Itoa(i), "failure").Inc()
+		}
+	}
+	return certMetrics, err
+}
+
+// keepCert decides which of the certs parsed are exported.  nil exports every cert.
+var keepCert func(cert *x509.Certificate) bool
+
+// SkipRootCerts ignores self-signed CA certs, so bundles holding long-lived roots do not flood dashboards with expiries
+// decades away
+func SkipRootCerts() {
+	keepCert = func(cert *x509.Certificate) bool {
+		return !isRootCert(cert)
+	}
+}
+
+// KeepLeafCertsOnly ignores every CA cert, roots and intermediates alike
+func KeepLeafCertsOnly() {
+	keepCert = func(cert *x509.Certificate) bool {
+		return !cert.IsCA
+	}
+}
+
+// KeepCACertsOnly ignores every cert that is not a CA, for teams that only track CA rollovers
+func KeepCACertsOnly() {
+	keepCert = func(cert *x509.Certificate) bool {
+		return cert.IsCA
+	}
+}
+
+func isRootCert(cert *x509.Certificate) bool {
+	return cert.IsCA && bytes.Equal(cert.RawSubject, cert.RawIssuer) && cert.CheckSignatureFrom(cert) == nil
+}
+
+// keptCerts drops the certs the cert filter ignores.  Their bundle still holds every cert parsed.
+func keptCerts(metrics []certMetric) []certMetric {
+	if keepCert == nil {
+		return metrics
+	}
+
+	kept := make([]certMetric, 0, len(metrics))
+	for _, metric := range metrics {
+		if metric.cert == nil || keepCert(metric.cert) {
+			kept = append(kept, metric)
+		}
+	}
+	return kept
+}
+
+// parseCertificateBytes parses the certs in PEM, PKCS7, PKCS12 or JKS data
+func parseCertificateBytes(certBytes []byte, password string) ([]certMetric, error) {
 	var metrics []certMetric
 
 	parsed, metrics, err := parseAsPEM(certBytes)
 	if parsed {
-		return metrics, err
+		return keptCerts(withBundle(metrics)), err
+	}
+	// Parse as a DER encoded PKCS7 bundle, e.g. a .p7b file
+	parsed, metrics, err = parseAsPKCS7(certBytes)
+	if parsed {
+		return keptCerts(withBundle(metrics)), err
 	}
 	// Parse as PKCS
 	parsed, metrics, err = parseAsPKCS(certBytes, password)
 	if parsed {
-		return metrics, nil
+		return keptCerts(withBundle(metrics)), nil
+	}
+	if errors.Is(err, pkcs12.ErrIncorrectPassword) || errors.Is(err, pkcs12.ErrDecryption) {
+		return nil, fmt.Errorf("failed to parse as pkcs12: %w", err)
 	}
 	// Parse as JKS
 	parsed, metrics, err = parseAsJKS(certBytes, password)
 	if parsed {
-		return metrics, nil
+		return keptCerts(withBundle(metrics)), nil
 	}
-	return nil, fmt.Errorf("failed to parse as pem, pkcs12 or jks: %w", err)
+	return nil, fmt.Errorf("failed to parse as pem, pkcs7, pkcs12 or jks: %w", err)
+}
+
+// withBundle records the certs parsed together in every one of their metrics
+func withBundle(metrics []certMetric) []certMetric {
+	bundle := make([]*x509.Certificate, 0, len(metrics))
+	for _, metric := range metrics {
+		bundle = append(bundle, metric.cert)
+	}
+	for i := range metrics {
+		metrics[i].bundle = bundle
+	}
+	return metrics
 }
 
 func getCertificateMetrics(cert *x509.Certificate) certMetric {
 	var metric certMetric
 	metric.notAfter = float64(cert.NotAfter.Unix())
-	metric.durationUntilExpiry = time.Until(cert.NotAfter).Seconds()
+	metric.notBefore = float64(cert.NotBefore.Unix())
+	metric.durationUntilExpiry = cert.NotAfter.Sub(Now()).Seconds()
 	metric.issuer = cert.Issuer.CommonName
 	metric.cn = cert.Subject.CommonName
+	metric.cert = cert
 	return metric
 }
 
@@ -110,11 +443,23 @@ func parseAsPEM(certBytes []byte) (bool, []certMetric, error) {
 		if block == nil {
 			return true, metrics, fmt.Errorf("Failed to parse intermediate as a pem")
 		}
-		if block.Type == "CERTIFICATE" {
+		if block.Type == "CERTIFICATE" || block.Type == "PKCS7" {
 			blocks = append(blocks, block)
 		}
 	}
 	for _, block := range blocks {
+		// PEM encoded PKCS7 bundles, e.g. .p7b files exported by Windows CAs, hold several certs
+		if block.Type == "PKCS7" {
+			certs, err := parsePKCS7Certificates(block.Bytes)
+			if err != nil {
+				return true, metrics, err
+			}
+			for _, cert := range certs {
+				metrics = append(metrics, getCertificateMetrics(cert))
+			}
+			continue
+		}
+
 		cert, err := x509.ParseCertificate(block.Bytes)
 		if err != nil {
 			return true, metrics, err
@@ -132,6 +477,10 @@ func parseAsJKS(certBytes []byte, password string) (bool, []certMetric, error) {
 	jks, err := jks.Parse(certBytes, &jks.Options{Password: password})
 	if err != nil {
 		glog.Errorf("Failed to parse as a jks: %v", err)
+		// the digest of a key store is keyed with its password
+		if err.Error() == "digest mismatch" {
+			err = fmt.Errorf("%w: %v", errIncorrectPassword, err)
+		}
 		return false, nil, err
 	}
 	certChain := jks.Keypairs[0].CertChain
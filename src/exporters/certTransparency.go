@@ -0,0 +1,47 @@
+package exporters
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+)
+
+var ctCheckEnabled = false
+
+// sctListOID identifies the extension holding the signed certificate timestamps embedded by the CA
+var sctListOID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 11129, 2, 4, 2}
+
+// EnableCTCheck exports cert_exporter_cert_ct_logged for every leaf cert, telling whether the CA embedded signed
+// certificate timestamps, i.e. submitted the cert to Certificate Transparency logs.  The timestamps are not verified
+// against the logs.
+func EnableCTCheck() {
+	ctCheckEnabled = true
+}
+
+// embeddedSCTCount returns the number of signed certificate timestamps embedded in the cert
+func embeddedSCTCount(cert *x509.Certificate) int {
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(sctListOID) {
+			continue
+		}
+
+		var list []byte
+		if _, err := asn1.Unmarshal(ext.Value, &list); err != nil || len(list) < 2 {
+			return 0
+		}
+
+		// a SignedCertificateTimestampList is a list of opaque SCTs, each prefixed with its 16 bit length
+		list = list[2:]
+		n := 0
+		for len(list) >= 2 {
+			length := int(binary.BigEndian.Uint16(list))
+			if length == 0 || len(list) < 2+length {
+				break
+			}
+			list = list[2+length:]
+			n++
+		}
+		return n
+	}
+	return 0
+}
@@ -0,0 +1,161 @@
+package exporters
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"path/filepath"
+)
+
+var (
+	chainVerificationEnabled = false
+	chainCompletenessEnabled = false
+	// verificationRoots are the CAs chains are verified against.  nil verifies against the system roots.
+	verificationRoots *x509.CertPool
+)
+
+// trustBundle replaces verificationRoots for the secrets and configmaps it selects
+type trustBundle struct {
+	namespaces  []string
+	annotations map[string]string
+	roots       *x509.CertPool
+}
+
+var trustBundles []trustBundle
+
+// AddTrustBundle verifies the chains of certs in secrets and configmaps in a namespace matching one of the namespace
+// globs, and annotated with all of annotations, against roots.  Empty globs or annotations match everything.  Bundles are
+// tried in the order they were added.
+func AddTrustBundle(namespaces []string, annotations map[string]string, roots *x509.CertPool) {
+	trustBundles = append(trustBundles, trustBundle{namespaces: namespaces, annotations: annotations, roots: roots})
+}
+
+func (b trustBundle) matches(src certSource) bool {
+	for key, value := range b.annotations {
+		if src.annotations[key] != value {
+			return false
+		}
+	}
+
+	if len(b.namespaces) == 0 {
+		return true
+	}
+	for _, glob := range b.namespaces {
+		if ok, _ := filepath.Match(glob, src.namespace); ok && src.namespace != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// rootsFor returns the CAs the chains of certs found in src are verified against
+func rootsFor(src certSource) *x509.CertPool {
+	for _, b := range trustBundles {
+		if b.matches(src) {
+			return b.roots
+		}
+	}
+	return verificationRoots
+}
+
+// EnableChainVerification exports cert_exporter_cert_verified for every leaf cert, verifying its chain against roots, or
+// the system roots if nil.  Intermediates are taken from the certs stored next to the leaf.
+func EnableChainVerification(roots *x509.CertPool) {
+	chainVerificationEnabled = true
+	verificationRoots = roots
+}
+
+// EnableChainCompletenessCheck exports cert_exporter_cert_chain_incomplete for every leaf cert, telling whether the
+// certs stored next to it complete its chain up to roots, or the system roots if nil
+func EnableChainCompletenessCheck(roots *x509.CertPool) {
+	chainCompletenessEnabled = true
+	verificationRoots = roots
+}
+
+// missingIssuer follows the chain of a leaf cert through the certs stored next to it, and those of src, and returns the
+// issuer of the cert it stops at, or "" if a root of its source signed one of them.  Unlike verifyChain it ignores
+// validity periods and usages, so it only fails on intermediates that are missing.
+func missingIssuer(src certSource, metric certMetric) string {
+	candidates := append(append([]*x509.Certificate{}, metric.bundle...), src.chain...)
+	roots := rootsFor(src)
+
+	current := metric.cert
+	for i := 0; i <= len(candidates); i++ {
+		if anchored(current, roots) {
+			return ""
+		}
+
+		var parent *x509.Certificate
+		for _, candidate := range candidates {
+			if candidate != current && bytes.Equal(candidate.RawSubject, current.RawIssuer) && current.CheckSignatureFrom(candidate) == nil {
+				parent = candidate
+				break
+			}
+		}
+		if parent == nil {
+			break
+		}
+		current = parent
+	}
+	return current.Issuer.String()
+}
+
+// anchored reports whether cert is one of roots or was signed by one of them.  It is checked halfway through the
+// validity of cert, so an expired cert still counts as anchored.
+func anchored(cert *x509.Certificate, roots *x509.CertPool) bool {
+	_, err := cert.Verify(x509.VerifyOptions{
+		Roots:       roots,
+		CurrentTime: cert.NotBefore.Add(cert.NotAfter.Sub(cert.NotBefore) / 2),
+		KeyUsages:   []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	var unknownAuthority x509.UnknownAuthorityError
+	return !errors.As(err, &unknownAuthority)
+}
+
+// verifyChain verifies the chain of a leaf cert against the roots of its source and returns why it could not be
+// verified, or "" if it was
+func verifyChain(src certSource, metric certMetric) string {
+	intermediates := x509.NewCertPool()
+	for _, cert := range metric.bundle {
+		if cert != metric.cert {
+			intermediates.AddCert(cert)
+		}
+	}
+
+	_, err := metric.cert.Verify(x509.VerifyOptions{
+		Roots:         rootsFor(src),
+		Intermediates: intermediates,
+		CurrentTime:   Now(),
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return verificationFailureReason(err)
+}
+
+// verificationFailureReason turns a verification error into the reason label of cert_exporter_cert_verified
+func verificationFailureReason(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var unknownAuthority x509.UnknownAuthorityError
+	if errors.As(err, &unknownAuthority) {
+		return "unknown_authority"
+	}
+
+	var invalid x509.CertificateInvalidError
+	if errors.As(err, &invalid) {
+		switch invalid.Reason {
+		case x509.Expired:
+			return "expired"
+		case x509.NotAuthorizedToSign:
+			return "not_authorized_to_sign"
+		case x509.IncompatibleUsage:
+			return "incompatible_usage"
+		case x509.CANotAuthorizedForThisName, x509.CANotAuthorizedForExtKeyUsage:
+			return "constraint_violation"
+		}
+		return "invalid"
+	}
+
+	return "other"
+}
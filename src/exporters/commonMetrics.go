@@ -0,0 +1,257 @@
+package exporters
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+const (
+	sourceFile       = "file"
+	sourceKubeConfig = "kubeconfig"
+	sourceSecret     = "secret"
+	sourceConfigMap  = "configmap"
+	sourceWebhook    = "webhook"
+	sourceAws        = "aws"
+)
+
+// scopedSource names the source of a secret or configmap exporter after its profile and cluster, if any, e.g.
+// secret:team-a@spoke-1
+func scopedSource(source, profile, cluster string) string {
+	if profile != "" {
+		source += ":" + profile
+	}
+	if cluster != "" {
+		source += "@" + cluster
+	}
+	return source
+}
+
+// certSource identifies where a certificate was found.  It labels the metrics that are shared by all checkers.
+type certSource struct {
+	source    string
+	namespace string
+	name      string
+	key       string
+	// annotations of the secret or configmap the cert was found in
+	annotations map[string]string
+	// chain holds the other certs of the object that may complete the chain of the cert, e.g. ca.crt for tls.crt
+	chain []*x509.Certificate
+}
+
+func (s certSource) labelValues(metric certMetric, extra ...string) []string {
+	return append([]string{s.source, s.namespace, s.name, s.key, metric.issuer, metric.cn}, extra...)
+}
+
+// commonSeriesPerCert returns the number of series exportCommonMetrics sets for every cert.  Quotas count it on top of
+// the series each exporter sets itself.
+func commonSeriesPerCert() int {
+	n := 6
+	if certInfoEnabled {
+		n++
+	}
+	if lifetimeEnabled {
+		n += 3
+	}
+	if chainVerificationEnabled {
+		n++
+	}
+	if chainCompletenessEnabled {
+		n++
+	}
+	if ctCheckEnabled {
+		n++
+	}
+	if rotations != nil {
+		n += 2
+	}
+	return n
+}
+
+var (
+	minRSAKeySize   = 2048
+	minECDSAKeySize = 256
+	certInfoEnabled = false
+	lifetimeEnabled = false
+
+	warningThreshold  = 30 * 24 * time.Hour
+	criticalThreshold = 7 * 24 * time.Hour
+)
+
+// warningDaysAnnotation and criticalDaysAnnotation override the thresholds for the certs of a secret or configmap, e.g.
+// for short-lived mesh certs
+const (
+	warningDaysAnnotation  = "cert-exporter.io/warning-days"
+	criticalDaysAnnotation = "cert-exporter.io/critical-days"
+)
+
+// SetThresholds configures how long before expiry certs are flagged by cert_exporter_cert_warning and
+// cert_exporter_cert_critical
+func SetThresholds(warning, critical time.Duration) {
+	warningThreshold = warning
+	criticalThreshold = critical
+}
+
+// SetMinimumKeySizes configures the key sizes below which a certificate's key is flagged as too small
+func SetMinimumKeySizes(rsaBits, ecdsaBits int) {
+	minRSAKeySize = rsaBits
+	minECDSAKeySize = ecdsaBits
+}
+
+// EnableCertInfo exports cert_exporter_cert_info for every cert.  It is off by default as every cert gets its own series.
+func EnableCertInfo() {
+	certInfoEnabled = true
+}
+
+// EnableLifetimeMetrics exports the validity of every cert, its age and the share of its validity that has elapsed, so
+// alerts can be relative to the lifetime of a cert.  It is off by default as every cert gets three more series.
+func EnableLifetimeMetrics() {
+	lifetimeEnabled = true
+}
+
+func setCommonMetric(src certSource, vec *prometheus.GaugeVec, value float64, labelValues ...string) {
+	setSeries(src.source, objectKey(src.namespace, src.name), vec, value, labelValues...)
+}
+
+// exportCommonMetrics exports the metrics every checker publishes for a certificate, regardless of where it was found
+func exportCommonMetrics(src certSource, metric certMetric) {
+	if metric.cert == nil {
+		return
+	}
+
+	keyAlgorithm, keySize := publicKeyInfo(metric.cert)
+	setCommonMetric(src, metrics.CertKeyInfo, 1, src.labelValues(metric, keyAlgorithm, strconv.Itoa(keySize))...)
+
+	tooSmall := 0.0
+	if (keyAlgorithm == "RSA" && keySize < minRSAKeySize) || (keyAlgorithm == "ECDSA" && keySize < minECDSAKeySize) {
+		tooSmall = 1
+	}
+	setCommonMetric(src, metrics.CertKeyTooSmall, tooSmall, src.labelValues(metric, keyAlgorithm)...)
+
+	weakSignature := 0.0
+	if isWeakSignatureAlgorithm(metric.cert.SignatureAlgorithm) {
+		weakSignature = 1
+	}
+	setCommonMetric(src, metrics.CertWeakSignature, weakSignature, src.labelValues(metric, metric.cert.SignatureAlgorithm.String())...)
+
+	expired := 0.0
+	if metric.durationUntilExpiry <= 0 {
+		expired = 1
+	}
+	setCommonMetric(src, metrics.CertExpired, expired, src.labelValues(metric)...)
+
+	setCommonMetric(src, metrics.CertWarning, withinThreshold(metric, thresholdFor(src, warningDaysAnnotation, warningThreshold)), src.labelValues(metric)...)
+	setCommonMetric(src, metrics.CertCritical, withinThreshold(metric, thresholdFor(src, criticalDaysAnnotation, criticalThreshold)), src.labelValues(metric)...)
+
+	if lifetimeEnabled {
+		age := Now().Sub(metric.cert.NotBefore).Seconds()
+		validity := metric.cert.NotAfter.Sub(metric.cert.NotBefore).Seconds()
+		setCommonMetric(src, metrics.CertAgeSeconds, age, src.labelValues(metric)...)
+		setCommonMetric(src, metrics.CertValiditySeconds, validity, src.labelValues(metric)...)
+		if validity > 0 {
+			setCommonMetric(src, metrics.CertLifetimeUsedRatio, age/validity, src.labelValues(metric)...)
+		}
+	}
+
+	if chainVerificationEnabled && !metric.cert.IsCA {
+		reason := verifyChain(src, metric)
+		verified := 0.0
+		if reason == "" {
+			verified = 1
+		}
+		setCommonMetric(src, metrics.CertVerified, verified, src.labelValues(metric, reason)...)
+	}
+
+	if chainCompletenessEnabled && !metric.cert.IsCA {
+		missing := missingIssuer(src, metric)
+		incomplete := 0.0
+		if missing != "" {
+			incomplete = 1
+		}
+		setCommonMetric(src, metrics.CertChainIncomplete, incomplete, src.labelValues(metric, missing)...)
+	}
+
+	if ctCheckEnabled && !metric.cert.IsCA {
+		logged := 0.0
+		if embeddedSCTCount(metric.cert) > 0 {
+			logged = 1
+		}
+		setCommonMetric(src, metrics.CertCTLogged, logged, src.labelValues(metric)...)
+	}
+
+	if rotations != nil {
+		recordRotation(src, metric)
+	}
+
+	if certInfoEnabled {
+		setCommonMetric(src, metrics.CertInfo, 1, src.labelValues(metric, metric.cert.Subject.String(), metric.cert.Issuer.String(), strings.Join(subjectAltNames(metric.cert), ","), metric.cert.SerialNumber.Text(16))...)
+	}
+}
+
+// thresholdFor returns the threshold of the certs found in src, which the object can override with annotation
+func thresholdFor(src certSource, annotation string, threshold time.Duration) time.Duration {
+	value, ok := src.annotations[annotation]
+	if !ok {
+		return threshold
+	}
+
+	days, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || days < 0 {
+		metrics.RecordError(src.source, src.namespace, metrics.ReasonParse)
+		glog.Errorf("Invalid %v %q on %v/%v, using %v", annotation, value, src.namespace, src.name, threshold)
+		return threshold
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// withinThreshold returns 1 if the cert expires within threshold, 0 otherwise
+func withinThreshold(metric certMetric, threshold time.Duration) float64 {
+	if metric.durationUntilExpiry < threshold.Seconds() {
+		return 1
+	}
+	return 0
+}
+
+// subjectAltNames returns every DNS name, IP address, email address and URI the certificate is valid for
+func subjectAltNames(cert *x509.Certificate) []string {
+	sans := append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	return sans
+}
+
+// isWeakSignatureAlgorithm returns true for signature algorithms relying on the broken MD2, MD5 or SHA-1 hashes
+func isWeakSignatureAlgorithm(algorithm x509.SignatureAlgorithm) bool {
+	switch algorithm {
+	case x509.MD2WithRSA, x509.MD5WithRSA, x509.SHA1WithRSA, x509.DSAWithSHA1, x509.ECDSAWithSHA1:
+		return true
+	}
+	return false
+}
+
+// publicKeyInfo returns the algorithm and size in bits of the certificate's public key
+func publicKeyInfo(cert *x509.Certificate) (string, int) {
+	switch pub := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return "RSA", pub.N.BitLen()
+	case *ecdsa.PublicKey:
+		return "ECDSA", pub.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return "Ed25519", ed25519.PublicKeySize * 8
+	}
+	return cert.PublicKeyAlgorithm.String(), 0
+}
@@ -1,31 +1,187 @@
 package exporters
 
 import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"path/filepath"
+
+	"github.com/joe-elliott/cert-exporter/src/appconfig"
 	"github.com/joe-elliott/cert-exporter/src/metrics"
 )
 
 // ConfigMapExporter exports PEM file certs
 type ConfigMapExporter struct {
+	// ConfigFileRoot is the local directory cert paths referenced from application configs are resolved in when they
+	// are not stored in the configmap itself.  Empty disables reading referenced certs from disk.
+	ConfigFileRoot string
+	// Profile is the config profile the exporter runs for.  Empty for the checker configured with flags.
+	Profile string
+	// Cluster is the name of the cluster the exporter scans.  Empty for the cluster cert-exporter runs in.
+	Cluster string
+	// CopyLabels are the keys of the copied labels this exporter fills.  Nil fills every copied label.
+	CopyLabels []string
+
+	// certsParsed counts the certs parsed during the current cycle per namespace
+	certsParsed map[string]int
+	// expiries are the soonest notAfter of every configmap exported during the current cycle
+	expiries map[string]ObjectExpiry
+	// parsed remembers the certs parsed from unchanged data
+	parsed parseCache
 }
 
 // ExportMetrics exports the provided PEM file
-func (c *ConfigMapExporter) ExportMetrics(bytes []byte, keyName, configMapName, configMapNamespace, password string, labels map[string]string) error {
-	metricCollection, err := secondsToExpiryFromCertAsBytes(bytes, password)
+func (c *ConfigMapExporter) ExportMetrics(bytes []byte, keyName, configMapName, configMapNamespace, password string, labels, annotations map[string]string) error {
+	metricCollection, err := c.parsed.parse(bytes, password)
 	if err != nil {
 		return err
 	}
 
 	serviceline := labels["serviceline"]
+	objectLabels := copiedLabelValues(c.Profile, c.Cluster, c.CopyLabels, labels, annotations)
+	c.countCertsParsed(configMapNamespace, len(metricCollection))
+
+	for _, metric := range capCerts(c.source(), configMapNamespace, metricCollection) {
+		if !metrics.AllowSeries(c.source(), configMapNamespace, configMapName, serviceline, 3+commonSeriesPerCert()) {
+			continue
+		}
 
-	for _, metric := range metricCollection {
-		metrics.ConfigMapExpirySeconds.WithLabelValues(keyName, metric.issuer, metric.cn, configMapName, configMapNamespace, serviceline).Set(metric.durationUntilExpiry)
-		metrics.ConfigMapNotAfterTimestamp.WithLabelValues(keyName, metric.issuer, metric.cn, configMapName, configMapNamespace, serviceline).Set(metric.notAfter)
+		setSeries(c.source(), objectKey(configMapNamespace, configMapName), metrics.ConfigMapExpirySeconds, metric.durationUntilExpiry, metric.objectLabelValues(objectLabels, keyName, metric.issuer, metric.cn, configMapName, configMapNamespace)...)
+		setSeries(c.source(), objectKey(configMapNamespace, configMapName), metrics.ConfigMapNotAfterTimestamp, metric.notAfter, metric.objectLabelValues(objectLabels, keyName, metric.issuer, metric.cn, configMapName, configMapNamespace)...)
+		setSeries(c.source(), objectKey(configMapNamespace, configMapName), metrics.ConfigMapNotBeforeTimestamp, metric.notBefore, metric.objectLabelValues(objectLabels, keyName, metric.issuer, metric.cn, configMapName, configMapNamespace)...)
+		exportCommonMetrics(certSource{source: c.source(), namespace: configMapNamespace, name: configMapName, key: keyName, annotations: annotations}, metric)
+		c.expiries = trackObjectExpiry(c.expiries, configMapNamespace, configMapName, objectLabels, metric.cert)
 	}
 
 	return nil
 }
 
-func (c *ConfigMapExporter) ResetMetrics() {
-	metrics.ConfigMapExpirySeconds.Reset()
-	metrics.ConfigMapNotAfterTimestamp.Reset()
+// ExportParseError flags a data key of the configmap that could not be exported because of err, until it is exported
+// again
+func (c *ConfigMapExporter) ExportParseError(keyName, configMapName, configMapNamespace string, err error) {
+	setSeries(c.source(), objectKey(configMapNamespace, configMapName), metrics.ConfigMapParseError, 1, configMapName, configMapNamespace, keyName, ErrorReason(err))
+}
+
+// ExportConfigMetrics exports the certs referenced from the application config stored under keyName.  data holds all
+// keys of the configmap, so certs stored next to the config can be resolved.
+func (c *ConfigMapExporter) ExportConfigMetrics(format, keyName, configMapName, configMapNamespace string, data map[string][]byte, labels, annotations map[string]string) error {
+	refs, err := appconfig.ParseCertReferences(format, data[keyName])
+	if err != nil {
+		return err
+	}
+
+	return c.exportCertReferences(format, refs, keyName, configMapName, configMapNamespace, data, labels, annotations)
+}
+
+// ExportEmbeddedMetrics exports the PEM certs embedded in the fields matching paths of the YAML or JSON config stored
+// under keyName
+func (c *ConfigMapExporter) ExportEmbeddedMetrics(paths []string, keyName, configMapName, configMapNamespace string, data map[string][]byte, labels, annotations map[string]string) error {
+	refs, err := appconfig.ParseEmbeddedCerts(data[keyName], paths)
+	if err != nil {
+		return err
+	}
+
+	return c.exportCertReferences(appconfig.Embedded, refs, keyName, configMapName, configMapNamespace, data, labels, annotations)
+}
+
+// exportCertReferences exports the certs referenced from the config stored under keyName, labeled with its format
+func (c *ConfigMapExporter) exportCertReferences(format string, refs []appconfig.CertReference, keyName, configMapName, configMapNamespace string, data map[string][]byte, labels, annotations map[string]string) error {
+	serviceline := labels["serviceline"]
+	objectLabels := copiedLabelValues(c.Profile, c.Cluster, c.CopyLabels, labels, annotations)
+
+	var lastErr error
+	for _, ref := range refs {
+		certBytes, err := c.resolveCertReference(ref, data)
+		if err != nil {
+			lastErr = fmt.Errorf("%v %v: %w", keyName, ref.Field, err)
+			continue
+		}
+
+		metricCollection, err := c.parsed.parse(certBytes, "")
+		if err != nil {
+			lastErr = fmt.Errorf("%v %v: %w", keyName, ref.Field, err)
+			continue
+		}
+		c.countCertsParsed(configMapNamespace, len(metricCollection))
+
+		for _, metric := range metricCollection {
+			if !metrics.AllowSeries(c.source(), configMapNamespace, configMapName, serviceline, 3+commonSeriesPerCert()) {
+				continue
+			}
+
+			setSeries(c.source(), objectKey(configMapNamespace, configMapName), metrics.ConfigMapConfigExpirySeconds, metric.durationUntilExpiry, metric.objectLabelValues(objectLabels, keyName, format, ref.Field, ref.Path, metric.issuer, metric.cn, configMapName, configMapNamespace)...)
+			setSeries(c.source(), objectKey(configMapNamespace, configMapName), metrics.ConfigMapConfigNotAfterTimestamp, metric.notAfter, metric.objectLabelValues(objectLabels, keyName, format, ref.Field, ref.Path, metric.issuer, metric.cn, configMapName, configMapNamespace)...)
+			setSeries(c.source(), objectKey(configMapNamespace, configMapName), metrics.ConfigMapConfigNotBeforeTimestamp, metric.notBefore, metric.objectLabelValues(objectLabels, keyName, format, ref.Field, ref.Path, metric.issuer, metric.cn, configMapName, configMapNamespace)...)
+			exportCommonMetrics(certSource{source: c.source(), namespace: configMapNamespace, name: configMapName, key: keyName + "/" + ref.Field, annotations: annotations}, metric)
+			c.expiries = trackObjectExpiry(c.expiries, configMapNamespace, configMapName, objectLabels, metric.cert)
+		}
+	}
+
+	return lastErr
+}
+
+// resolveCertReference returns the cert a config points at.  A referenced path is looked up by file name among the
+// keys of the configmap first, then under ConfigFileRoot.
+func (c *ConfigMapExporter) resolveCertReference(ref appconfig.CertReference, data map[string][]byte) ([]byte, error) {
+	if ref.Path == "" {
+		return ref.Inline, nil
+	}
+
+	if certBytes, ok := data[path.Base(ref.Path)]; ok {
+		return certBytes, nil
+	}
+
+	if c.ConfigFileRoot == "" {
+		return nil, fmt.Errorf("%v is not stored in the configmap", ref.Path)
+	}
+
+	// joining a cleaned absolute path keeps references from escaping the root
+	return ioutil.ReadFile(filepath.Join(c.ConfigFileRoot, filepath.Clean("/"+ref.Path)))
+}
+
+// BeginCycle is called before the configmaps of a cycle are exported.  Metrics are not reset, so scrapes during a scan
+// still see every series of the previous one.
+func (c *ConfigMapExporter) BeginCycle() {
+	c.certsParsed = map[string]int{}
+	c.expiries = nil
+	c.parsed.begin()
+	beginSeriesCycle(c.source())
+	metrics.StartCardinalityCycle(c.source())
+}
+
+// FinishCycle is called once every configmap of a cycle has been exported.  It deletes the series of configmaps that
+// disappeared or no longer hold the cert they exported.
+func (c *ConfigMapExporter) FinishCycle() {
+	exportSoonestExpiries(c.source(), metrics.ConfigMapSoonestNotAfterTimestamp, c.expiries)
+	deleteStaleSeries(c.source())
+	metrics.FinishCardinalityCycle(c.source())
+}
+
+// KeepNamespace keeps the series of the configmaps of namespace until the next cycle, because it could not be listed
+// during this one.  It must be called before FinishCycle.
+func (c *ConfigMapExporter) KeepNamespace(namespace string) {
+	keepNamespaceSeries(c.source(), namespace)
+}
+
+// source identifies the series of the exporter.  Each profile and cluster tracks its own, so they never delete each
+// other's.
+func (c *ConfigMapExporter) source() string {
+	return scopedSource(sourceConfigMap, c.Profile, c.Cluster)
+}
+
+// CertsParsed returns the number of certs parsed per namespace during the current cycle
+func (c *ConfigMapExporter) CertsParsed() map[string]int {
+	return c.certsParsed
+}
+
+func (c *ConfigMapExporter) countCertsParsed(namespace string, n int) {
+	if c.certsParsed == nil {
+		c.certsParsed = map[string]int{}
+	}
+	c.certsParsed[namespace] += n
+}
+
+// ObjectExpiries returns the soonest notAfter of every configmap exported during the current cycle
+func (c *ConfigMapExporter) ObjectExpiries() []ObjectExpiry {
+	return sortedObjectExpiries(c.expiries)
 }
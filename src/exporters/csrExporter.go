@@ -0,0 +1,56 @@
+package exporters
+
+import (
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+const sourceCSR = "csr"
+
+// CSRExporter exports the certs issued for CertificateSigningRequests and the requests stuck without one
+type CSRExporter struct {
+}
+
+// ExportMetrics exports the PEM certs issued for a CertificateSigningRequest
+func (c *CSRExporter) ExportMetrics(bytes []byte, csrName, signerName string) error {
+	metricCollection, err := secondsToExpiryFromCertAsBytes(bytes, "")
+	if err != nil {
+		return err
+	}
+
+	for _, metric := range metricCollection {
+		setSeries(sourceCSR, objectKey("", csrName), metrics.CSRExpirySeconds, metric.durationUntilExpiry, metric.labelValues(metric.issuer, metric.cn, csrName, signerName)...)
+		setSeries(sourceCSR, objectKey("", csrName), metrics.CSRNotAfterTimestamp, metric.notAfter, metric.labelValues(metric.issuer, metric.cn, csrName, signerName)...)
+		setSeries(sourceCSR, objectKey("", csrName), metrics.CSRNotBeforeTimestamp, metric.notBefore, metric.labelValues(metric.issuer, metric.cn, csrName, signerName)...)
+		exportCommonMetrics(certSource{source: sourceCSR, name: csrName}, metric)
+	}
+
+	return nil
+}
+
+// ExportPendingMetrics exports whether a CertificateSigningRequest that was not issued a cert, Pending or Denied, has
+// been in that state for too long
+func (c *CSRExporter) ExportPendingMetrics(csrName, signerName, state string, stuck bool) {
+	value := 0.0
+	if stuck {
+		value = 1
+	}
+	setSeries(sourceCSR, objectKey("", csrName), metrics.CSRStuck, value, csrName, signerName, state)
+}
+
+// BeginCycle is called before the CertificateSigningRequests of a cycle are exported.  Metrics are not reset, so
+// scrapes during a scan still see every series of the previous one.
+func (c *CSRExporter) BeginCycle() {
+	beginSeriesCycle(sourceCSR)
+}
+
+// KeepSeries keeps every series of the exporter until the next cycle, because the CertificateSigningRequests could not
+// be listed during this one.  It must be called before FinishCycle.
+func (c *CSRExporter) KeepSeries() {
+	keepNamespaceSeries(sourceCSR, "")
+}
+
+// FinishCycle is called once every CertificateSigningRequest of a cycle has been exported.  It deletes the series of
+// the requests that were garbage collected or changed state.
+func (c *CSRExporter) FinishCycle() {
+	deleteStaleSeries(sourceCSR)
+}
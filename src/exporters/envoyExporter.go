@@ -0,0 +1,101 @@
+package exporters
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+const sourceEnvoy = "envoy"
+
+// envoyCerts is the response of the /certs endpoint of the Envoy admin interface
+type envoyCerts struct {
+	Certificates []struct {
+		CACert    []envoyCertDetails `json:"ca_cert"`
+		CertChain []envoyCertDetails `json:"cert_chain"`
+	} `json:"certificates"`
+}
+
+type envoyCertDetails struct {
+	Path            string `json:"path"`
+	SerialNumber    string `json:"serial_number"`
+	SubjectAltNames []struct {
+		DNS       string `json:"dns"`
+		URI       string `json:"uri"`
+		IPAddress string `json:"ip_address"`
+	} `json:"subject_alt_names"`
+	ValidFrom      string `json:"valid_from"`
+	ExpirationTime string `json:"expiration_time"`
+}
+
+// sans returns the comma separated SANs of the cert
+func (d envoyCertDetails) sans() string {
+	var sans []string
+	for _, san := range d.SubjectAltNames {
+		for _, value := range []string{san.URI, san.DNS, san.IPAddress} {
+			if value != "" {
+				sans = append(sans, value)
+			}
+		}
+	}
+	return strings.Join(sans, ",")
+}
+
+// EnvoyExporter exports the certs Envoy holds in memory, e.g. the workload certs Istio delivers over SDS, as listed by
+// its admin interface.  Envoy only describes the certs, so the metrics shared by every checker are not exported.
+type EnvoyExporter struct {
+}
+
+// ExportMetrics exports the certs of a /certs response of the Envoy at target, the admin URL or the pod it runs in
+func (c *EnvoyExporter) ExportMetrics(body []byte, target, podName, podNamespace string) error {
+	var certs envoyCerts
+	if err := json.Unmarshal(body, &certs); err != nil {
+		return fmt.Errorf("failed to parse the certs of %v: %w", target, err)
+	}
+
+	for _, certificate := range certs.Certificates {
+		for _, group := range []struct {
+			certType string
+			details  []envoyCertDetails
+		}{{"ca", certificate.CACert}, {"cert_chain", certificate.CertChain}} {
+			for _, d := range group.details {
+				notAfter, err := time.Parse(time.RFC3339, d.ExpirationTime)
+				if err != nil {
+					return fmt.Errorf("failed to parse the expiration time of %v of %v: %w", d.Path, target, err)
+				}
+				notBefore, err := time.Parse(time.RFC3339, d.ValidFrom)
+				if err != nil {
+					return fmt.Errorf("failed to parse the validity start of %v of %v: %w", d.Path, target, err)
+				}
+
+				labels := []string{target, podName, podNamespace, group.certType, d.Path, d.SerialNumber, d.sans()}
+				setSeries(sourceEnvoy, objectKey(podNamespace, target), metrics.EnvoyCertExpirySeconds, notAfter.Sub(Now()).Seconds(), labels...)
+				setSeries(sourceEnvoy, objectKey(podNamespace, target), metrics.EnvoyCertNotAfterTimestamp, float64(notAfter.Unix()), labels...)
+				setSeries(sourceEnvoy, objectKey(podNamespace, target), metrics.EnvoyCertNotBeforeTimestamp, float64(notBefore.Unix()), labels...)
+			}
+		}
+	}
+
+	return nil
+}
+
+// BeginCycle is called before the Envoys of a cycle are exported.  Metrics are not reset, so scrapes during a scan
+// still see every series of the previous one.
+func (c *EnvoyExporter) BeginCycle() {
+	beginSeriesCycle(sourceEnvoy)
+}
+
+// KeepNamespace keeps the series of the Envoy pods of namespace until the next cycle, because its pods could not be
+// listed during this one.  It must be called before FinishCycle.
+func (c *EnvoyExporter) KeepNamespace(namespace string) {
+	keepNamespaceSeries(sourceEnvoy, namespace)
+}
+
+// FinishCycle is called once every Envoy of a cycle has been exported.  It deletes the series of the Envoys that
+// disappeared or no longer hold the cert they exported.
+func (c *EnvoyExporter) FinishCycle() {
+	deleteStaleSeries(sourceEnvoy)
+}
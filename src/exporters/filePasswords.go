@@ -0,0 +1,58 @@
+package exporters
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v3"
+)
+
+// FilePassword maps the cert files matching Glob to the password protecting them, read from the environment variable Env
+// or the file File
+type FilePassword struct {
+	Glob string
+	Env  string
+	File string
+}
+
+// ParseFilePassword parses a <path glob>=env:<variable> or <path glob>=file:<path> password source
+func ParseFilePassword(value string) (FilePassword, error) {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return FilePassword{}, fmt.Errorf("password source %q is not <path glob>=env:<variable> or <path glob>=file:<path>", value)
+	}
+	if _, err := doublestar.Match(parts[0], ""); err != nil {
+		return FilePassword{}, fmt.Errorf("password source %q: %w", value, err)
+	}
+
+	switch {
+	case strings.HasPrefix(parts[1], "env:") && len(parts[1]) > len("env:"):
+		return FilePassword{Glob: parts[0], Env: strings.TrimPrefix(parts[1], "env:")}, nil
+	case strings.HasPrefix(parts[1], "file:") && len(parts[1]) > len("file:"):
+		return FilePassword{Glob: parts[0], File: strings.TrimPrefix(parts[1], "file:")}, nil
+	}
+	return FilePassword{}, fmt.Errorf("password source %q is not <path glob>=env:<variable> or <path glob>=file:<path>", value)
+}
+
+// passwordForFile returns the password of the first source whose glob matches file, or "" if none does.  Sources are
+// read on every call so rotated passwords are picked up.
+func passwordForFile(passwords []FilePassword, file string) (string, error) {
+	for _, p := range passwords {
+		if ok, _ := doublestar.Match(p.Glob, file); !ok {
+			continue
+		}
+
+		if p.Env != "" {
+			return os.Getenv(p.Env), nil
+		}
+
+		data, err := ioutil.ReadFile(p.File)
+		if err != nil {
+			return "", fmt.Errorf("failed to read the password of %v: %w", file, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,47 @@
+package exporters
+
+import (
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+const sourceGateway = "gateway"
+
+// GatewayExporter exports the certs Gateway API gateways terminate TLS with
+type GatewayExporter struct {
+}
+
+// ExportMetrics exports the PEM certs of the secret a listener of a gateway references
+func (c *GatewayExporter) ExportMetrics(bytes []byte, gatewayName, gatewayNamespace, listener, hostname, secretName, secretNamespace string) error {
+	metricCollection, err := secondsToExpiryFromCertAsBytes(bytes, "")
+	if err != nil {
+		return err
+	}
+
+	for _, metric := range metricCollection {
+		labels := metric.labelValues(metric.issuer, metric.cn, gatewayName, gatewayNamespace, listener, hostname, secretName, secretNamespace)
+		setSeries(sourceGateway, objectKey(gatewayNamespace, gatewayName), metrics.GatewayExpirySeconds, metric.durationUntilExpiry, labels...)
+		setSeries(sourceGateway, objectKey(gatewayNamespace, gatewayName), metrics.GatewayNotAfterTimestamp, metric.notAfter, labels...)
+		setSeries(sourceGateway, objectKey(gatewayNamespace, gatewayName), metrics.GatewayNotBeforeTimestamp, metric.notBefore, labels...)
+		exportCommonMetrics(certSource{source: sourceGateway, namespace: gatewayNamespace, name: gatewayName, key: listener}, metric)
+	}
+
+	return nil
+}
+
+// BeginCycle is called before the gateways of a cycle are exported.  Metrics are not reset, so scrapes during a scan
+// still see every series of the previous one.
+func (c *GatewayExporter) BeginCycle() {
+	beginSeriesCycle(sourceGateway)
+}
+
+// KeepNamespace keeps the series of the gateways of namespace until the next cycle, because it could not be listed
+// during this one.  It must be called before FinishCycle.
+func (c *GatewayExporter) KeepNamespace(namespace string) {
+	keepNamespaceSeries(sourceGateway, namespace)
+}
+
+// FinishCycle is called once every gateway of a cycle has been exported.  It deletes the series of gateways that
+// disappeared or whose listeners no longer reference the cert they exported.
+func (c *GatewayExporter) FinishCycle() {
+	deleteStaleSeries(sourceGateway)
+}
@@ -0,0 +1,37 @@
+package exporters
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+)
+
+// gzipMaxBytes bounds the decompressed size of gzip compressed certs.  0 leaves gzip compressed data alone.
+var gzipMaxBytes int64
+
+// SetGzipMaxBytes decompresses gzip compressed certs, e.g. truststores stored compressed in the binary data of secrets
+// and configmaps, before parsing them, reading at most maxBytes decompressed bytes
+func SetGzipMaxBytes(maxBytes int64) {
+	gzipMaxBytes = maxBytes
+}
+
+// isGzip returns true if gzip decompression is enabled and the provided bytes start with the gzip magic
+func isGzip(data []byte) bool {
+	return gzipMaxBytes > 0 && bytes.HasPrefix(data, gzipMagic)
+}
+
+// gunzip decompresses gzip data, failing once it decompresses to more than gzipMaxBytes
+func gunzip(data []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress gzip data: %w", err)
+	}
+	defer gz.Close()
+
+	budget := gzipMaxBytes
+	decompressed, err := readBounded(gz, &budget)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decompress gzip data: %w", err)
+	}
+	return decompressed, nil
+}
@@ -0,0 +1,53 @@
+package exporters
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// jwtClaims are the registered claims of a JWT the exporter reports on
+type jwtClaims struct {
+	Issuer  string   `json:"iss"`
+	Subject string   `json:"sub"`
+	Expiry  *float64 `json:"exp"`
+}
+
+// IsJWT returns true if the provided bytes are a compact serialized JWT: three base64url encoded segments, the first of
+// which is a JSON header naming its algorithm
+func IsJWT(tokenBytes []byte) bool {
+	segments := strings.Split(string(bytes.TrimSpace(tokenBytes)), ".")
+	if len(segments) != 3 {
+		return false
+	}
+
+	header, err := base64.RawURLEncoding.DecodeString(segments[0])
+	if err != nil {
+		return false
+	}
+
+	var h struct {
+		Algorithm string `json:"alg"`
+	}
+	return json.Unmarshal(header, &h) == nil && h.Algorithm != ""
+}
+
+// parseJWTClaims decodes the claims of a JWT without verifying its signature
+func parseJWTClaims(tokenBytes []byte) (jwtClaims, error) {
+	var claims jwtClaims
+
+	segments := strings.Split(string(bytes.TrimSpace(tokenBytes)), ".")
+	if len(segments) != 3 {
+		return claims, errors.New("JWT does not have three segments")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(segments[1])
+	if err != nil {
+		return claims, err
+	}
+
+	err = json.Unmarshal(payload, &claims)
+	return claims, err
+}
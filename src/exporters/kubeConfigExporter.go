@@ -1,6 +1,7 @@
 package exporters
 
 import (
+	"bytes"
 	"fmt"
 	"path"
 
@@ -31,7 +32,7 @@ func (c *KubeConfigExporter) ExportMetrics(file, nodeName string) error {
 			}
 		} else if c.Cluster.CertificateAuthority != "" {
 			certFile := pathToFileFromKubeConfig(c.Cluster.CertificateAuthority, file)
-			metricCollection, err = secondsToExpiryFromCertAsFile(certFile)
+			metricCollection, err = secondsToExpiryFromCertAsFile(certFile, "")
 
 			if err != nil {
 				return err
@@ -41,8 +42,10 @@ func (c *KubeConfigExporter) ExportMetrics(file, nodeName string) error {
 		}
 
 		for _, metric := range metricCollection {
-			metrics.KubeConfigExpirySeconds.WithLabelValues(file, "cluster", metric.cn, metric.issuer, c.Name, nodeName).Set(metric.durationUntilExpiry)
-			metrics.KubeConfigNotAfterTimestamp.WithLabelValues(file, "cluster", metric.cn, metric.issuer, c.Name, nodeName).Set(metric.notAfter)
+			setSeries(sourceKubeConfig, objectKey("", file), metrics.KubeConfigExpirySeconds, metric.durationUntilExpiry, metric.fileLabelValues(file, file, "cluster", metric.cn, metric.issuer, c.Name, nodeName)...)
+			setSeries(sourceKubeConfig, objectKey("", file), metrics.KubeConfigNotAfterTimestamp, metric.notAfter, metric.fileLabelValues(file, file, "cluster", metric.cn, metric.issuer, c.Name, nodeName)...)
+			setSeries(sourceKubeConfig, objectKey("", file), metrics.KubeConfigNotBeforeTimestamp, metric.notBefore, metric.fileLabelValues(file, file, "cluster", metric.cn, metric.issuer, c.Name, nodeName)...)
+			exportCommonMetrics(certSource{source: sourceKubeConfig, name: file, key: "cluster/" + c.Name}, metric)
 		}
 	}
 
@@ -57,7 +60,7 @@ func (c *KubeConfigExporter) ExportMetrics(file, nodeName string) error {
 			}
 		} else if u.User.ClientCertificate != "" {
 			certFile := pathToFileFromKubeConfig(u.User.ClientCertificate, file)
-			metricCollection, err = secondsToExpiryFromCertAsFile(certFile)
+			metricCollection, err = secondsToExpiryFromCertAsFile(certFile, "")
 
 			if err != nil {
 				return err
@@ -67,14 +70,26 @@ func (c *KubeConfigExporter) ExportMetrics(file, nodeName string) error {
 		}
 
 		for _, metric := range metricCollection {
-			metrics.KubeConfigExpirySeconds.WithLabelValues(file, "user", metric.cn, metric.issuer, u.Name, nodeName).Set(metric.durationUntilExpiry)
-			metrics.KubeConfigNotAfterTimestamp.WithLabelValues(file, "user", metric.cn, metric.issuer, u.Name, nodeName).Set(metric.notAfter)
+			setSeries(sourceKubeConfig, objectKey("", file), metrics.KubeConfigExpirySeconds, metric.durationUntilExpiry, metric.fileLabelValues(file, file, "user", metric.cn, metric.issuer, u.Name, nodeName)...)
+			setSeries(sourceKubeConfig, objectKey("", file), metrics.KubeConfigNotAfterTimestamp, metric.notAfter, metric.fileLabelValues(file, file, "user", metric.cn, metric.issuer, u.Name, nodeName)...)
+			setSeries(sourceKubeConfig, objectKey("", file), metrics.KubeConfigNotBeforeTimestamp, metric.notBefore, metric.fileLabelValues(file, file, "user", metric.cn, metric.issuer, u.Name, nodeName)...)
+			exportCommonMetrics(certSource{source: sourceKubeConfig, name: file, key: "user/" + u.Name}, metric)
 		}
 	}
 
 	return nil
 }
 
+// IsKubeConfig returns true if the provided bytes are a kubeconfig with at least one cluster or user
+func IsKubeConfig(data []byte) bool {
+	if !bytes.Contains(data, []byte("Config")) {
+		return false
+	}
+
+	k, err := kubeconfig.ParseKubeConfigBytes(data)
+	return err == nil && k.Kind == "Config" && (len(k.Clusters) > 0 || len(k.Users) > 0)
+}
+
 func pathToFileFromKubeConfig(file, kubeConfigFile string) string {
 	if !path.IsAbs(file) {
 		kubeConfigPath := path.Dir(kubeConfigFile)
@@ -84,7 +99,14 @@ func pathToFileFromKubeConfig(file, kubeConfigFile string) string {
 	return file
 }
 
-func (c *KubeConfigExporter) ResetMetrics() {
-	metrics.KubeConfigExpirySeconds.Reset()
-	metrics.KubeConfigNotAfterTimestamp.Reset()
-}
\ No newline at end of file
+// BeginCycle is called before the kubeconfigs of a cycle are exported.  Metrics are not reset, so scrapes during a scan
+// still see every series of the previous one.
+func (c *KubeConfigExporter) BeginCycle() {
+	beginSeriesCycle(sourceKubeConfig)
+}
+
+// FinishCycle is called once every kubeconfig of a cycle has been exported.  It deletes the series of kubeconfigs
+// that disappeared or no longer hold the cert they exported.
+func (c *KubeConfigExporter) FinishCycle() {
+	deleteStaleSeries(sourceKubeConfig)
+}
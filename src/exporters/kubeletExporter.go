@@ -0,0 +1,72 @@
+package exporters
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+const sourceKubelet = "kubelet"
+
+// kubeletRotationDeadline is the share of the lifetime of its certs after which kubelet has always rotated them.  It
+// rotates at a random point between 70% and 90% of their lifetime.
+const kubeletRotationDeadline = 0.9
+
+// KubeletExporter exports the current client and serving certs of kubelet, kubelet-client-current.pem and
+// kubelet-server-current.pem, and whether their rotation is overdue
+type KubeletExporter struct {
+}
+
+// ExportMetrics exports the leaf cert of a kubelet-*-current.pem file.  The file is a symlink to the cert last issued,
+// exported as target.
+func (c *KubeletExporter) ExportMetrics(file, nodeName string) error {
+	target, err := filepath.EvalSymlinks(file)
+	if err != nil {
+		return err
+	}
+
+	metricCollection, err := secondsToExpiryFromCertAsFile(target, "")
+	if err != nil {
+		return err
+	}
+	if len(metricCollection) == 0 {
+		return nil
+	}
+
+	certType := "server"
+	if strings.HasPrefix(filepath.Base(file), "kubelet-client") {
+		certType = "client"
+	}
+
+	// the file holds the key and the cert kubelet was issued, which is the only one exported
+	metric := metricCollection[0]
+	lifetime := metric.cert.NotAfter.Sub(metric.cert.NotBefore).Seconds()
+	used := 1.0
+	if lifetime > 0 {
+		used = Now().Sub(metric.cert.NotBefore).Seconds() / lifetime
+	}
+	stuck := 0.0
+	if used > kubeletRotationDeadline {
+		stuck = 1
+	}
+
+	labels := []string{certType, file, target, nodeName}
+	setSeries(sourceKubelet, objectKey("", file), metrics.KubeletCertExpirySeconds, metric.durationUntilExpiry, labels...)
+	setSeries(sourceKubelet, objectKey("", file), metrics.KubeletCertLifetimeUsedRatio, used, labels...)
+	setSeries(sourceKubelet, objectKey("", file), metrics.KubeletCertRotationStuck, stuck, labels...)
+	exportCommonMetrics(certSource{source: sourceKubelet, name: file}, metric)
+
+	return nil
+}
+
+// BeginCycle is called before the kubelet certs of a cycle are exported
+func (c *KubeletExporter) BeginCycle() {
+	beginSeriesCycle(sourceKubelet)
+}
+
+// FinishCycle is called once the kubelet certs of a cycle have been exported.  It deletes the series of the certs
+// kubelet rotated.
+func (c *KubeletExporter) FinishCycle() {
+	deleteStaleSeries(sourceKubelet)
+}
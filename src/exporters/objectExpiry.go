@@ -0,0 +1,59 @@
+package exporters
+
+import (
+	"crypto/x509"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ObjectExpiry is the soonest notAfter of the certs found in a secret or configmap during a cycle
+type ObjectExpiry struct {
+	Namespace string
+	Name      string
+	NotAfter  time.Time
+
+	// objectLabels are the copied label values of the object
+	objectLabels []string
+}
+
+// trackObjectExpiry records that cert was found in an object, keeping the soonest notAfter of every object.  The map is
+// created on first use.
+func trackObjectExpiry(expiries map[string]ObjectExpiry, namespace, name string, objectLabels []string, cert *x509.Certificate) map[string]ObjectExpiry {
+	if cert == nil {
+		return expiries
+	}
+	if expiries == nil {
+		expiries = map[string]ObjectExpiry{}
+	}
+
+	key := objectKey(namespace, name)
+	if e, ok := expiries[key]; !ok || cert.NotAfter.Before(e.NotAfter) {
+		expiries[key] = ObjectExpiry{Namespace: namespace, Name: name, NotAfter: cert.NotAfter, objectLabels: objectLabels}
+	}
+	return expiries
+}
+
+// sortedObjectExpiries returns the expiries sorted by namespace and name
+func sortedObjectExpiries(expiries map[string]ObjectExpiry) []ObjectExpiry {
+	sorted := make([]ObjectExpiry, 0, len(expiries))
+	for _, e := range expiries {
+		sorted = append(sorted, e)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Namespace != sorted[j].Namespace {
+			return sorted[i].Namespace < sorted[j].Namespace
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+// exportSoonestExpiries sets vec to the soonest notAfter of every object, labeled with its name, namespace and copied
+// labels
+func exportSoonestExpiries(source string, vec *prometheus.GaugeVec, expiries map[string]ObjectExpiry) {
+	for key, e := range expiries {
+		setSeries(source, key, vec, float64(e.NotAfter.Unix()), append([]string{e.Name, e.Namespace}, e.objectLabels...)...)
+	}
+}
@@ -0,0 +1,59 @@
+package exporters
+
+import (
+	"crypto/sha256"
+)
+
+// parsedCerts is the outcome of parsing the same data with the same password
+type parsedCerts struct {
+	metrics []certMetric
+	err     error
+}
+
+// parseCache remembers the certs parsed from the data of secrets and configmaps by content hash, so unchanged objects
+// are not parsed again every cycle.  Entries not used for a whole cycle are forgotten.
+type parseCache struct {
+	previous map[[sha256.Size]byte]parsedCerts
+	current  map[[sha256.Size]byte]parsedCerts
+}
+
+// begin starts a cycle.  Data that was parsed in the previous cycle is not parsed again.
+func (c *parseCache) begin() {
+	c.previous = c.current
+	c.current = map[[sha256.Size]byte]parsedCerts{}
+}
+
+// parse returns the certs in PEM, PKCS12 or JKS data, parsing it only if it was not parsed with password during this
+// or the previous cycle
+func (c *parseCache) parse(certBytes []byte, password string) ([]certMetric, error) {
+	hash := sha256.New()
+	hash.Write([]byte(password))
+	hash.Write([]byte{0})
+	hash.Write(certBytes)
+	var key [sha256.Size]byte
+	copy(key[:], hash.Sum(nil))
+
+	parsed, ok := c.current[key]
+	if !ok {
+		parsed, ok = c.previous[key]
+	}
+	if !ok {
+		parsed.metrics, parsed.err = secondsToExpiryFromCertAsBytes(certBytes, password)
+	}
+	if c.current == nil {
+		c.current = map[[sha256.Size]byte]parsedCerts{}
+	}
+	c.current[key] = parsed
+
+	if parsed.err != nil {
+		return nil, parsed.err
+	}
+
+	// the time left changes every cycle even though the certs did not
+	certMetrics := make([]certMetric, len(parsed.metrics))
+	for i, metric := range parsed.metrics {
+		metric.durationUntilExpiry = metric.cert.NotAfter.Sub(Now()).Seconds()
+		certMetrics[i] = metric
+	}
+	return certMetrics, nil
+}
@@ -0,0 +1,72 @@
+package exporters
+
+import (
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+)
+
+// oidSignedData is the content type of PKCS#7 SignedData, which certificate-only bundles (.p7b, .p7c) use without
+// content or signers
+var oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+
+// pkcs7ContentInfo is the outer structure of a PKCS#7 message, see RFC 2315
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,optional,tag:0"`
+}
+
+// pkcs7SignedData is the SignedData content of a PKCS#7 message.  Only the certificates are decoded.
+type pkcs7SignedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	ContentInfo      asn1.RawValue
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      asn1.RawValue
+}
+
+// isPKCS7 returns true if der is a PKCS#7 SignedData message, e.g. a .p7b bundle
+func isPKCS7(der []byte) bool {
+	var info pkcs7ContentInfo
+	_, err := asn1.Unmarshal(der, &info)
+	return err == nil && info.ContentType.Equal(oidSignedData)
+}
+
+// parsePKCS7Certificates returns the certs of a DER encoded PKCS#7 SignedData message.  Signatures are not verified.
+func parsePKCS7Certificates(der []byte) ([]*x509.Certificate, error) {
+	var info pkcs7ContentInfo
+	if _, err := asn1.Unmarshal(der, &info); err != nil {
+		return nil, err
+	}
+	if !info.ContentType.Equal(oidSignedData) {
+		return nil, errors.New("PKCS#7 message is not SignedData")
+	}
+
+	var signedData pkcs7SignedData
+	if _, err := asn1.Unmarshal(info.Content.Bytes, &signedData); err != nil {
+		return nil, err
+	}
+	if len(signedData.Certificates.Bytes) == 0 {
+		return nil, errors.New("PKCS#7 message holds no certs")
+	}
+	return x509.ParseCertificates(signedData.Certificates.Bytes)
+}
+
+// parseAsPKCS7 parses a DER encoded PKCS#7 certificate bundle
+func parseAsPKCS7(certBytes []byte) (bool, []certMetric, error) {
+	if !isPKCS7(certBytes) {
+		return false, nil, nil
+	}
+
+	certs, err := parsePKCS7Certificates(certBytes)
+	if err != nil {
+		return true, nil, err
+	}
+
+	metrics := make([]certMetric, 0, len(certs))
+	for _, cert := range certs {
+		metrics = append(metrics, getCertificateMetrics(cert))
+	}
+	return true, metrics, nil
+}
@@ -0,0 +1,134 @@
+package exporters
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+// Preset is a well-known layout of cert and kubeconfig files
+type Preset struct {
+	CertGlobs       []string
+	KubeConfigGlobs []string
+
+	// components maps the files of the layout, or globs of them, to the component they belong to
+	components map[string]string
+}
+
+// presets are the supported layouts by name.  Paths are relative to the root of the host.
+var presets = map[string]Preset{
+	"kubeadm": {
+		CertGlobs: []string{
+			"/etc/kubernetes/pki/*.crt",
+			"/etc/kubernetes/pki/etcd/*.crt",
+			"/var/lib/kubelet/pki/kubelet-client-current.pem",
+			"/var/lib/kubelet/pki/kubelet.crt",
+		},
+		KubeConfigGlobs: []string{
+			"/etc/kubernetes/*.conf",
+		},
+		components: map[string]string{
+			"/etc/kubernetes/pki/ca.crt":                       "ca",
+			"/etc/kubernetes/pki/apiserver.crt":                "apiserver",
+			"/etc/kubernetes/pki/apiserver-kubelet-client.crt": "apiserver-kubelet-client",
+			"/etc/kubernetes/pki/apiserver-etcd-client.crt":    "apiserver-etcd-client",
+			"/etc/kubernetes/pki/front-proxy-ca.crt":           "front-proxy-ca",
+			"/etc/kubernetes/pki/front-proxy-client.crt":       "front-proxy-client",
+			"/etc/kubernetes/pki/etcd/ca.crt":                  "etcd-ca",
+			"/etc/kubernetes/pki/etcd/server.crt":              "etcd-server",
+			"/etc/kubernetes/pki/etcd/peer.crt":                "etcd-peer",
+			"/etc/kubernetes/pki/etcd/healthcheck-client.crt":  "etcd-healthcheck-client",
+			"/var/lib/kubelet/pki/kubelet-client-current.pem":  "kubelet-client",
+			"/var/lib/kubelet/pki/kubelet.crt":                 "kubelet-serving",
+			"/etc/kubernetes/admin.conf":                       "admin",
+			"/etc/kubernetes/super-admin.conf":                 "super-admin",
+			"/etc/kubernetes/controller-manager.conf":          "controller-manager",
+			"/etc/kubernetes/scheduler.conf":                   "scheduler",
+			"/etc/kubernetes/kubelet.conf":                     "kubelet",
+		},
+	},
+	// system-trust covers the CA trust stores of Debian and Ubuntu, built from /usr/share/ca-certificates and the CAs
+	// added to /usr/local/share/ca-certificates, and of RHEL and Fedora, built from the anchors added to
+	// /etc/pki/ca-trust/source.  Debian links every CA into /etc/ssl/certs, so its bundle ca-certificates.crt is skipped.
+	"system-trust": {
+		CertGlobs: []string{
+			"/etc/ssl/certs/*.pem",
+			"/etc/pki/ca-trust/source/anchors/*",
+			"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem",
+		},
+		components: map[string]string{
+			"/etc/ssl/certs/*.pem":                              "ca-certificates",
+			"/etc/pki/ca-trust/source/anchors/*":                "ca-trust-anchor",
+			"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem": "ca-trust-bundle",
+		},
+	},
+}
+
+// fileComponents maps the files of the enabled preset to their component
+var fileComponents map[string]string
+
+// LookupPreset returns the preset called name with its paths moved under root, e.g. where the host filesystem is
+// mounted in the exporter pod
+func LookupPreset(name, root string) (Preset, error) {
+	preset, ok := presets[name]
+	if !ok {
+		names := make([]string, 0, len(presets))
+		for name := range presets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return Preset{}, fmt.Errorf("unknown preset %q, supported presets are %v", name, strings.Join(names, ", "))
+	}
+	if root == "" {
+		return preset, nil
+	}
+
+	rooted := Preset{components: map[string]string{}}
+	for _, glob := range preset.CertGlobs {
+		rooted.CertGlobs = append(rooted.CertGlobs, filepath.Join(root, glob))
+	}
+	for _, glob := range preset.KubeConfigGlobs {
+		rooted.KubeConfigGlobs = append(rooted.KubeConfigGlobs, filepath.Join(root, glob))
+	}
+	for file, component := range preset.components {
+		rooted.components[filepath.Join(root, file)] = component
+	}
+	return rooted, nil
+}
+
+// EnablePreset labels the cert and kubeconfig file metrics with the component of the files of the preset.  Other
+// files get an empty component.  It may be called for several presets, and must be called before metrics.Init.
+func EnablePreset(preset Preset) {
+	if fileComponents == nil {
+		fileComponents = map[string]string{}
+		metrics.EnableComponentLabel()
+	}
+	for file, component := range preset.components {
+		fileComponents[file] = component
+	}
+}
+
+// fileComponent returns the component of file, or of the glob of the presets matching it
+func fileComponent(file string) string {
+	if component, ok := fileComponents[file]; ok {
+		return component
+	}
+	for glob, component := range fileComponents {
+		if matched, _ := filepath.Match(glob, file); matched {
+			return component
+		}
+	}
+	return ""
+}
+
+// fileLabelValues returns the provided label values followed by the component of file, when enabled, and the values
+// of the optional cert labels
+func (m certMetric) fileLabelValues(file string, values ...string) []string {
+	if fileComponents != nil {
+		values = append(values, fileComponent(file))
+	}
+	return m.labelValues(values...)
+}
@@ -0,0 +1,111 @@
+package exporters
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+// rotationHistoryRetention is how long the history of a cert that is no longer seen is kept, so certs briefly missing,
+// e.g. while their secret is recreated, keep their history
+const rotationHistoryRetention = 30 * 24 * time.Hour
+
+// RotationRecord is the rotation history of a cert
+type RotationRecord struct {
+	Serial string `json:"serial"`
+	// LastRotation is when the current serial was first seen, or the notBefore of the first cert seen
+	LastRotation time.Time `json:"lastRotation"`
+	Rotations    int       `json:"rotations"`
+	LastSeen     time.Time `json:"lastSeen"`
+}
+
+// RotationStore persists the rotation history of every cert across restarts
+type RotationStore interface {
+	Load() (map[string]RotationRecord, error)
+	Save(records map[string]RotationRecord) error
+}
+
+type rotationHistory struct {
+	store RotationStore
+
+	mutex   sync.Mutex
+	records map[string]RotationRecord
+	dirty   bool
+}
+
+var rotations *rotationHistory
+
+// EnableRotationHistory exports cert_exporter_cert_last_rotation_timestamp and cert_exporter_cert_rotations for every
+// cert, recording when its serial changed in store
+func EnableRotationHistory(store RotationStore) error {
+	records, err := store.Load()
+	if err != nil {
+		return err
+	}
+	if records == nil {
+		records = map[string]RotationRecord{}
+	}
+
+	rotations = &rotationHistory{store: store, records: records}
+	return nil
+}
+
+// SaveRotationHistory persists the rotations recorded since it was last called.  It is called after every scan.
+func SaveRotationHistory() {
+	if rotations == nil {
+		return
+	}
+
+	rotations.mutex.Lock()
+	defer rotations.mutex.Unlock()
+
+	for key, record := range rotations.records {
+		if Now().Sub(record.LastSeen) > rotationHistoryRetention {
+			delete(rotations.records, key)
+			rotations.dirty = true
+		}
+	}
+	if !rotations.dirty {
+		return
+	}
+
+	if err := rotations.store.Save(rotations.records); err != nil {
+		glog.Errorf("Error saving the rotation history: %v", err)
+		metrics.ErrorTotal.Inc()
+		return
+	}
+	rotations.dirty = false
+}
+
+// recordRotation updates the history of a cert and exports it
+func recordRotation(src certSource, metric certMetric) {
+	rotations.mutex.Lock()
+	defer rotations.mutex.Unlock()
+
+	key := strings.Join([]string{src.source, src.namespace, src.name, src.key, metric.cn}, "/")
+	serial := metric.cert.SerialNumber.Text(16)
+	record, ok := rotations.records[key]
+	switch {
+	case !ok:
+		record = RotationRecord{Serial: serial, LastRotation: metric.cert.NotBefore}
+		rotations.dirty = true
+	case record.Serial != serial:
+		record.Serial = serial
+		record.LastRotation = Now()
+		record.Rotations++
+		rotations.dirty = true
+	}
+	// lastSeen only needs to be precise to the retention
+	if Now().Sub(record.LastSeen) > 24*time.Hour {
+		record.LastSeen = Now()
+		rotations.dirty = true
+	}
+	rotations.records[key] = record
+
+	setCommonMetric(src, metrics.CertLastRotationTimestamp, float64(record.LastRotation.Unix()), src.labelValues(metric)...)
+	setCommonMetric(src, metrics.CertRotations, float64(record.Rotations), src.labelValues(metric)...)
+}
@@ -0,0 +1,47 @@
+package exporters
+
+import (
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+const sourceRoute = "route"
+
+// RouteExporter exports the certs of OpenShift Routes
+type RouteExporter struct {
+}
+
+// ExportMetrics exports the PEM certs of a field of spec.tls of a route: certificate, caCertificate or
+// destinationCACertificate
+func (c *RouteExporter) ExportMetrics(bytes []byte, field, routeName, routeNamespace, host string) error {
+	metricCollection, err := secondsToExpiryFromCertAsBytes(bytes, "")
+	if err != nil {
+		return err
+	}
+
+	for _, metric := range metricCollection {
+		setSeries(sourceRoute, objectKey(routeNamespace, routeName), metrics.RouteExpirySeconds, metric.durationUntilExpiry, metric.labelValues(field, metric.issuer, metric.cn, routeName, routeNamespace, host)...)
+		setSeries(sourceRoute, objectKey(routeNamespace, routeName), metrics.RouteNotAfterTimestamp, metric.notAfter, metric.labelValues(field, metric.issuer, metric.cn, routeName, routeNamespace, host)...)
+		setSeries(sourceRoute, objectKey(routeNamespace, routeName), metrics.RouteNotBeforeTimestamp, metric.notBefore, metric.labelValues(field, metric.issuer, metric.cn, routeName, routeNamespace, host)...)
+		exportCommonMetrics(certSource{source: sourceRoute, namespace: routeNamespace, name: routeName, key: field}, metric)
+	}
+
+	return nil
+}
+
+// BeginCycle is called before the routes of a cycle are exported.  Metrics are not reset, so scrapes during a scan
+// still see every series of the previous one.
+func (c *RouteExporter) BeginCycle() {
+	beginSeriesCycle(sourceRoute)
+}
+
+// KeepNamespace keeps the series of the routes of namespace until the next cycle, because it could not be listed during
+// this one.  It must be called before FinishCycle.
+func (c *RouteExporter) KeepNamespace(namespace string) {
+	keepNamespaceSeries(sourceRoute, namespace)
+}
+
+// FinishCycle is called once every route of a cycle has been exported.  It deletes the series of routes that
+// disappeared or no longer hold the cert they exported.
+func (c *RouteExporter) FinishCycle() {
+	deleteStaleSeries(sourceRoute)
+}
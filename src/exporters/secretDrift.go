@@ -0,0 +1,106 @@
+package exporters
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v3"
+
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+// SecretDrift maps the cert files matching Glob to the key of the secret they are mounted from.  An empty Key stands
+// for the base name of the file.
+type SecretDrift struct {
+	Glob      string
+	Namespace string
+	Name      string
+	Key       string
+}
+
+// ParseSecretDrift parses a <path glob>=<namespace>/<secret>[/<key>] drift source
+func ParseSecretDrift(value string) (SecretDrift, error) {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return SecretDrift{}, fmt.Errorf("drift source %q is not <path glob>=<namespace>/<secret>[/<key>]", value)
+	}
+	if _, err := doublestar.Match(parts[0], ""); err != nil {
+		return SecretDrift{}, fmt.Errorf("drift source %q: %w", value, err)
+	}
+
+	secret := strings.SplitN(parts[1], "/", 3)
+	if len(secret) < 2 || secret[0] == "" || secret[1] == "" || (len(secret) == 3 && secret[2] == "") {
+		return SecretDrift{}, fmt.Errorf("drift source %q is not <path glob>=<namespace>/<secret>[/<key>]", value)
+	}
+
+	d := SecretDrift{Glob: parts[0], Namespace: secret[0], Name: secret[1]}
+	if len(secret) == 3 {
+		d.Key = secret[2]
+	}
+	return d, nil
+}
+
+// SecretDriftFor returns the first drift source whose glob matches file, with its key resolved
+func SecretDriftFor(drifts []SecretDrift, file string) (SecretDrift, bool) {
+	for _, d := range drifts {
+		if ok, _ := doublestar.Match(d.Glob, file); !ok {
+			continue
+		}
+
+		if d.Key == "" {
+			d.Key = filepath.Base(file)
+		}
+		return d, true
+	}
+	return SecretDrift{}, false
+}
+
+// ExportDriftMetrics exports whether the certs of file differ from the ones currently stored in the key of its secret,
+// given the data of the secret
+func (c *CertExporter) ExportDriftMetrics(file, nodeName string, d SecretDrift, secretData map[string][]byte) error {
+	current, ok := secretData[d.Key]
+	if !ok {
+		return fmt.Errorf("secret %v/%v has no key %v", d.Namespace, d.Name, d.Key)
+	}
+
+	password, err := passwordForFile(c.Passwords, file)
+	if err != nil {
+		return err
+	}
+
+	mountedBytes, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	mounted, err := secondsToExpiryFromCertAsBytes(mountedBytes, password)
+	if err != nil {
+		return err
+	}
+	stored, err := secondsToExpiryFromCertAsBytes(current, password)
+	if err != nil {
+		return fmt.Errorf("failed to parse secret %v/%v key %v: %w", d.Namespace, d.Name, d.Key, err)
+	}
+
+	drift := 0.0
+	if !sameCerts(mounted, stored) {
+		drift = 1
+	}
+	setSeries(sourceFile, objectKey("", file), metrics.CertSecretDrift, drift, file, nodeName, d.Namespace, d.Name, d.Key)
+	return nil
+}
+
+// sameCerts reports whether both lists hold the same certs in the same order
+func sameCerts(a, b []certMetric) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i].cert.Raw, b[i].cert.Raw) {
+			return false
+		}
+	}
+	return true
+}
@@ -1,31 +1,476 @@
 package exporters
 
 import (
+	"bytes"
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/joe-elliott/cert-exporter/src/kubeconfig"
 	"github.com/joe-elliott/cert-exporter/src/metrics"
 )
 
+const (
+	caCertKey       = "ca.crt"
+	caPrivateKeyKey = "ca.key"
+
+	// expectedHostsAnnotation lists, comma separated, the hostnames the leaf certs of a secret must be valid for
+	expectedHostsAnnotation = "cert-exporter.io/expected-hosts"
+)
+
+// keyPairs maps the private keys conventionally stored next to their certificate to that certificate
+var keyPairs = map[string]string{
+	corev1.TLSPrivateKeyKey: corev1.TLSCertKey,
+	caPrivateKeyKey:         caCertKey,
+}
+
 // SecretExporter exports PEM file certs
 type SecretExporter struct {
+	// Profile is the config profile the exporter runs for.  Empty for the checker configured with flags.
+	Profile string
+	// Cluster is the name of the cluster the exporter scans.  Empty for the cluster cert-exporter runs in.
+	Cluster string
+	// CopyLabels are the keys of the copied labels this exporter fills.  Nil fills every copied label.
+	CopyLabels []string
+
+	// internal CAs and every cert seen during the current cycle, to check no cert outlives the CA that issued it
+	cas    []secretCA
+	issued map[string]*x509.Certificate
+
+	// certsParsed counts the certs parsed during the current cycle per namespace
+	certsParsed map[string]int
+	// expiries are the soonest notAfter of every secret exported during the current cycle
+	expiries map[string]ObjectExpiry
+	// parsed remembers the certs parsed from unchanged data
+	parsed parseCache
+}
+
+type secretCA struct {
+	cert            *x509.Certificate
+	secretName      string
+	secretNamespace string
 }
 
 // ExportMetrics exports the provided PEM file
-func (c *SecretExporter) ExportMetrics(bytes []byte, keyName, secretName, secretNamespace string, password string, labels map[string]string) error {
-	metricCollection, err := secondsToExpiryFromCertAsBytes(bytes, password)
+func (c *SecretExporter) ExportMetrics(bytes []byte, keyName, secretName, secretNamespace string, password string, labels, annotations map[string]string) error {
+	metricCollection, err := c.parsed.parse(bytes, password)
 	if err != nil {
 		return err
 	}
 
 	serviceline := labels["serviceline"]
+	objectLabels := copiedLabelValues(c.Profile, c.Cluster, c.CopyLabels, labels, annotations)
+	c.countCertsParsed(secretNamespace, len(metricCollection))
 
 	for _, metric := range metricCollection {
-		metrics.SecretExpirySeconds.WithLabelValues(keyName, metric.issuer, metric.cn, secretName, secretNamespace, serviceline).Set(metric.durationUntilExpiry)
-		metrics.SecretNotAfterTimestamp.WithLabelValues(keyName, metric.issuer, metric.cn, secretName, secretNamespace, serviceline).Set(metric.notAfter)
+		if metric.cert != nil {
+			if c.issued == nil {
+				c.issued = map[string]*x509.Certificate{}
+			}
+			c.issued[string(metric.cert.Raw)] = metric.cert
+		}
+	}
+
+	for _, metric := range capCerts(c.source(), secretNamespace, metricCollection) {
+		if !metrics.AllowSeries(c.source(), secretNamespace, secretName, serviceline, 3+commonSeriesPerCert()) {
+			continue
+		}
+
+		setSeries(c.source(), objectKey(secretNamespace, secretName), metrics.SecretExpirySeconds, metric.durationUntilExpiry, metric.objectLabelValues(objectLabels, keyName, metric.issuer, metric.cn, secretName, secretNamespace)...)
+		setSeries(c.source(), objectKey(secretNamespace, secretName), metrics.SecretNotAfterTimestamp, metric.notAfter, metric.objectLabelValues(objectLabels, keyName, metric.issuer, metric.cn, secretName, secretNamespace)...)
+		setSeries(c.source(), objectKey(secretNamespace, secretName), metrics.SecretNotBeforeTimestamp, metric.notBefore, metric.objectLabelValues(objectLabels, keyName, metric.issuer, metric.cn, secretName, secretNamespace)...)
+		exportCommonMetrics(certSource{source: c.source(), namespace: secretNamespace, name: secretName, key: keyName, annotations: annotations}, metric)
+		c.expiries = trackObjectExpiry(c.expiries, secretNamespace, secretName, objectLabels, metric.cert)
+		if metric.cert != nil && !metric.cert.IsCA {
+			c.exportSANMetrics(metric, keyName, secretName, secretNamespace, serviceline, annotations[expectedHostsAnnotation])
+		}
 	}
 
 	return nil
 }
 
-func (c *SecretExporter) ResetMetrics() {
-	metrics.SecretExpirySeconds.Reset()
-	metrics.SecretNotAfterTimestamp.Reset()
+// ExportKubeConfigMetrics exports the certificate-authority-data of every cluster and client-certificate-data of every
+// user of the provided kubeconfig, e.g. the admin kubeconfig of another cluster.  Paths to files are ignored since they
+// cannot be resolved from a secret.
+func (c *SecretExporter) ExportKubeConfigMetrics(kubeConfigBytes []byte, keyName, secretName, secretNamespace string, labels, annotations map[string]string) error {
+	k, err := kubeconfig.ParseKubeConfigBytes(kubeConfigBytes)
+	if err != nil {
+		return err
+	}
+
+	objectLabels := copiedLabelValues(c.Profile, c.Cluster, c.CopyLabels, labels, annotations)
+
+	export := func(certType, name, data string) error {
+		if data == "" {
+			return nil
+		}
+
+		metricCollection, err := secondsToExpiryFromCertAsBase64String(data)
+		if err != nil {
+			return err
+		}
+		c.countCertsParsed(secretNamespace, len(metricCollection))
+
+		for _, metric := range capCerts(c.source(), secretNamespace, metricCollection) {
+			if !metrics.AllowSeries(c.source(), secretNamespace, secretName, labels["serviceline"], 3+commonSeriesPerCert()) {
+				continue
+			}
+
+			setSeries(c.source(), objectKey(secretNamespace, secretName), metrics.SecretKubeConfigExpirySeconds, metric.durationUntilExpiry, metric.objectLabelValues(objectLabels, keyName, certType, name, metric.issuer, metric.cn, secretName, secretNamespace)...)
+			setSeries(c.source(), objectKey(secretNamespace, secretName), metrics.SecretKubeConfigNotAfterTimestamp, metric.notAfter, metric.objectLabelValues(objectLabels, keyName, certType, name, metric.issuer, metric.cn, secretName, secretNamespace)...)
+			setSeries(c.source(), objectKey(secretNamespace, secretName), metrics.SecretKubeConfigNotBeforeTimestamp, metric.notBefore, metric.objectLabelValues(objectLabels, keyName, certType, name, metric.issuer, metric.cn, secretName, secretNamespace)...)
+			exportCommonMetrics(certSource{source: c.source(), namespace: secretNamespace, name: secretName, key: keyName + "/" + certType + "/" + name, annotations: annotations}, metric)
+			c.expiries = trackObjectExpiry(c.expiries, secretNamespace, secretName, objectLabels, metric.cert)
+		}
+		return nil
+	}
+
+	for _, cluster := range k.Clusters {
+		if err := export("cluster", cluster.Name, cluster.Cluster.CertificateAuthorityData); err != nil {
+			return err
+		}
+	}
+	for _, user := range k.Users {
+		if err := export("user", user.Name, user.User.ClientCertificateData); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExportArchiveMetrics exports the certs of every member of the provided tar, gzipped tar or zip archive, e.g. the cert
+// bundles some vendor charts ship.  Members that hold no certs, like READMEs, are skipped.
+func (c *SecretExporter) ExportArchiveMetrics(archiveBytes []byte, keyName, secretName, secretNamespace string, password string, labels, annotations map[string]string) error {
+	members, err := readArchive(archiveBytes)
+	if err != nil {
+		return fmt.Errorf("failed to read archive: %w", err)
+	}
+
+	serviceline := labels["serviceline"]
+	objectLabels := copiedLabelValues(c.Profile, c.Cluster, c.CopyLabels, labels, annotations)
+
+	exported := false
+	var lastErr error
+	for _, member := range members {
+		metricCollection, err := c.parsed.parse(member.data, password)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		exported = true
+		c.countCertsParsed(secretNamespace, len(metricCollection))
+
+		for _, metric := range capCerts(c.source(), secretNamespace, metricCollection) {
+			if !metrics.AllowSeries(c.source(), secretNamespace, secretName, serviceline, 3+commonSeriesPerCert()) {
+				continue
+			}
+
+			setSeries(c.source(), objectKey(secretNamespace, secretName), metrics.SecretArchiveExpirySeconds, metric.durationUntilExpiry, metric.objectLabelValues(objectLabels, keyName, member.path, metric.issuer, metric.cn, secretName, secretNamespace)...)
+			setSeries(c.source(), objectKey(secretNamespace, secretName), metrics.SecretArchiveNotAfterTimestamp, metric.notAfter, metric.objectLabelValues(objectLabels, keyName, member.path, metric.issuer, metric.cn, secretName, secretNamespace)...)
+			setSeries(c.source(), objectKey(secretNamespace, secretName), metrics.SecretArchiveNotBeforeTimestamp, metric.notBefore, metric.objectLabelValues(objectLabels, keyName, member.path, metric.issuer, metric.cn, secretName, secretNamespace)...)
+			exportCommonMetrics(certSource{source: c.source(), namespace: secretNamespace, name: secretName, key: keyName + "/" + member.path, annotations: annotations}, metric)
+			c.expiries = trackObjectExpiry(c.expiries, secretNamespace, secretName, objectLabels, metric.cert)
+		}
+	}
+
+	if !exported && lastErr != nil {
+		return fmt.Errorf("no member of the archive holds certs: %w", lastErr)
+	}
+	return nil
+}
+
+// ExportParseError flags a data key of the secret that could not be exported because of err, until it is exported again
+func (c *SecretExporter) ExportParseError(keyName, secretName, secretNamespace string, err error) {
+	setSeries(c.source(), objectKey(secretNamespace, secretName), metrics.ParseError, 1, secretName, secretNamespace, keyName, ErrorReason(err))
+}
+
+// ExportJWTMetrics exports the expiry of the provided JWT, e.g. a static service account token.  Tokens without an exp
+// claim never expire and are not exported.
+func (c *SecretExporter) ExportJWTMetrics(tokenBytes []byte, keyName, secretName, secretNamespace string, labels, annotations map[string]string) error {
+	claims, err := parseJWTClaims(tokenBytes)
+	if err != nil {
+		return err
+	}
+	if claims.Expiry == nil {
+		return nil
+	}
+
+	if !metrics.AllowSeries(c.source(), secretNamespace, secretName, labels["serviceline"], 2) {
+		return nil
+	}
+
+	expiry := time.Unix(int64(*claims.Expiry), 0)
+	labelValues := append([]string{keyName, claims.Issuer, claims.Subject, secretName, secretNamespace}, copiedLabelValues(c.Profile, c.Cluster, c.CopyLabels, labels, annotations)...)
+	setSeries(c.source(), objectKey(secretNamespace, secretName), metrics.SecretJWTExpirySeconds, expiry.Sub(Now()).Seconds(), labelValues...)
+	setSeries(c.source(), objectKey(secretNamespace, secretName), metrics.SecretJWTExpiryTimestamp, float64(expiry.Unix()), labelValues...)
+	return nil
+}
+
+// ExportPGPKeyMetrics exports the expiry of the primary keys and subkeys of the provided OpenPGP key block, e.g. a
+// release signing key.  Keys that never expire are not exported.
+func (c *SecretExporter) ExportPGPKeyMetrics(keyBytes []byte, keyName, secretName, secretNamespace string, labels, annotations map[string]string) error {
+	keys, err := parsePGPKeys(keyBytes)
+	if err != nil {
+		return err
+	}
+
+	if !metrics.AllowSeries(c.source(), secretNamespace, secretName, labels["serviceline"], 2*len(keys)) {
+		return nil
+	}
+
+	objectLabels := copiedLabelValues(c.Profile, c.Cluster, c.CopyLabels, labels, annotations)
+	for _, key := range keys {
+		labelValues := append([]string{keyName, key.fingerprint, key.keyType, key.uid, secretName, secretNamespace}, objectLabels...)
+		setSeries(c.source(), objectKey(secretNamespace, secretName), metrics.SecretPGPKeyExpirySeconds, key.expiry.Sub(Now()).Seconds(), labelValues...)
+		setSeries(c.source(), objectKey(secretNamespace, secretName), metrics.SecretPGPKeyExpiryTimestamp, float64(key.expiry.Unix()), labelValues...)
+	}
+	return nil
+}
+
+// ExportTLSSecretMetrics exports a kubernetes.io/tls secret as a whole rather than key by key.  The first cert of tls.crt
+// is the leaf, the rest of tls.crt its intermediates, and every cert of the optional ca.crt a CA, and tls.key is paired
+// with the leaf.
+func (c *SecretExporter) ExportTLSSecretMetrics(data map[string][]byte, secretName, secretNamespace string, labels, annotations map[string]string) error {
+	if data[corev1.TLSCertKey] == nil {
+		return fmt.Errorf("secret %v/%v has no %v", secretNamespace, secretName, corev1.TLSCertKey)
+	}
+
+	serviceline := labels["serviceline"]
+	objectLabels := copiedLabelValues(c.Profile, c.Cluster, c.CopyLabels, labels, annotations)
+
+	// ca.crt may hold the intermediates missing from tls.crt.  Errors are reported when it is exported below.
+	var caCerts []*x509.Certificate
+	if data[caCertKey] != nil {
+		if metricCollection, err := c.parsed.parse(data[caCertKey], ""); err == nil && len(metricCollection) > 0 {
+			caCerts = metricCollection[0].bundle
+		}
+	}
+
+	var leaf *x509.Certificate
+	for _, keyName := range []string{corev1.TLSCertKey, caCertKey} {
+		if data[keyName] == nil {
+			continue
+		}
+
+		metricCollection, err := c.parsed.parse(data[keyName], "")
+		if err != nil {
+			return err
+		}
+		c.countCertsParsed(secretNamespace, len(metricCollection))
+
+		for _, metric := range metricCollection {
+			role := "ca"
+			if keyName == corev1.TLSCertKey {
+				role = "intermediate"
+				// the bundle still holds the certs ignored by the cert filter
+				if metric.cert == metric.bundle[0] {
+					role = "leaf"
+					leaf = metric.cert
+				}
+			}
+
+			if metric.cert != nil {
+				if c.issued == nil {
+					c.issued = map[string]*x509.Certificate{}
+				}
+				c.issued[string(metric.cert.Raw)] = metric.cert
+			}
+
+			if !metrics.AllowSeries(c.source(), secretNamespace, secretName, serviceline, 3+commonSeriesPerCert()) {
+				continue
+			}
+
+			setSeries(c.source(), objectKey(secretNamespace, secretName), metrics.TLSSecretExpirySeconds, metric.durationUntilExpiry, metric.objectLabelValues(objectLabels, keyName, role, metric.issuer, metric.cn, secretName, secretNamespace)...)
+			setSeries(c.source(), objectKey(secretNamespace, secretName), metrics.TLSSecretNotAfterTimestamp, metric.notAfter, metric.objectLabelValues(objectLabels, keyName, role, metric.issuer, metric.cn, secretName, secretNamespace)...)
+			setSeries(c.source(), objectKey(secretNamespace, secretName), metrics.TLSSecretNotBeforeTimestamp, metric.notBefore, metric.objectLabelValues(objectLabels, keyName, role, metric.issuer, metric.cn, secretName, secretNamespace)...)
+			exportCommonMetrics(certSource{source: c.source(), namespace: secretNamespace, name: secretName, key: keyName, annotations: annotations, chain: caCerts}, metric)
+			c.expiries = trackObjectExpiry(c.expiries, secretNamespace, secretName, objectLabels, metric.cert)
+			if role == "leaf" {
+				c.exportSANMetrics(metric, keyName, secretName, secretNamespace, serviceline, annotations[expectedHostsAnnotation])
+			}
+		}
+	}
+
+	if data[corev1.TLSPrivateKeyKey] == nil || leaf == nil {
+		return nil
+	}
+
+	key, err := parsePrivateKey(data[corev1.TLSPrivateKeyKey], "")
+	if err != nil {
+		return err
+	}
+
+	if !metrics.AllowSeries(c.source(), secretNamespace, secretName, serviceline, 1) {
+		return nil
+	}
+
+	mismatch := 1.0
+	if keyMatchesCertificate(key, leaf) {
+		mismatch = 0
+	}
+	setSeries(c.source(), objectKey(secretNamespace, secretName), metrics.TLSSecretKeyPairMismatch, mismatch, secretName, secretNamespace)
+	return nil
+}
+
+// exportSANMetrics checks a leaf cert is valid for every host of the expected hosts annotation, catching renewals that
+// dropped a SAN
+func (c *SecretExporter) exportSANMetrics(metric certMetric, keyName, secretName, secretNamespace, serviceline, expectedHosts string) {
+	if metric.cert == nil || expectedHosts == "" {
+		return
+	}
+
+	var hosts []string
+	for _, host := range strings.Split(expectedHosts, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+
+	if !metrics.AllowSeries(c.source(), secretNamespace, secretName, serviceline, len(hosts)) {
+		return
+	}
+
+	for _, host := range hosts {
+		mismatch := 0.0
+		if metric.cert.VerifyHostname(host) != nil {
+			mismatch = 1
+		}
+		setSeries(c.source(), objectKey(secretNamespace, secretName), metrics.SecretSANMismatch, mismatch, keyName, metric.cn, secretName, secretNamespace, host)
+	}
+}
+
+// ExportKeyPairMetrics decrypts the provided private key and checks it against the certificates stored in the same secret.
+// tls.key is only paired with tls.crt, and ca.key with ca.crt, when the secret has one, since a rotation that updated just
+// one of them is exactly what needs to be caught.  A ca.key matching its ca.crt makes the secret an internal CA.
+func (c *SecretExporter) ExportKeyPairMetrics(keyBytes []byte, keyName, secretName, secretNamespace string, password string, data map[string][]byte, labels map[string]string) error {
+	key, err := parsePrivateKey(keyBytes, password)
+	if err != nil {
+		return err
+	}
+
+	candidates := data
+	if certKey, ok := keyPairs[keyName]; ok && data[certKey] != nil {
+		candidates = map[string][]byte{certKey: data[certKey]}
+	}
+
+	found := false
+	var matched *x509.Certificate
+	for _, certBytes := range candidates {
+		cert := leafCertificateFromPEM(certBytes)
+		if cert == nil {
+			continue
+		}
+
+		found = true
+		if keyMatchesCertificate(key, cert) {
+			matched = cert
+			break
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("no certificate found in secret %v/%v to pair with key %v", secretNamespace, secretName, keyName)
+	}
+
+	if !metrics.AllowSeries(c.source(), secretNamespace, secretName, labels["serviceline"], 1) {
+		return nil
+	}
+
+	if matched != nil {
+		setSeries(c.source(), objectKey(secretNamespace, secretName), metrics.SecretKeyPairMismatch, 0, keyName, secretName, secretNamespace)
+	} else {
+		setSeries(c.source(), objectKey(secretNamespace, secretName), metrics.SecretKeyPairMismatch, 1, keyName, secretName, secretNamespace)
+	}
+
+	if matched != nil && keyName == caPrivateKeyKey && data[caCertKey] != nil && matched.IsCA {
+		if metrics.AllowSeries(c.source(), secretNamespace, secretName, labels["serviceline"], 2) {
+			c.cas = append(c.cas, secretCA{cert: matched, secretName: secretName, secretNamespace: secretNamespace})
+		}
+	}
+
+	return nil
+}
+
+// exportCAMetrics compares every internal CA with the longest lived cert it issued that was seen during the cycle
+func (c *SecretExporter) exportCAMetrics() {
+	for _, ca := range c.cas {
+		var longest *x509.Certificate
+		for _, cert := range c.issued {
+			if bytes.Equal(cert.Raw, ca.cert.Raw) || !bytes.Equal(cert.RawIssuer, ca.cert.RawSubject) {
+				continue
+			}
+			if cert.CheckSignatureFrom(ca.cert) != nil {
+				continue
+			}
+			if longest == nil || cert.NotAfter.After(longest.NotAfter) {
+				longest = cert
+			}
+		}
+
+		if longest == nil {
+			continue
+		}
+
+		margin := ca.cert.NotAfter.Sub(longest.NotAfter).Seconds()
+		setSeries(c.source(), objectKey(ca.secretNamespace, ca.secretName), metrics.SecretCALifetimeMarginSeconds, margin, ca.cert.Subject.CommonName, ca.secretName, ca.secretNamespace)
+		if margin < 0 {
+			setSeries(c.source(), objectKey(ca.secretNamespace, ca.secretName), metrics.SecretCAOutlivedByIssuedCert, 1, ca.cert.Subject.CommonName, ca.secretName, ca.secretNamespace)
+		} else {
+			setSeries(c.source(), objectKey(ca.secretNamespace, ca.secretName), metrics.SecretCAOutlivedByIssuedCert, 0, ca.cert.Subject.CommonName, ca.secretName, ca.secretNamespace)
+		}
+	}
+}
+
+// BeginCycle is called before the secrets of a cycle are exported.  Metrics are not reset, so scrapes during a scan still
+// see every series of the previous one.
+func (c *SecretExporter) BeginCycle() {
+	c.cas = nil
+	c.issued = nil
+	c.certsParsed = map[string]int{}
+	c.expiries = nil
+	c.parsed.begin()
+	beginSeriesCycle(c.source())
+	metrics.StartCardinalityCycle(c.source())
+}
+
+// FinishCycle is called once every secret of a cycle has been exported.  It deletes the series of secrets that
+// disappeared or no longer hold the cert they exported.
+func (c *SecretExporter) FinishCycle() {
+	c.exportCAMetrics()
+	exportSoonestExpiries(c.source(), metrics.SecretSoonestNotAfterTimestamp, c.expiries)
+	deleteStaleSeries(c.source())
+	metrics.FinishCardinalityCycle(c.source())
+}
+
+// KeepNamespace keeps the series of the secrets of namespace until the next cycle, because it could not be listed
+// during this one.  It must be called before FinishCycle.
+func (c *SecretExporter) KeepNamespace(namespace string) {
+	keepNamespaceSeries(c.source(), namespace)
+}
+
+// source identifies the series of the exporter.  Each profile and cluster tracks its own, so they never delete each
+// other's.
+func (c *SecretExporter) source() string {
+	return scopedSource(sourceSecret, c.Profile, c.Cluster)
+}
+
+// CertsParsed returns the number of certs parsed per namespace during the current cycle
+func (c *SecretExporter) CertsParsed() map[string]int {
+	return c.certsParsed
+}
+
+func (c *SecretExporter) countCertsParsed(namespace string, n int) {
+	if c.certsParsed == nil {
+		c.certsParsed = map[string]int{}
+	}
+	c.certsParsed[namespace] += n
+}
+
+// ObjectExpiries returns the soonest notAfter of every secret exported during the current cycle
+func (c *SecretExporter) ObjectExpiries() []ObjectExpiry {
+	return sortedObjectExpiries(c.expiries)
 }
@@ -0,0 +1,92 @@
+package exporters
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type series struct {
+	vec         *prometheus.GaugeVec
+	labelValues []string
+	seen        bool
+}
+
+// trackedSeries remembers every series a source set, per object the cert was found in.  Stale series can then be
+// deleted one by one instead of resetting whole metrics while a scan is in progress, and a source never wipes the
+// series another source set on the shared metrics.
+var (
+	trackedSeriesMutex sync.Mutex
+	trackedSeries      = map[string]map[string]map[string]*series{}
+)
+
+// objectKey identifies the object a cert was found in within its source
+func objectKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// setSeries sets a series and records it as exported by object during the current cycle of source
+func setSeries(source, object string, vec *prometheus.GaugeVec, value float64, labelValues ...string) {
+	vec.WithLabelValues(labelValues...).Set(value)
+
+	trackedSeriesMutex.Lock()
+	defer trackedSeriesMutex.Unlock()
+
+	if trackedSeries[source] == nil {
+		trackedSeries[source] = map[string]map[string]*series{}
+	}
+	if trackedSeries[source][object] == nil {
+		trackedSeries[source][object] = map[string]*series{}
+	}
+	key := fmt.Sprintf("%p\xff%s", vec, strings.Join(labelValues, "\xff"))
+	trackedSeries[source][object][key] = &series{vec: vec, labelValues: labelValues, seen: true}
+}
+
+// beginSeriesCycle marks every series of source as stale until it is set again
+func beginSeriesCycle(source string) {
+	trackedSeriesMutex.Lock()
+	defer trackedSeriesMutex.Unlock()
+
+	for _, objectSeries := range trackedSeries[source] {
+		for _, s := range objectSeries {
+			s.seen = false
+		}
+	}
+}
+
+// keepNamespaceSeries marks the series of source found in namespace as set, so a namespace that could not be listed
+// keeps the series of its last successful scan instead of flapping.  The empty namespace keeps every series of source.
+func keepNamespaceSeries(source, namespace string) {
+	trackedSeriesMutex.Lock()
+	defer trackedSeriesMutex.Unlock()
+
+	for object, objectSeries := range trackedSeries[source] {
+		if namespace != "" && !strings.HasPrefix(object, objectKey(namespace, "")) {
+			continue
+		}
+		for _, s := range objectSeries {
+			s.seen = true
+		}
+	}
+}
+
+// deleteStaleSeries deletes the series of source that were not set since beginSeriesCycle.  Objects that disappeared
+// lose all of their series, objects that were updated only the ones they no longer export.
+func deleteStaleSeries(source string) {
+	trackedSeriesMutex.Lock()
+	defer trackedSeriesMutex.Unlock()
+
+	for object, objectSeries := range trackedSeries[source] {
+		for key, s := range objectSeries {
+			if !s.seen {
+				s.vec.DeleteLabelValues(s.labelValues...)
+				delete(objectSeries, key)
+			}
+		}
+		if len(objectSeries) == 0 {
+			delete(trackedSeries[source], object)
+		}
+	}
+}
@@ -16,14 +16,29 @@ func (c *WebhookExporter) ExportMetrics(bytes []byte, typeName, webhookName, adm
 	}
 
 	for _, metric := range metricCollection {
-		metrics.WebhookExpirySeconds.WithLabelValues(typeName, metric.issuer, metric.cn, webhookName, admissionReviewVersionName).Set(metric.durationUntilExpiry)
-		metrics.WebhookNotAfterTimestamp.WithLabelValues(typeName, metric.issuer, metric.cn, webhookName, admissionReviewVersionName).Set(metric.notAfter)
+		setSeries(sourceWebhook, objectKey("", webhookName), metrics.WebhookExpirySeconds, metric.durationUntilExpiry, metric.labelValues(typeName, metric.issuer, metric.cn, webhookName, admissionReviewVersionName)...)
+		setSeries(sourceWebhook, objectKey("", webhookName), metrics.WebhookNotAfterTimestamp, metric.notAfter, metric.labelValues(typeName, metric.issuer, metric.cn, webhookName, admissionReviewVersionName)...)
+		setSeries(sourceWebhook, objectKey("", webhookName), metrics.WebhookNotBeforeTimestamp, metric.notBefore, metric.labelValues(typeName, metric.issuer, metric.cn, webhookName, admissionReviewVersionName)...)
+		exportCommonMetrics(certSource{source: sourceWebhook, name: webhookName, key: typeName + "/" + admissionReviewVersionName}, metric)
 	}
 
 	return nil
 }
 
-func (c *WebhookExporter) ResetMetrics() {
-	metrics.WebhookExpirySeconds.Reset()
-	metrics.WebhookNotAfterTimestamp.Reset()
+// BeginCycle is called before the webhook configurations of a cycle are exported.  Metrics are not reset, so scrapes during a scan
+// still see every series of the previous one.
+func (c *WebhookExporter) BeginCycle() {
+	beginSeriesCycle(sourceWebhook)
+}
+
+// KeepSeries keeps every series of the exporter until the next cycle, because the webhook configurations could not be
+// listed during this one.  It must be called before FinishCycle.
+func (c *WebhookExporter) KeepSeries() {
+	keepNamespaceSeries(sourceWebhook, "")
+}
+
+// FinishCycle is called once every webhook configuration of a cycle has been exported.  It deletes the series of
+// webhook configurations that disappeared or no longer hold the cert they exported.
+func (c *WebhookExporter) FinishCycle() {
+	deleteStaleSeries(sourceWebhook)
 }
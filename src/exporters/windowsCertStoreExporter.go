@@ -0,0 +1,50 @@
+package exporters
+
+import (
+	"crypto/sha1"
+	"crypto/x509"
+	"fmt"
+
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+const sourceWindows = "windows"
+
+// WindowsCertStoreExporter exports the certs of the LocalMachine certificate stores of a Windows node, identified by
+// their thumbprint like certlm.msc and the Cert: drive of PowerShell do
+type WindowsCertStoreExporter struct {
+}
+
+// ExportMetrics exports the DER encoded cert found in store, e.g. My or Root
+func (c *WindowsCertStoreExporter) ExportMetrics(certBytes []byte, store, nodeName string) error {
+	cert, err := x509.ParseCertificate(certBytes)
+	if err != nil {
+		return fmt.Errorf("failed to parse a cert of the %v store: %w", store, err)
+	}
+
+	metric := getCertificateMetrics(cert)
+	thumbprint := windowsThumbprint(cert)
+
+	labels := []string{store, thumbprint, metric.issuer, metric.cn, nodeName}
+	setSeries(sourceWindows, objectKey(store, thumbprint), metrics.WindowsCertExpirySeconds, metric.durationUntilExpiry, labels...)
+	setSeries(sourceWindows, objectKey(store, thumbprint), metrics.WindowsCertNotAfterTimestamp, metric.notAfter, labels...)
+	exportCommonMetrics(certSource{source: sourceWindows, name: store, key: thumbprint}, metric)
+
+	return nil
+}
+
+// windowsThumbprint returns the uppercase hex SHA-1 hash of the DER encoded cert, as Windows shows it
+func windowsThumbprint(cert *x509.Certificate) string {
+	return fmt.Sprintf("%X", sha1.Sum(cert.Raw))
+}
+
+// BeginCycle is called before the certificate stores of a cycle are exported
+func (c *WindowsCertStoreExporter) BeginCycle() {
+	beginSeriesCycle(sourceWindows)
+}
+
+// FinishCycle is called once every certificate store of a cycle has been exported.  It deletes the series of the
+// certs that were removed from their store.
+func (c *WindowsCertStoreExporter) FinishCycle() {
+	deleteStaleSeries(sourceWindows)
+}
@@ -7,6 +7,7 @@ import (
 
 // KubeConfig is a partial description of a kubeconfig file.  It defines only the fields required by this application.
 type KubeConfig struct {
+	Kind     string `yaml:"kind"`
 	Clusters []struct {
 		Name    string
 		Cluster struct {
@@ -25,14 +26,19 @@ type KubeConfig struct {
 
 // ParseKubeConfig serializes the provided kubeconfig file into the KubeConfig struct
 func ParseKubeConfig(file string) (*KubeConfig, error) {
-	k := &KubeConfig{}
-
 	data, err := ioutil.ReadFile(file)
 	if err != nil {
 		return nil, err
 	}
 
-	err = yaml.Unmarshal([]byte(data), k)
+	return ParseKubeConfigBytes(data)
+}
+
+// ParseKubeConfigBytes serializes the provided kubeconfig, e.g. one stored in a secret, into the KubeConfig struct
+func ParseKubeConfigBytes(data []byte) (*KubeConfig, error) {
+	k := &KubeConfig{}
+
+	err := yaml.Unmarshal(data, k)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,164 @@
+package metrics
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// cardinality counts the series every source exports per namespace, object and serviceline.  The counts of the cycle
+// in progress are kept apart from the counts of the last completed cycle, which are the ones served.
+type cardinality struct {
+	mutex           sync.Mutex
+	maxPerNamespace int
+	maxPerObject    int
+	namespaces      map[string]map[string]int
+	objects         map[string]map[string]int
+	servicelines    map[string]map[string]int
+	published       map[string]cardinalityCounts
+}
+
+type cardinalityCounts struct {
+	namespaces   map[string]int
+	servicelines map[string]int
+}
+
+var tracker = &cardinality{
+	namespaces:   map[string]map[string]int{},
+	objects:      map[string]map[string]int{},
+	servicelines: map[string]map[string]int{},
+	published:    map[string]cardinalityCounts{},
+}
+
+// Contributor is a label value and the number of series exported for it
+type Contributor struct {
+	Value  string `json:"value"`
+	Series int    `json:"series"`
+}
+
+// SetMaxSeriesPerNamespace caps the number of series each source exports per namespace.  0 disables the quota.
+func SetMaxSeriesPerNamespace(max int) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	tracker.maxPerNamespace = max
+}
+
+// SetMaxSeriesPerObject caps the number of series each source exports per secret or configmap, e.g. for truststores
+// holding hundreds of certs.  0 disables the cap.
+func SetMaxSeriesPerObject(max int) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	tracker.maxPerObject = max
+}
+
+// AllowSeries records n series about to be exported by source for the object ns/name and returns false if they would
+// take the namespace or the object over its quota.  Nothing is recorded for series that are not allowed.
+func AllowSeries(source, ns, name, serviceline string, n int) bool {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	object := ns + "/" + name
+	if tracker.maxPerNamespace > 0 && tracker.namespaces[source][ns]+n > tracker.maxPerNamespace ||
+		tracker.maxPerObject > 0 && tracker.objects[source][object]+n > tracker.maxPerObject {
+		SeriesDroppedTotal.WithLabelValues(source, ns).Add(float64(n))
+		return false
+	}
+
+	increment(tracker.namespaces, source, ns, n)
+	increment(tracker.objects, source, object, n)
+	increment(tracker.servicelines, source, serviceline, n)
+	return true
+}
+
+// StartCardinalityCycle forgets the series counted for source in its previous cycle
+func StartCardinalityCycle(source string) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	delete(tracker.namespaces, source)
+	delete(tracker.objects, source)
+	delete(tracker.servicelines, source)
+}
+
+// FinishCardinalityCycle publishes the series counted for source in the cycle that just completed
+func FinishCardinalityCycle(source string) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	tracker.published[source] = cardinalityCounts{
+		namespaces:   tracker.namespaces[source],
+		servicelines: tracker.servicelines[source],
+	}
+}
+
+// CardinalityHandler lists the namespaces and servicelines contributing the most series in the last completed cycle
+// of every source.  The number of entries returned can be changed with the top query parameter (default 10).
+func CardinalityHandler(w http.ResponseWriter, r *http.Request) {
+	top := 10
+	if t := r.URL.Query().Get("top"); t != "" {
+		var err error
+		top, err = strconv.Atoi(t)
+		if err != nil || top < 1 {
+			http.Error(w, "top must be a positive integer", http.StatusBadRequest)
+			return
+		}
+	}
+
+	tracker.mutex.Lock()
+	namespaces := map[string]int{}
+	servicelines := map[string]int{}
+	for _, counts := range tracker.published {
+		sum(namespaces, counts.namespaces)
+		sum(servicelines, counts.servicelines)
+	}
+	response := struct {
+		MaxSeriesPerNamespace int           `json:"maxSeriesPerNamespace"`
+		MaxSeriesPerObject    int           `json:"maxSeriesPerObject"`
+		Namespaces            []Contributor `json:"namespaces"`
+		Servicelines          []Contributor `json:"servicelines"`
+	}{
+		MaxSeriesPerNamespace: tracker.maxPerNamespace,
+		MaxSeriesPerObject:    tracker.maxPerObject,
+		Namespaces:            topContributors(namespaces, top),
+		Servicelines:          topContributors(servicelines, top),
+	}
+	tracker.mutex.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+func increment(counts map[string]map[string]int, source, value string, n int) {
+	if counts[source] == nil {
+		counts[source] = map[string]int{}
+	}
+	counts[source][value] += n
+}
+
+func sum(into, counts map[string]int) {
+	for value, n := range counts {
+		into[value] += n
+	}
+}
+
+func topContributors(counts map[string]int, top int) []Contributor {
+	contributors := make([]Contributor, 0, len(counts))
+	for value, n := range counts {
+		contributors = append(contributors, Contributor{Value: value, Series: n})
+	}
+	sort.Slice(contributors, func(i, j int) bool {
+		if contributors[i].Series != contributors[j].Series {
+			return contributors[i].Series > contributors[j].Series
+		}
+		return contributors[i].Value < contributors[j].Value
+	})
+
+	if len(contributors) > top {
+		contributors = contributors[:top]
+	}
+	return contributors
+}
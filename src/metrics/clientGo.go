@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	clientmetrics "k8s.io/client-go/tools/metrics"
+)
+
+var (
+	// KubeAPIRequestsTotal is a prometheus counter that indicates the number of requests sent to the kubernetes API by
+	// status code.
+	KubeAPIRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "kube_api_requests_total",
+			Help:      "Number of requests sent to the kubernetes API, by status code. code is <error> when no response was received.",
+		},
+		[]string{"code", "method", "host"},
+	)
+
+	// KubeAPIRequestDuration is a prometheus histogram that indicates the latency of the requests sent to the kubernetes
+	// API.
+	KubeAPIRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "kube_api_request_duration_seconds",
+			Help:      "Latency of the requests sent to the kubernetes API.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"verb", "host"},
+	)
+
+	// KubeAPIRateLimiterDuration is a prometheus histogram that indicates how long requests to the kubernetes API waited
+	// for the client side rate limiter.
+	KubeAPIRateLimiterDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "kube_api_rate_limiter_duration_seconds",
+			Help:      "Time the requests sent to the kubernetes API waited for the client side rate limiter.",
+			Buckets:   prometheus.DefBuckets,
+		},
+		[]string{"verb", "host"},
+	)
+)
+
+// registerClientGoMetrics makes client-go report every request to the kubernetes API.  Scans that are throttled or
+// failing show up there before their results go stale.
+func registerClientGoMetrics() {
+	prometheus.MustRegister(KubeAPIRequestsTotal)
+	prometheus.MustRegister(KubeAPIRequestDuration)
+	prometheus.MustRegister(KubeAPIRateLimiterDuration)
+
+	clientmetrics.Register(clientmetrics.RegisterOpts{
+		RequestLatency:     latencyMetric{KubeAPIRequestDuration},
+		RateLimiterLatency: latencyMetric{KubeAPIRateLimiterDuration},
+		RequestResult:      resultMetric{},
+	})
+}
+
+type latencyMetric struct {
+	histogram *prometheus.HistogramVec
+}
+
+// Observe records the latency by verb and host.  The path is left out, as it holds the names of objects.
+func (m latencyMetric) Observe(ctx context.Context, verb string, u url.URL, latency time.Duration) {
+	m.histogram.WithLabelValues(verb, u.Host).Observe(latency.Seconds())
+}
+
+type resultMetric struct{}
+
+func (resultMetric) Increment(ctx context.Context, code, method, host string) {
+	KubeAPIRequestsTotal.WithLabelValues(code, method, host).Inc()
+}
@@ -0,0 +1,187 @@
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ExpiringSeries describes every expiry series currently exported with less than window left before the cert expires
+func ExpiringSeries(window time.Duration) ([]string, error) {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	var expiring []string
+	for _, family := range families {
+		name := internalName(family.GetName())
+		if !strings.HasPrefix(name, namespace+"_") || !strings.Contains(name, "_expires_in_seconds") {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			if metric.GetGauge().GetValue() >= window.Seconds() {
+				continue
+			}
+
+			labels := make([]string, 0, len(metric.GetLabel()))
+			for _, label := range metric.GetLabel() {
+				labels = append(labels, fmt.Sprintf("%s=%q", label.GetName(), label.GetValue()))
+			}
+			expiring = append(expiring, fmt.Sprintf("%s{%s} %v", family.GetName(), strings.Join(labels, ","), metric.GetGauge().GetValue()))
+		}
+	}
+
+	sort.Strings(expiring)
+	return expiring, nil
+}
+
+// InventoryEntry is a cert, or a token or key with an expiry, exported by any checker
+type InventoryEntry struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Key       string `json:"key"`
+	CN        string `json:"cn"`
+	Issuer    string `json:"issuer"`
+	DaysLeft  int    `json:"daysLeft"`
+	Expired   bool   `json:"expired"`
+	// Cluster is empty for the cluster cert-exporter runs in
+	Cluster string `json:"cluster,omitempty"`
+	// Node is the node a file or certificate store was read on, empty for objects of the kubernetes API
+	Node string `json:"node,omitempty"`
+	// SecondsLeft is the time left as of the last scan, for callers needing more precision than DaysLeft
+	SecondsLeft float64 `json:"-"`
+}
+
+// inventorySource describes how the labels of an expiry metric map to an inventory entry
+type inventorySource struct {
+	kind string
+	// namespace and name are the labels naming the object the cert was found in.  fixedNamespace is used for objects
+	// of a single namespace, whose metrics do not label it.
+	namespace      string
+	fixedNamespace string
+	name           string
+	// key are the labels joined with / into the key of the entry.  Alternatives are separated by |, the first
+	// non-empty one is used.
+	key    []string
+	cn     string
+	issuer string
+	node   string
+}
+
+// inventorySources maps the expiry metric of every checker to the way its labels make up an inventory entry
+var inventorySources = map[string]inventorySource{
+	namespace + "_secret_expires_in_seconds":            {kind: "secret", namespace: "secret_namespace", name: "secret_name", key: []string{"key_name"}, cn: "cn", issuer: "issuer"},
+	namespace + "_tls_secret_expires_in_seconds":        {kind: "secret", namespace: "secret_namespace", name: "secret_name", key: []string{"key_name"}, cn: "cn", issuer: "issuer"},
+	namespace + "_secret_kubeconfig_expires_in_seconds": {kind: "secret", namespace: "secret_namespace", name: "secret_name", key: []string{"key_name", "type", "name"}, cn: "cn", issuer: "issuer"},
+	namespace + "_secret_archive_expires_in_seconds":    {kind: "secret", namespace: "secret_namespace", name: "secret_name", key: []string{"key_name", "member"}, cn: "cn", issuer: "issuer"},
+	namespace + "_secret_jwt_expires_in_seconds":        {kind: "secret", namespace: "secret_namespace", name: "secret_name", key: []string{"key_name"}, cn: "subject", issuer: "issuer"},
+	namespace + "_secret_pgp_key_expires_in_seconds":    {kind: "secret", namespace: "secret_namespace", name: "secret_name", key: []string{"key_name", "fingerprint"}, cn: "uid"},
+	namespace + "_configmap_expires_in_seconds":         {kind: "configmap", namespace: "configmap_namespace", name: "configmap_name", key: []string{"key_name"}, cn: "cn", issuer: "issuer"},
+	namespace + "_configmap_config_expires_in_seconds":  {kind: "configmap", namespace: "configmap_namespace", name: "configmap_name", key: []string{"key_name", "config_path|config_field"}, cn: "cn", issuer: "issuer"},
+	namespace + "_cert_expires_in_seconds":              {kind: "file", name: "filename", cn: "cn", issuer: "issuer", node: "nodename"},
+	namespace + "_kubeconfig_expires_in_seconds":        {kind: "kubeconfig", name: "filename", key: []string{"type", "name"}, cn: "cn", issuer: "issuer", node: "nodename"},
+	namespace + "_kubelet_cert_expires_in_seconds":      {kind: "kubelet", name: "filename", key: []string{"type"}, node: "nodename"},
+	namespace + "_webhook_expires_in_seconds":           {kind: "webhook", name: "webhook_name", key: []string{"admission_review_version_name"}, cn: "cn", issuer: "issuer"},
+	namespace + "_route_expires_in_seconds":             {kind: "route", namespace: "route_namespace", name: "route_name", key: []string{"field"}, cn: "cn", issuer: "issuer"},
+	namespace + "_gateway_expires_in_seconds":           {kind: "gateway", namespace: "gateway_namespace", name: "gateway_name", key: []string{"listener"}, cn: "cn", issuer: "issuer"},
+	namespace + "_csr_expires_in_seconds":               {kind: "csr", name: "csr_name", cn: "cn", issuer: "issuer"},
+	namespace + "_bootstrap_token_expires_in_seconds":   {kind: "bootstrap-token", fixedNamespace: "kube-system", name: "secret_name", key: []string{"token_id"}},
+	namespace + "_envoy_cert_expires_in_seconds":        {kind: "envoy", namespace: "pod_namespace", name: "target", key: []string{"type", "path"}},
+	namespace + "_windows_cert_expires_in_seconds":      {kind: "windows", name: "store", key: []string{"thumbprint"}, cn: "cn", issuer: "issuer", node: "nodename"},
+	namespace + "_cert_expires_in_seconds_aws":          {kind: "aws", name: "secretName", key: []string{"key", "file"}, cn: "cn", issuer: "issuer"},
+}
+
+// inventoryGatherer gathers the metrics as registered, before the prefix, constant, dropped and hashed labels of the
+// exposition are applied, so the inventory always sees the real names and label values
+var inventoryGatherer = prometheus.DefaultGatherer
+
+// entry returns the inventory entry of a series of the expiry metric of source
+func (source inventorySource) entry(labels map[string]string, secondsLeft float64) InventoryEntry {
+	var key []string
+	for _, alternatives := range source.key {
+		for _, label := range strings.Split(alternatives, "|") {
+			if labels[label] != "" {
+				key = append(key, labels[label])
+				break
+			}
+		}
+	}
+
+	ns := source.fixedNamespace
+	if source.namespace != "" {
+		ns = labels[source.namespace]
+	}
+
+	cluster := labels["cluster"]
+	if cluster == clusterName {
+		cluster = ""
+	}
+
+	return InventoryEntry{
+		Kind:        source.kind,
+		Namespace:   ns,
+		Name:        labels[source.name],
+		Key:         strings.Join(key, "/"),
+		CN:          labels[source.cn],
+		Issuer:      labels[source.issuer],
+		DaysLeft:    int(secondsLeft / (24 * 60 * 60)),
+		Expired:     secondsLeft <= 0,
+		Cluster:     cluster,
+		Node:        labels[source.node],
+		SecondsLeft: secondsLeft,
+	}
+}
+
+// Inventory lists every cert currently exported by any checker, sorted by cluster, namespace, kind, name and key
+func Inventory() ([]InventoryEntry, error) {
+	families, err := inventoryGatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []InventoryEntry
+	for _, family := range families {
+		source, ok := inventorySources[family.GetName()]
+		if !ok {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			entries = append(entries, source.entry(labels, metric.GetGauge().GetValue()))
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Cluster != b.Cluster {
+			return a.Cluster < b.Cluster
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		if a.Key != b.Key {
+			return a.Key < b.Key
+		}
+		if a.Node != b.Node {
+			return a.Node < b.Node
+		}
+		return a.CN < b.CN
+	})
+	return entries, nil
+}
@@ -0,0 +1,195 @@
+package metrics
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"google.golang.org/protobuf/proto"
+)
+
+// constLabels are added to every metric gathered, including the go and process metrics.  They are set before Init.
+var constLabels = map[string]string{}
+
+// clusterName is the name of the cluster cert-exporter runs in, if set
+var clusterName string
+
+// SetClusterName labels every metric with the cluster cert-exporter runs in.  Metrics that label the cluster of each
+// cert themselves, the secret and configmap metrics when other clusters are scanned, keep their own value.  It must be
+// called before Init.
+func SetClusterName(name string) {
+	clusterName = name
+	constLabels["cluster"] = name
+}
+
+// exportedLabels are the labels of the metrics other than the secret and configmap metrics, which constant labels may
+// not replace either
+var exportedLabels = []string{"admission_review_version_name", "candidate", "checker", "code", "component", "csr_name", "feature", "field", "file", "filename", "gateway_name", "gateway_namespace", "goversion", "host", "hostname", "issuer_dn", "key", "key_algorithm", "key_size", "kind", "le", "listener", "method", "missing_issuer", "mode", "name", "namespace", "nodename", "path", "pod_name", "pod_namespace", "quantile", "reason", "resource", "result", "revision", "role", "route_name", "route_namespace", "sans", "secret_key", "signature_algorithm", "signer_name", "source", "state", "subject", "target", "token_id", "type", "type_name", "verb", "version", "webhook_name"}
+
+var labelNamePattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// SetConstLabels adds labels with a fixed value to every metric, e.g. the environment or region.  Names may neither
+// replace the labels of any metric nor the copied labels.  It must be called after SetCopiedLabels and before Init.
+func SetConstLabels(labels map[string]string) error {
+	for name, value := range labels {
+		if !labelNamePattern.MatchString(name) || strings.HasPrefix(name, "__") {
+			return fmt.Errorf("%q is not a valid label name", name)
+		}
+		for _, taken := range append(append(append([]string{}, exportedLabels...), reservedLabels...), copiedLabels...) {
+			if name == taken {
+				return fmt.Errorf("label %v is already exported", name)
+			}
+		}
+		constLabels[name] = value
+	}
+	return nil
+}
+
+// droppedLabels are removed from every metric gathered and the values of hashedLabels replaced by a hash.  They are set
+// before Init.
+var (
+	droppedLabels = map[string]bool{}
+	hashedLabels  = map[string]bool{}
+)
+
+// SetDroppedLabels removes labels from every metric, e.g. cn or issuer, to cut the cardinality of the metrics.  Series
+// left with the same labels are merged.  It must be called before Init.
+func SetDroppedLabels(names []string) error {
+	return setLabelNames(droppedLabels, names)
+}
+
+// SetHashedLabels replaces the values of labels of every metric with a short hash, e.g. for cn values that must not be
+// exposed but still tell certs apart.  It must be called before Init.
+func SetHashedLabels(names []string) error {
+	return setLabelNames(hashedLabels, names)
+}
+
+func setLabelNames(set map[string]bool, names []string) error {
+	for _, name := range names {
+		if !labelNamePattern.MatchString(name) {
+			return fmt.Errorf("%q is not a valid label name", name)
+		}
+		set[name] = true
+	}
+	return nil
+}
+
+// hashLabelValue returns the first 16 hex digits of the SHA-256 of value.  Empty values stay empty.
+func hashLabelValue(value string) string {
+	if value == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+var metricNamePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// metricPrefix replaces cert_exporter at the start of the name of every metric gathered.  It is set before Init.
+var metricPrefix = namespace
+
+// SetMetricPrefix replaces the cert_exporter prefix of every metric name, so differently configured instances can be
+// told apart.  It must be called before Init.
+func SetMetricPrefix(prefix string) error {
+	if !metricNamePattern.MatchString(prefix) {
+		return fmt.Errorf("%q is not a valid metric name prefix", prefix)
+	}
+	metricPrefix = prefix
+	return nil
+}
+
+// internalName returns the name a gathered metric was registered with, before its prefix was replaced
+func internalName(name string) string {
+	if metricPrefix == namespace || !strings.HasPrefix(name, metricPrefix+"_") {
+		return name
+	}
+	return namespace + strings.TrimPrefix(name, metricPrefix)
+}
+
+// exposedGatherer renames every metric gathered to the configured prefix, drops and hashes labels and adds the
+// constant labels to every metric that does not have them yet
+type exposedGatherer struct {
+	gatherer prometheus.Gatherer
+	prefix   string
+	labels   map[string]string
+	dropped  map[string]bool
+	hashed   map[string]bool
+}
+
+func (g exposedGatherer) Gather() ([]*dto.MetricFamily, error) {
+	families, err := g.gatherer.Gather()
+	for _, family := range families {
+		if strings.HasPrefix(family.GetName(), namespace+"_") {
+			family.Name = proto.String(g.prefix + strings.TrimPrefix(family.GetName(), namespace))
+		}
+
+		for _, metric := range family.GetMetric() {
+			present := map[string]bool{}
+			kept := metric.Label[:0]
+			for _, label := range metric.GetLabel() {
+				if g.dropped[label.GetName()] {
+					continue
+				}
+				if g.hashed[label.GetName()] {
+					label.Value = proto.String(hashLabelValue(label.GetValue()))
+				}
+				present[label.GetName()] = true
+				kept = append(kept, label)
+			}
+			metric.Label = kept
+
+			for name, value := range g.labels {
+				if !present[name] {
+					metric.Label = append(metric.Label, &dto.LabelPair{Name: proto.String(name), Value: proto.String(value)})
+				}
+			}
+			sort.Slice(metric.Label, func(i, j int) bool {
+				return metric.Label[i].GetName() < metric.Label[j].GetName()
+			})
+		}
+		if len(g.dropped) > 0 {
+			mergeDuplicates(family)
+		}
+	}
+	return families, err
+}
+
+// mergeDuplicates merges the series of family left with the same labels once labels were dropped.  Gauges keep the
+// lowest value, e.g. the soonest expiry, and counters are summed.  Of other types the first series is kept.
+func mergeDuplicates(family *dto.MetricFamily) {
+	merged := map[string]*dto.Metric{}
+	kept := family.Metric[:0]
+	for _, metric := range family.GetMetric() {
+		pairs := make([]string, 0, len(metric.GetLabel()))
+		for _, label := range metric.GetLabel() {
+			pairs = append(pairs, label.GetName()+"\xff"+label.GetValue())
+		}
+		key := strings.Join(pairs, "\xfe")
+
+		first, ok := merged[key]
+		if !ok {
+			merged[key] = metric
+			kept = append(kept, metric)
+			continue
+		}
+
+		switch {
+		case metric.Gauge != nil && first.Gauge != nil:
+			if metric.Gauge.GetValue() < first.Gauge.GetValue() {
+				first.Gauge.Value = metric.Gauge.Value
+			}
+		case metric.Untyped != nil && first.Untyped != nil:
+			if metric.Untyped.GetValue() < first.Untyped.GetValue() {
+				first.Untyped.Value = metric.Untyped.Value
+			}
+		case metric.Counter != nil && first.Counter != nil:
+			first.Counter.Value = proto.Float64(first.Counter.GetValue() + metric.Counter.GetValue())
+		}
+	}
+	family.Metric = kept
+}
@@ -1,149 +1,1410 @@
 package metrics
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
 
 const (
 	namespace = "cert_exporter"
 )
 
-var (
-	// ErrorTotal is a prometheus counter that indicates the total number of unexpected errors encountered by the application
-	ErrorTotal = prometheus.NewCounter(
-		prometheus.CounterOpts{
+// optionalCertLabels are added to every expiry metric.  They are enabled before Init.
+var optionalCertLabels []string
+
+// AddCertLabel adds a label describing the certificate to every expiry metric.  It must be called before Init and the
+// values of the label appended, in order, to the labels of every expiry metric.
+func AddCertLabel(name string) {
+	optionalCertLabels = append(optionalCertLabels, name)
+}
+
+// profileLabelEnabled labels the secret and configmap metrics with the config profile that exported them.  It is set
+// before Init.
+var profileLabelEnabled = false
+
+// EnableProfileLabel adds the profile label to the secret and configmap metrics.  It must be called before Init.
+func EnableProfileLabel() {
+	profileLabelEnabled = true
+}
+
+// clusterLabelEnabled labels the secret and configmap metrics with the cluster they were found in.  It is set before
+// Init.
+var clusterLabelEnabled = false
+
+// EnableClusterLabel adds the cluster label to the secret and configmap metrics.  It must be called before Init.
+func EnableClusterLabel() {
+	clusterLabelEnabled = true
+}
+
+// componentLabelEnabled labels the cert and kubeconfig file metrics with the component of a preset the file belongs
+// to.  It is set before Init.
+var componentLabelEnabled = false
+
+// EnableComponentLabel adds the component label to the cert and kubeconfig file metrics.  It must be called before
+// Init.
+func EnableComponentLabel() {
+	componentLabelEnabled = true
+}
+
+// copiedLabels are the labels copied from the secrets and configmaps certs are found in.  They are set before Init.
+var copiedLabels = []string{"serviceline"}
+
+// reservedLabels are the labels of the secret and configmap metrics copied labels may not replace
+var reservedLabels = []string{"key_name", "issuer", "cn", "secret_name", "secret_namespace", "configmap_name", "configmap_namespace", "config_source", "config_field", "config_path", "member", "serial", "fingerprint", "subject", "profile", "cluster", "type", "name", "role", "uid"}
+
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// SanitizeLabelName turns a kubernetes label key into a valid prometheus label name, e.g. app.kubernetes.io/name into
+// app_kubernetes_io_name
+func SanitizeLabelName(key string) string {
+	name := invalidLabelChars.ReplaceAllString(key, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// SetCopiedLabels sets the names of the labels copied from secrets and configmaps.  It must be called before Init.
+func SetCopiedLabels(names []string) error {
+	seen := map[string]bool{}
+	for _, name := range names {
+		for _, reserved := range reservedLabels {
+			if name == reserved {
+				return fmt.Errorf("label %v is already exported", name)
+			}
+		}
+		if seen[name] {
+			return fmt.Errorf("label %v is copied twice", name)
+		}
+		seen[name] = true
+	}
+
+	copiedLabels = names
+	return nil
+}
+
+// objectLabels returns the labels of a secret or configmap metric followed by the profile and cluster labels, when
+// enabled, and the labels copied from the object
+func objectLabels(labels ...string) []string {
+	if profileLabelEnabled {
+		labels = append(labels, "profile")
+	}
+	if clusterLabelEnabled {
+		labels = append(labels, "cluster")
+	}
+	return append(labels, copiedLabels...)
+}
+
+// fileLabels returns the labels of a cert or kubeconfig file metric followed by the component label, when enabled
+func fileLabels(labels ...string) []string {
+	if componentLabelEnabled {
+		labels = append(labels, "component")
+	}
+	return labels
+}
+
+// expiryLabels returns the labels of an expiry metric followed by the optional cert labels
+func expiryLabels(labels ...string) []string {
+	return append(labels, optionalCertLabels...)
+}
+
+// certLabels returns the labels identifying a certificate in the metrics shared by all checkers, followed by extra
+func certLabels(extra ...string) []string {
+	return append([]string{"source", "namespace", "name", "key_name", "issuer", "cn"}, extra...)
+}
+
+var (
+	// ErrorTotal is a prometheus counter that indicates the total number of unexpected errors encountered by the application
+	ErrorTotal = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "error_total",
+			Help:      "Cert Exporter Errors",
+		},
+	)
+
+	// ErrorsTotal is a prometheus counter of the errors encountered by every checker, by namespace and reason.  Every
+	// error it counts is also counted by ErrorTotal.
+	ErrorsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "errors_total",
+			Help:      "Errors encountered by the checker, by namespace and reason.",
+		},
+		[]string{"checker", "namespace", "reason"},
+	)
+
+	// CertExpirySeconds is a prometheus gauge that indicates the number of seconds until certificates on disk expires.
+	CertExpirySeconds *prometheus.GaugeVec
+
+	// CertNotAfterTimestamp is a prometheus gauge that indicates the NotAfter timestamp.
+	CertNotAfterTimestamp *prometheus.GaugeVec
+
+	// CertNotBeforeTimestamp is a prometheus gauge that indicates the NotBefore timestamp.
+	CertNotBeforeTimestamp *prometheus.GaugeVec
+
+	// KubeConfigExpirySeconds is a prometheus gauge that indicates the number of seconds until a kubeconfig certificate expires.
+	KubeConfigExpirySeconds *prometheus.GaugeVec
+
+	// KubeConfigNotAfterTimestamp is a prometheus gauge that indicates the NotAfter timestamp.
+	KubeConfigNotAfterTimestamp *prometheus.GaugeVec
+
+	// KubeConfigNotBeforeTimestamp is a prometheus gauge that indicates the NotBefore timestamp.
+	KubeConfigNotBeforeTimestamp *prometheus.GaugeVec
+
+	// SecretExpirySeconds is a prometheus gauge that indicates the number of seconds until a kubernetes secret certificate expires
+	SecretExpirySeconds *prometheus.GaugeVec
+
+	// SecretNotAfterTimestamp is a prometheus gauge that indicates the NotAfter timestamp.
+	SecretNotAfterTimestamp *prometheus.GaugeVec
+
+	// SecretNotBeforeTimestamp is a prometheus gauge that indicates the NotBefore timestamp.
+	SecretNotBeforeTimestamp *prometheus.GaugeVec
+
+	// SecretKubeConfigExpirySeconds is a prometheus gauge that indicates the number of seconds until a cert in a kubeconfig stored in a kubernetes secret expires
+	SecretKubeConfigExpirySeconds *prometheus.GaugeVec
+
+	// SecretKubeConfigNotAfterTimestamp is a prometheus gauge that indicates the NotAfter timestamp.
+	SecretKubeConfigNotAfterTimestamp *prometheus.GaugeVec
+
+	// SecretKubeConfigNotBeforeTimestamp is a prometheus gauge that indicates the NotBefore timestamp.
+	SecretKubeConfigNotBeforeTimestamp *prometheus.GaugeVec
+
+	// SecretArchiveExpirySeconds is a prometheus gauge that indicates the number of seconds until a cert in an archive stored in a kubernetes secret expires
+	SecretArchiveExpirySeconds *prometheus.GaugeVec
+
+	// SecretArchiveNotAfterTimestamp is a prometheus gauge that indicates the NotAfter timestamp.
+	SecretArchiveNotAfterTimestamp *prometheus.GaugeVec
+
+	// SecretArchiveNotBeforeTimestamp is a prometheus gauge that indicates the NotBefore timestamp.
+	SecretArchiveNotBeforeTimestamp *prometheus.GaugeVec
+
+	// SecretJWTExpirySeconds is a prometheus gauge that indicates the number of seconds until a JWT in a kubernetes secret expires
+	SecretJWTExpirySeconds *prometheus.GaugeVec
+
+	// SecretJWTExpiryTimestamp is a prometheus gauge that indicates the exp claim of a JWT in a kubernetes secret.
+	SecretJWTExpiryTimestamp *prometheus.GaugeVec
+
+	// SecretPGPKeyExpirySeconds is a prometheus gauge that indicates the number of seconds until an OpenPGP key in a
+	// kubernetes secret expires
+	SecretPGPKeyExpirySeconds *prometheus.GaugeVec
+
+	// SecretPGPKeyExpiryTimestamp is a prometheus gauge that indicates the expiration timestamp of an OpenPGP key in a
+	// kubernetes secret.
+	SecretPGPKeyExpiryTimestamp *prometheus.GaugeVec
+
+	// SecretSoonestNotAfterTimestamp is a prometheus gauge that indicates the NotAfter timestamp of the cert expiring
+	// first in a kubernetes secret.
+	SecretSoonestNotAfterTimestamp *prometheus.GaugeVec
+
+	// TLSSecretExpirySeconds is a prometheus gauge that indicates the number of seconds until a cert of a kubernetes.io/tls secret expires
+	TLSSecretExpirySeconds *prometheus.GaugeVec
+
+	// TLSSecretNotAfterTimestamp is a prometheus gauge that indicates the NotAfter timestamp.
+	TLSSecretNotAfterTimestamp *prometheus.GaugeVec
+
+	// TLSSecretNotBeforeTimestamp is a prometheus gauge that indicates the NotBefore timestamp.
+	TLSSecretNotBeforeTimestamp *prometheus.GaugeVec
+
+	// TLSSecretKeyPairMismatch is a prometheus gauge that indicates if tls.key does not correspond to the leaf cert in tls.crt.
+	TLSSecretKeyPairMismatch = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "tls_secret_keypair_mismatch",
+			Help:      "1 if tls.key in the kubernetes.io/tls secret does not correspond to the leaf cert in tls.crt, 0 otherwise.",
+		},
+		[]string{"secret_name", "secret_namespace"},
+	)
+
+	// SecretKeyPairMismatch is a prometheus gauge that indicates if a private key in a secret does not correspond to the certificate stored with it.
+	SecretKeyPairMismatch = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "secret_keypair_mismatch",
+			Help:      "1 if the private key in the secret does not correspond to the certificate stored with it, 0 otherwise.",
+		},
+		[]string{"key_name", "secret_name", "secret_namespace"},
+	)
+
+	// SecretSANMismatch is a prometheus gauge that indicates if a cert in a secret does not cover a host the secret expects.
+	SecretSANMismatch = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "secret_san_mismatch",
+			Help:      "1 if the leaf cert in the secret is not valid for a host listed in its cert-exporter.io/expected-hosts annotation, 0 otherwise.",
+		},
+		[]string{"key_name", "cn", "secret_name", "secret_namespace", "host"},
+	)
+
+	// SecretCALifetimeMarginSeconds is a prometheus gauge that indicates how long an internal CA outlives the longest lived cert it issued.
+	SecretCALifetimeMarginSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "secret_ca_lifetime_margin_seconds",
+			Help:      "Seconds between the expiry of the longest lived cert issued by the CA in the secret and the expiry of the CA. Negative if the cert outlives the CA.",
+		},
+		[]string{"cn", "secret_name", "secret_namespace"},
+	)
+
+	// SecretCAOutlivedByIssuedCert is a prometheus gauge that flags internal CAs expiring before a cert they issued.
+	SecretCAOutlivedByIssuedCert = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "secret_ca_outlived_by_issued_cert",
+			Help:      "1 if a cert issued by the CA in the secret expires after the CA, 0 otherwise.",
+		},
+		[]string{"cn", "secret_name", "secret_namespace"},
+	)
+
+	// ParseError is a prometheus gauge that flags data keys of secrets that could not be parsed in the last cycle.
+	ParseError = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "parse_error",
+			Help:      "1 if the data key of the secret matched the include and exclude globs but could not be parsed in the last cycle.",
+		},
+		[]string{"secret", "namespace", "key", "reason"},
+	)
+
+	// ConfigMapParseError is a prometheus gauge that flags data keys of configmaps that could not be parsed in the last cycle.
+	ConfigMapParseError = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "configmap_parse_error",
+			Help:      "1 if the data key of the configmap matched the include and exclude globs but could not be parsed in the last cycle.",
+		},
+		[]string{"configmap", "namespace", "key", "reason"},
+	)
+
+	// AwsCertExpirySeconds is a prometheus gauge that indicates the number of seconds until certificates on AWS expires.
+	AwsCertExpirySeconds *prometheus.GaugeVec
+
+	// ConfigMapExpirySeconds is a prometheus gauge that indicates the number of seconds until a kubernetes configmap certificate expires
+	ConfigMapExpirySeconds *prometheus.GaugeVec
+
+	// ConfigMapNotAfterTimestamp is a prometheus gauge that indicates the NotAfter timestamp.
+	ConfigMapNotAfterTimestamp *prometheus.GaugeVec
+
+	// ConfigMapNotBeforeTimestamp is a prometheus gauge that indicates the NotBefore timestamp.
+	ConfigMapNotBeforeTimestamp *prometheus.GaugeVec
+
+	// ConfigMapConfigExpirySeconds is a prometheus gauge that indicates the number of seconds until a cert referenced from an application config stored in a configmap expires
+	ConfigMapConfigExpirySeconds *prometheus.GaugeVec
+
+	// ConfigMapConfigNotAfterTimestamp is a prometheus gauge that indicates the NotAfter timestamp.
+	ConfigMapConfigNotAfterTimestamp *prometheus.GaugeVec
+
+	// ConfigMapConfigNotBeforeTimestamp is a prometheus gauge that indicates the NotBefore timestamp.
+	ConfigMapConfigNotBeforeTimestamp *prometheus.GaugeVec
+
+	// ConfigMapSoonestNotAfterTimestamp is a prometheus gauge that indicates the NotAfter timestamp of the cert expiring
+	// first in a kubernetes configmap.
+	ConfigMapSoonestNotAfterTimestamp *prometheus.GaugeVec
+
+	// WebhookExpirySeconds is a prometheus gauge that indicates the number of seconds until a kubernetes webhook certificate expires
+	WebhookExpirySeconds *prometheus.GaugeVec
+
+	// WebhookNotAfterTimestamp is a prometheus gauge that indicates the NotAfter timestamp.
+	WebhookNotAfterTimestamp *prometheus.GaugeVec
+
+	// WebhookNotBeforeTimestamp is a prometheus gauge that indicates the NotBefore timestamp.
+	WebhookNotBeforeTimestamp *prometheus.GaugeVec
+
+	// RouteExpirySeconds is a prometheus gauge that indicates the number of seconds until a cert of an OpenShift route expires
+	RouteExpirySeconds *prometheus.GaugeVec
+
+	// RouteNotAfterTimestamp is a prometheus gauge that indicates the NotAfter timestamp.
+	RouteNotAfterTimestamp *prometheus.GaugeVec
+
+	// RouteNotBeforeTimestamp is a prometheus gauge that indicates the NotBefore timestamp.
+	RouteNotBeforeTimestamp *prometheus.GaugeVec
+
+	// GatewayExpirySeconds is a prometheus gauge that indicates the number of seconds until a cert of a Gateway API gateway listener expires
+	GatewayExpirySeconds *prometheus.GaugeVec
+
+	// GatewayNotAfterTimestamp is a prometheus gauge that indicates the NotAfter timestamp.
+	GatewayNotAfterTimestamp *prometheus.GaugeVec
+
+	// GatewayNotBeforeTimestamp is a prometheus gauge that indicates the NotBefore timestamp.
+	GatewayNotBeforeTimestamp *prometheus.GaugeVec
+
+	// CSRExpirySeconds is a prometheus gauge that indicates the number of seconds until the cert issued for a CertificateSigningRequest expires
+	CSRExpirySeconds *prometheus.GaugeVec
+
+	// CSRNotAfterTimestamp is a prometheus gauge that indicates the NotAfter timestamp.
+	CSRNotAfterTimestamp *prometheus.GaugeVec
+
+	// CSRNotBeforeTimestamp is a prometheus gauge that indicates the NotBefore timestamp.
+	CSRNotBeforeTimestamp *prometheus.GaugeVec
+
+	// CSRStuck is a prometheus gauge that indicates if a CertificateSigningRequest has been pending or denied for too long.
+	CSRStuck = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "csr_stuck",
+			Help:      "1 if the CertificateSigningRequest has been in the Pending or Denied state for longer than allowed, 0 otherwise.",
+		},
+		[]string{"csr_name", "signer_name", "state"},
+	)
+
+	// CertKeyInfo is a prometheus gauge that describes the public key algorithm and size of every exported certificate.
+	CertKeyInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cert_key_info",
+			Help:      "Public key algorithm and size of the cert. Always 1.",
+		},
+		certLabels("key_algorithm", "key_size"),
+	)
+
+	// CertKeyTooSmall is a prometheus gauge that flags certificates with a key below the configured minimum size.
+	CertKeyTooSmall = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cert_key_too_small",
+			Help:      "1 if the public key of the cert is smaller than the configured minimum size, 0 otherwise.",
+		},
+		certLabels("key_algorithm"),
+	)
+
+	// CertWeakSignature is a prometheus gauge that flags certificates signed with a deprecated signature algorithm.
+	CertWeakSignature = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cert_weak_signature",
+			Help:      "1 if the cert is signed with a deprecated (MD2, MD5 or SHA-1) signature algorithm, 0 otherwise.",
+		},
+		certLabels("signature_algorithm"),
+	)
+
+	// CertExpired is a prometheus gauge that flags every exported certificate whose notAfter has passed.
+	CertExpired = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cert_expired",
+			Help:      "1 if the notAfter of the cert has passed, 0 otherwise.",
+		},
+		certLabels(),
+	)
+
+	// CertWarning is a prometheus gauge that flags every exported certificate expiring within the warning threshold.
+	CertWarning = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cert_warning",
+			Help:      "1 if the cert expires within --warning-days or has expired, 0 otherwise.",
+		},
+		certLabels(),
+	)
+
+	// CertCritical is a prometheus gauge that flags every exported certificate expiring within the critical threshold.
+	CertCritical = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cert_critical",
+			Help:      "1 if the cert expires within --critical-days or has expired, 0 otherwise.",
+		},
+		certLabels(),
+	)
+
+	// CertAgeSeconds is a prometheus gauge that indicates the number of seconds since every exported certificate became
+	// valid.
+	CertAgeSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cert_age_seconds",
+			Help:      "Number of seconds since the notBefore of the cert.",
+		},
+		certLabels(),
+	)
+
+	// CertValiditySeconds is a prometheus gauge that indicates the length of the validity of every exported certificate.
+	CertValiditySeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cert_validity_seconds",
+			Help:      "Number of seconds from the notBefore to the notAfter of the cert.",
+		},
+		certLabels(),
+	)
+
+	// CertLifetimeUsedRatio is a prometheus gauge that indicates the share of the validity of every exported certificate
+	// that has elapsed.
+	CertLifetimeUsedRatio = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cert_lifetime_used_ratio",
+			Help:      "Time elapsed since the notBefore of the cert, as a share of its validity.",
+		},
+		certLabels(),
+	)
+
+	// CertVerified is a prometheus gauge that indicates if the chain of every exported leaf certificate could be verified.
+	CertVerified = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cert_verified",
+			Help:      "1 if a chain from the leaf cert to a trusted root could be verified, 0 otherwise. reason tells why it could not.",
+		},
+		certLabels("reason"),
+	)
+
+	// CertChainIncomplete is a prometheus gauge that indicates if the certs stored next to every exported leaf certificate
+	// miss an intermediate of its chain.
+	CertChainIncomplete = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cert_chain_incomplete",
+			Help:      "1 if the certs stored with the leaf cert do not complete its chain up to a trusted root, 0 otherwise. missing_issuer is the issuer the chain stops at.",
+		},
+		certLabels("missing_issuer"),
+	)
+
+	// CertLastRotationTimestamp is a prometheus gauge that indicates when the serial of every exported certificate last
+	// changed.
+	CertLastRotationTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cert_last_rotation_timestamp",
+			Help:      "Timestamp the current serial of the cert was first seen at, or its notBefore if no rotation was seen yet.",
+		},
+		certLabels(),
+	)
+
+	// CertRotations is a prometheus gauge that indicates how many times the serial of every exported certificate changed.
+	CertRotations = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cert_rotations",
+			Help:      "Number of times the serial of the cert changed since the rotation history was started.",
+		},
+		certLabels(),
+	)
+
+	// CertCTLogged is a prometheus gauge that indicates if every exported leaf certificate embeds signed certificate
+	// timestamps.
+	CertCTLogged = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cert_ct_logged",
+			Help:      "1 if the leaf cert embeds signed certificate timestamps of Certificate Transparency logs, 0 otherwise.",
+		},
+		certLabels(),
+	)
+
+	// CertSecretDrift is a prometheus gauge that indicates if a mounted cert file differs from the secret it is mounted
+	// from.
+	CertSecretDrift = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cert_secret_drift",
+			Help:      "1 if the certs of the file differ from the ones currently stored in the secret it is mounted from, 0 otherwise.",
+		},
+		[]string{"filename", "nodename", "secret_namespace", "secret_name", "secret_key"},
+	)
+
+	// KubeletCertExpirySeconds is a prometheus gauge that indicates the number of seconds until the current client or
+	// serving cert of kubelet expires.
+	KubeletCertExpirySeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "kubelet_cert_expires_in_seconds",
+			Help:      "Number of seconds til the current kubelet cert expires.",
+		},
+		[]string{"type", "filename", "target", "nodename"},
+	)
+
+	// KubeletCertLifetimeUsedRatio is a prometheus gauge that indicates the share of the lifetime of the current kubelet
+	// cert that has elapsed.
+	KubeletCertLifetimeUsedRatio = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "kubelet_cert_lifetime_used_ratio",
+			Help:      "Time elapsed since the current kubelet cert was issued, as a share of its validity.",
+		},
+		[]string{"type", "filename", "target", "nodename"},
+	)
+
+	// KubeletCertRotationStuck is a prometheus gauge that indicates if kubelet failed to rotate its cert in time.
+	KubeletCertRotationStuck = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "kubelet_cert_rotation_stuck",
+			Help:      "1 if more than 90% of the validity of the current kubelet cert elapsed, by which kubelet always rotates it, 0 otherwise.",
+		},
+		[]string{"type", "filename", "target", "nodename"},
+	)
+
+	// EnvoyCertExpirySeconds is a prometheus gauge that indicates the number of seconds until a cert Envoy holds in memory
+	// expires.
+	EnvoyCertExpirySeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "envoy_cert_expires_in_seconds",
+			Help:      "Number of seconds til the cert loaded by Envoy expires.",
+		},
+		[]string{"target", "pod_name", "pod_namespace", "type", "path", "serial", "sans"},
+	)
+
+	// EnvoyCertNotAfterTimestamp is a prometheus gauge that indicates the NotAfter timestamp.
+	EnvoyCertNotAfterTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "envoy_cert_not_after_timestamp",
+			Help:      "Expiration timestamp for cert loaded by Envoy.",
+		},
+		[]string{"target", "pod_name", "pod_namespace", "type", "path", "serial", "sans"},
+	)
+
+	// EnvoyCertNotBeforeTimestamp is a prometheus gauge that indicates the NotBefore timestamp.
+	EnvoyCertNotBeforeTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "envoy_cert_not_before_timestamp",
+			Help:      "Timestamp from which the cert loaded by Envoy is valid.",
+		},
+		[]string{"target", "pod_name", "pod_namespace", "type", "path", "serial", "sans"},
+	)
+
+	// WindowsCertExpirySeconds is a prometheus gauge that indicates the number of seconds until a cert of a Windows
+	// certificate store expires.
+	WindowsCertExpirySeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "windows_cert_expires_in_seconds",
+			Help:      "Number of seconds til the cert in the Windows certificate store expires.",
+		},
+		[]string{"store", "thumbprint", "issuer", "cn", "nodename"},
+	)
+
+	// WindowsCertNotAfterTimestamp is a prometheus gauge that indicates the NotAfter timestamp.
+	WindowsCertNotAfterTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "windows_cert_not_after_timestamp",
+			Help:      "Expiration timestamp for cert in the Windows certificate store.",
+		},
+		[]string{"store", "thumbprint", "issuer", "cn", "nodename"},
+	)
+
+	// BootstrapTokenExpirySeconds is a prometheus gauge that indicates the number of seconds until a bootstrap token
+	// expires.
+	BootstrapTokenExpirySeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "bootstrap_token_expires_in_seconds",
+			Help:      "Number of seconds til the bootstrap token expires.",
+		},
+		[]string{"token_id", "secret_name"},
+	)
+
+	// BootstrapTokenExpirationTimestamp is a prometheus gauge that indicates the expiration timestamp of a bootstrap
+	// token.
+	BootstrapTokenExpirationTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "bootstrap_token_expiration_timestamp",
+			Help:      "Expiration timestamp of the bootstrap token.",
+		},
+		[]string{"token_id", "secret_name"},
+	)
+
+	// CertInfo is a prometheus gauge that carries the subject, issuer, SANs and serial of every exported certificate.
+	CertInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cert_info",
+			Help:      "Subject, issuer, SANs and serial number of the cert. Always 1.",
+		},
+		certLabels("subject", "issuer_dn", "sans", "serial"),
+	)
+
+	// SeriesDroppedTotal is a prometheus counter that indicates the number of series not exported because their namespace or object hit its quota.
+	SeriesDroppedTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "series_dropped_total",
+			Help:      "Number of series not exported because their namespace exceeded --max-series-per-namespace or their object --max-series-per-object.",
+		},
+		[]string{"source", "namespace"},
+	)
+
+	// CertsOverLimitTotal is a prometheus counter that indicates the number of certs not exported because their data key holds more than --max-certs-per-key.
+	CertsOverLimitTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "certs_over_limit_total",
+			Help:      "Number of certs not exported because their data key holds more than --max-certs-per-key certs. Only the soonest expiring certs of a key are exported.",
+		},
+		[]string{"source", "namespace"},
+	)
+
+	// TimeOffsetSeconds is a prometheus gauge that indicates how far the clock used for expiry metrics is shifted from the real time.
+	TimeOffsetSeconds = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "time_offset_seconds",
+			Help:      "Seconds the clock used to compute expiry metrics is shifted by --pretend-now. 0 outside of fire drills.",
+		},
+	)
+
+	// ReadOnlyMode is a prometheus gauge that indicates if the exporter runs in read-only mode.
+	ReadOnlyMode = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "read_only_mode",
+			Help:      "1 if the exporter runs in read-only mode and refuses every write to the cluster, 0 otherwise.",
+		},
+	)
+
+	// CapabilityInfo is a prometheus gauge that lists the API verbs every running feature uses.
+	CapabilityInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "capability_info",
+			Help:      "API verbs used by the running features, per resource. Always 1.",
+		},
+		[]string{"feature", "resource", "verb"},
+	)
+
+	// EgressModeInfo is a prometheus gauge that reports whether the exporter is allowed to make outbound calls.
+	EgressModeInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "egress_mode_info",
+			Help:      "Egress mode the exporter is running in (default or no-egress).",
+		},
+		[]string{"mode"},
+	)
+
+	// EgressFeatureEnabled is a prometheus gauge that indicates if a configured outbound feature was started.
+	EgressFeatureEnabled = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "egress_feature_enabled",
+			Help:      "1 if the configured outbound feature is running, 0 if it was disabled by no-egress mode.",
+		},
+		[]string{"feature"},
+	)
+
+	// BuildInfo is a prometheus gauge that is always 1 and labeled with the version of the running exporter.
+	BuildInfo = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "build_info",
+			Help:      "Always 1. Labeled with the version, revision and Go version the exporter was built with.",
+		},
+		[]string{"version", "revision", "goversion"},
+	)
+
+	// LastScanTimestampSeconds is a prometheus gauge that holds when every checker last completed a scan successfully.
+	LastScanTimestampSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "last_scan_timestamp_seconds",
+			Help:      "Unix timestamp of the end of the last successful scan of the checker.",
+		},
+		[]string{"checker"},
+	)
+
+	// ScanDurationSeconds is a prometheus gauge that holds how long the last scan of every checker took.
+	ScanDurationSeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "scan_duration_seconds",
+			Help:      "Duration of the last scan of the checker.",
+		},
+		[]string{"checker"},
+	)
+
+	// ScansTotal is a prometheus counter of the scans every checker completed, by result.
+	ScansTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "scans_total",
+			Help:      "Scans completed by the checker. result is failure when the checker could not read everything it scans.",
+		},
+		[]string{"checker", "result"},
+	)
+
+	// ListRetriesTotal is a prometheus counter of the list requests every checker retried after a transient error.
+	ListRetriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "list_retries_total",
+			Help:      "List requests the checker retried within a scan after a transient error.",
+		},
+		[]string{"checker"},
+	)
+
+	// CertManagerRenewalsTotal is a prometheus counter of the cert-manager Certificates whose renewal was triggered.
+	CertManagerRenewalsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cert_manager_renewals_total",
+			Help:      "Renewals of cert-manager Certificates triggered because their secret was about to expire.",
+		},
+		[]string{"namespace"},
+	)
+
+	// RolloutRestartsTotal is a prometheus counter of the workloads restarted because a secret they mount rotated.
+	RolloutRestartsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rollout_restarts_total",
+			Help:      "Deployments and StatefulSets restarted because a secret they mount rotated, by kind.",
+		},
+		[]string{"namespace", "kind"},
+	)
+
+	// CandidatePasswordAttempts is a prometheus counter of the PKCS12 and JKS bundles opened with each candidate password, by result.
+	CandidatePasswordAttempts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "candidate_password_attempts_total",
+			Help:      "Attempts to open a PKCS12 or JKS bundle with the candidate password at the given position, by result.",
+		},
+		[]string{"candidate", "result"},
+	)
+
+	// ObjectsScanned is a prometheus gauge of the secrets or configmaps the last scan of a checker reviewed per namespace.
+	ObjectsScanned = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "objects_scanned",
+			Help:      "Secrets or configmaps matching the selectors in the last scan of the checker.",
+		},
+		[]string{"checker", "namespace"},
+	)
+
+	// DataKeysScanned is a prometheus gauge of the data keys the last scan of a checker exported per namespace.
+	DataKeysScanned = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "data_keys_scanned",
+			Help:      "Data keys matching the include and exclude globs in the last scan of the checker.",
+		},
+		[]string{"checker", "namespace"},
+	)
+
+	// CertsParsed is a prometheus gauge of the certs the last scan of a checker parsed per namespace.
+	CertsParsed = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
 			Namespace: namespace,
-			Name:      "error_total",
-			Help:      "Cert Exporter Errors",
+			Name:      "certs_parsed",
+			Help:      "Certs parsed in the last scan of the checker.",
 		},
+		[]string{"checker", "namespace"},
 	)
 
-	// CertExpirySeconds is a prometheus gauge that indicates the number of seconds until certificates on disk expires.
+	// NamespaceAccessDenied is a prometheus gauge that flags the namespaces a checker is not allowed to list in.
+	NamespaceAccessDenied = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "namespace_access_denied",
+			Help:      "1 if the last scan of the checker skipped the namespace because the exporter is not allowed to list in it.",
+		},
+		[]string{"checker", "namespace"},
+	)
+
+	// DataKeysFailed is a prometheus gauge of the data keys the last scan of a checker could not parse, per reason.
+	DataKeysFailed = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "data_keys_failed",
+			Help:      "Data keys matching the include and exclude globs that could not be parsed in the last scan of the checker.",
+		},
+		[]string{"checker", "reason"},
+	)
+)
+
+// coveredNamespaces holds the namespaces the coverage of every checker was last published for
+var (
+	coverageMutex     sync.Mutex
+	coveredNamespaces = map[string]map[string]bool{}
+)
+
+// SetScanCoverage publishes what the last scan of a checker covered per namespace and deletes the namespaces it no longer
+// covers
+func SetScanCoverage(checker string, objects, dataKeys, certs map[string]int) {
+	coverageMutex.Lock()
+	defer coverageMutex.Unlock()
+
+	covered := map[string]bool{}
+	for _, counts := range []map[string]int{objects, dataKeys, certs} {
+		for ns := range counts {
+			covered[ns] = true
+		}
+	}
+
+	for ns := range covered {
+		ObjectsScanned.WithLabelValues(checker, ns).Set(float64(objects[ns]))
+		DataKeysScanned.WithLabelValues(checker, ns).Set(float64(dataKeys[ns]))
+		CertsParsed.WithLabelValues(checker, ns).Set(float64(certs[ns]))
+	}
+
+	for ns := range coveredNamespaces[checker] {
+		if !covered[ns] {
+			ObjectsScanned.DeleteLabelValues(checker, ns)
+			DataKeysScanned.DeleteLabelValues(checker, ns)
+			CertsParsed.DeleteLabelValues(checker, ns)
+		}
+	}
+	coveredNamespaces[checker] = covered
+}
+
+// Reasons of the errors counted by ErrorsTotal
+const (
+	// ReasonAPI is a failed request to the kubernetes API or an external service
+	ReasonAPI = "api"
+	// ReasonParse is data that could not be parsed as a cert, key or application config
+	ReasonParse = "parse"
+	// ReasonPassword is an encrypted cert or key that could not be decrypted with the password found for it
+	ReasonPassword = "password"
+	// ReasonGlob is an invalid include or exclude glob
+	ReasonGlob = "glob"
+	// ReasonTLS is a serving cert that could not be reloaded
+	ReasonTLS = "tls"
+	// ReasonAuth is a request that could not be authenticated because of an error
+	ReasonAuth = "auth"
+	// ReasonOutput is a failed write of an output, e.g. remote write or a report file
+	ReasonOutput = "output"
+)
+
+// RecordError counts an error of a checker in both ErrorsTotal and ErrorTotal.  namespace is empty for errors not
+// related to a namespace.
+func RecordError(checker, namespace, reason string) {
+	ErrorTotal.Inc()
+	ErrorsTotal.WithLabelValues(checker, namespace, reason).Inc()
+}
+
+// ScanFinished records the outcome of a scan of a checker
+func ScanFinished(checker string, duration time.Duration, failed bool) {
+	ScanDurationSeconds.WithLabelValues(checker).Set(duration.Seconds())
+	if failed {
+		ScansTotal.WithLabelValues(checker, "failure").Inc()
+		return
+	}
+
+	ScansTotal.WithLabelValues(checker, "success").Inc()
+	LastScanTimestampSeconds.WithLabelValues(checker).SetToCurrentTime()
+}
+
+func Init(prometheusExporterMetricsDisabled bool) {
+	newExpiryMetrics()
+
+	if prometheusExporterMetricsDisabled {
+		emptyRegistry := prometheus.NewRegistry()
+		prometheus.DefaultRegisterer = emptyRegistry
+		prometheus.DefaultGatherer = emptyRegistry
+	}
+	inventoryGatherer = prometheus.DefaultGatherer
+	if len(constLabels) > 0 || metricPrefix != namespace || len(droppedLabels) > 0 || len(hashedLabels) > 0 {
+		prometheus.DefaultGatherer = exposedGatherer{gatherer: prometheus.DefaultGatherer, prefix: metricPrefix, labels: constLabels, dropped: droppedLabels, hashed: hashedLabels}
+	}
+
+	prometheus.MustRegister(ErrorTotal)
+	prometheus.MustRegister(ErrorsTotal)
+	registerClientGoMetrics()
+	prometheus.MustRegister(CertExpirySeconds)
+	prometheus.MustRegister(CertNotAfterTimestamp)
+	prometheus.MustRegister(CertNotBeforeTimestamp)
+	prometheus.MustRegister(KubeConfigExpirySeconds)
+	prometheus.MustRegister(KubeConfigNotAfterTimestamp)
+	prometheus.MustRegister(KubeConfigNotBeforeTimestamp)
+	prometheus.MustRegister(SecretExpirySeconds)
+	prometheus.MustRegister(SecretNotAfterTimestamp)
+	prometheus.MustRegister(SecretNotBeforeTimestamp)
+	prometheus.MustRegister(SecretKeyPairMismatch)
+	prometheus.MustRegister(SecretSANMismatch)
+	prometheus.MustRegister(SecretCALifetimeMarginSeconds)
+	prometheus.MustRegister(SecretCAOutlivedByIssuedCert)
+	prometheus.MustRegister(ParseError)
+	prometheus.MustRegister(ConfigMapParseError)
+	prometheus.MustRegister(SecretKubeConfigExpirySeconds)
+	prometheus.MustRegister(SecretKubeConfigNotAfterTimestamp)
+	prometheus.MustRegister(SecretKubeConfigNotBeforeTimestamp)
+	prometheus.MustRegister(SecretArchiveExpirySeconds)
+	prometheus.MustRegister(SecretArchiveNotAfterTimestamp)
+	prometheus.MustRegister(SecretArchiveNotBeforeTimestamp)
+	prometheus.MustRegister(SecretJWTExpirySeconds)
+	prometheus.MustRegister(SecretJWTExpiryTimestamp)
+	prometheus.MustRegister(SecretPGPKeyExpirySeconds)
+	prometheus.MustRegister(SecretPGPKeyExpiryTimestamp)
+	prometheus.MustRegister(SecretSoonestNotAfterTimestamp)
+	prometheus.MustRegister(TLSSecretExpirySeconds)
+	prometheus.MustRegister(TLSSecretNotAfterTimestamp)
+	prometheus.MustRegister(TLSSecretNotBeforeTimestamp)
+	prometheus.MustRegister(TLSSecretKeyPairMismatch)
+	prometheus.MustRegister(ConfigMapExpirySeconds)
+	prometheus.MustRegister(ConfigMapNotAfterTimestamp)
+	prometheus.MustRegister(ConfigMapNotBeforeTimestamp)
+	prometheus.MustRegister(ConfigMapConfigExpirySeconds)
+	prometheus.MustRegister(ConfigMapConfigNotAfterTimestamp)
+	prometheus.MustRegister(ConfigMapConfigNotBeforeTimestamp)
+	prometheus.MustRegister(ConfigMapSoonestNotAfterTimestamp)
+	prometheus.MustRegister(WebhookExpirySeconds)
+	prometheus.MustRegister(WebhookNotAfterTimestamp)
+	prometheus.MustRegister(WebhookNotBeforeTimestamp)
+	prometheus.MustRegister(RouteExpirySeconds)
+	prometheus.MustRegister(RouteNotAfterTimestamp)
+	prometheus.MustRegister(RouteNotBeforeTimestamp)
+	prometheus.MustRegister(GatewayExpirySeconds)
+	prometheus.MustRegister(GatewayNotAfterTimestamp)
+	prometheus.MustRegister(GatewayNotBeforeTimestamp)
+	prometheus.MustRegister(CSRExpirySeconds)
+	prometheus.MustRegister(CSRNotAfterTimestamp)
+	prometheus.MustRegister(CSRNotBeforeTimestamp)
+	prometheus.MustRegister(CSRStuck)
+	prometheus.MustRegister(AwsCertExpirySeconds)
+	prometheus.MustRegister(CertKeyInfo)
+	prometheus.MustRegister(CertKeyTooSmall)
+	prometheus.MustRegister(CertWeakSignature)
+	prometheus.MustRegister(CertExpired)
+	prometheus.MustRegister(CertWarning)
+	prometheus.MustRegister(CertCritical)
+	prometheus.MustRegister(CertAgeSeconds)
+	prometheus.MustRegister(CertValiditySeconds)
+	prometheus.MustRegister(CertLifetimeUsedRatio)
+	prometheus.MustRegister(CertVerified)
+	prometheus.MustRegister(CertChainIncomplete)
+	prometheus.MustRegister(CertLastRotationTimestamp)
+	prometheus.MustRegister(CertRotations)
+	prometheus.MustRegister(CertCTLogged)
+	prometheus.MustRegister(CertSecretDrift)
+	prometheus.MustRegister(KubeletCertExpirySeconds)
+	prometheus.MustRegister(KubeletCertLifetimeUsedRatio)
+	prometheus.MustRegister(KubeletCertRotationStuck)
+	prometheus.MustRegister(EnvoyCertExpirySeconds)
+	prometheus.MustRegister(EnvoyCertNotAfterTimestamp)
+	prometheus.MustRegister(EnvoyCertNotBeforeTimestamp)
+	prometheus.MustRegister(WindowsCertExpirySeconds)
+	prometheus.MustRegister(WindowsCertNotAfterTimestamp)
+	prometheus.MustRegister(BootstrapTokenExpirySeconds)
+	prometheus.MustRegister(BootstrapTokenExpirationTimestamp)
+	prometheus.MustRegister(CertInfo)
+	prometheus.MustRegister(SeriesDroppedTotal)
+	prometheus.MustRegister(CertsOverLimitTotal)
+	prometheus.MustRegister(TimeOffsetSeconds)
+	prometheus.MustRegister(ReadOnlyMode)
+	prometheus.MustRegister(CapabilityInfo)
+	prometheus.MustRegister(EgressModeInfo)
+	prometheus.MustRegister(EgressFeatureEnabled)
+	prometheus.MustRegister(BuildInfo)
+	prometheus.MustRegister(LastScanTimestampSeconds)
+	prometheus.MustRegister(ScanDurationSeconds)
+	prometheus.MustRegister(ScansTotal)
+	prometheus.MustRegister(ListRetriesTotal)
+	prometheus.MustRegister(CandidatePasswordAttempts)
+	prometheus.MustRegister(CertManagerRenewalsTotal)
+	prometheus.MustRegister(RolloutRestartsTotal)
+	prometheus.MustRegister(ObjectsScanned)
+	prometheus.MustRegister(DataKeysScanned)
+	prometheus.MustRegister(CertsParsed)
+	prometheus.MustRegister(DataKeysFailed)
+	prometheus.MustRegister(NamespaceAccessDenied)
+}
+
+// DisableGoCollector drops the go_* metrics about the Go runtime registered by default.  It must be called after Init.
+func DisableGoCollector() {
+	prometheus.Unregister(prometheus.NewGoCollector())
+}
+
+// DisableProcessCollector drops the process_* metrics registered by default.  It must be called after Init.
+func DisableProcessCollector() {
+	prometheus.Unregister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+}
+
+// newExpiryMetrics builds the expiry metrics of every checker, now that the optional cert labels are known
+func newExpiryMetrics() {
 	CertExpirySeconds = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: namespace,
 			Name:      "cert_expires_in_seconds",
 			Help:      "Number of seconds til the cert expires.",
 		},
-		[]string{"filename", "issuer", "cn", "nodename"},
+		expiryLabels(fileLabels("filename", "issuer", "cn", "nodename")...),
 	)
 
-	// CertNotAfterTimestamp is a prometheus gauge that indicates the NotAfter timestamp.
 	CertNotAfterTimestamp = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: namespace,
 			Name:      "cert_not_after_timestamp",
 			Help:      "Timestamp of when the certificate expires.",
 		},
-		[]string{"filename", "issuer", "cn", "nodename"},
+		expiryLabels(fileLabels("filename", "issuer", "cn", "nodename")...),
+	)
+
+	CertNotBeforeTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "cert_not_before_timestamp",
+			Help:      "Timestamp of when the certificate becomes valid.",
+		},
+		expiryLabels(fileLabels("filename", "issuer", "cn", "nodename")...),
 	)
 
-	// KubeConfigExpirySeconds is a prometheus gauge that indicates the number of seconds until a kubeconfig certificate expires.
 	KubeConfigExpirySeconds = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: namespace,
 			Name:      "kubeconfig_expires_in_seconds",
 			Help:      "Number of seconds til the cert in the kubeconfig expires.",
 		},
-		[]string{"filename", "type", "cn", "issuer", "name", "nodename"},
+		expiryLabels(fileLabels("filename", "type", "cn", "issuer", "name", "nodename")...),
 	)
 
-	// KubeConfigNotAfterTimestamp is a prometheus gauge that indicates the NotAfter timestamp.
 	KubeConfigNotAfterTimestamp = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: namespace,
 			Name:      "kubeconfig_not_after_timestamp",
 			Help:      "Expiration timestamp for cert in the kubeconfig.",
 		},
-		[]string{"filename", "type", "cn", "issuer", "name", "nodename"},
+		expiryLabels(fileLabels("filename", "type", "cn", "issuer", "name", "nodename")...),
+	)
+
+	KubeConfigNotBeforeTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "kubeconfig_not_before_timestamp",
+			Help:      "Timestamp from which the cert in the kubeconfig is valid.",
+		},
+		expiryLabels(fileLabels("filename", "type", "cn", "issuer", "name", "nodename")...),
 	)
 
-	// SecretExpirySeconds is a prometheus gauge that indicates the number of seconds until a kubernetes secret certificate expires
 	SecretExpirySeconds = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: namespace,
 			Name:      "secret_expires_in_seconds",
 			Help:      "Number of seconds til the cert in the secret expires.",
 		},
-		[]string{"key_name", "issuer", "cn", "secret_name", "secret_namespace", "serviceline"},
+		expiryLabels(objectLabels("key_name", "issuer", "cn", "secret_name", "secret_namespace")...),
 	)
 
-	// SecretNotAfterTimestamp is a prometheus gauge that indicates the NotAfter timestamp.
 	SecretNotAfterTimestamp = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: namespace,
 			Name:      "secret_not_after_timestamp",
 			Help:      "Expiration timestamp for cert in the secret.",
 		},
-		[]string{"key_name", "issuer", "cn", "secret_name", "secret_namespace", "serviceline"},
+		expiryLabels(objectLabels("key_name", "issuer", "cn", "secret_name", "secret_namespace")...),
+	)
+
+	SecretNotBeforeTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "secret_not_before_timestamp",
+			Help:      "Timestamp from which the cert in the secret is valid.",
+		},
+		expiryLabels(objectLabels("key_name", "issuer", "cn", "secret_name", "secret_namespace")...),
+	)
+
+	SecretKubeConfigExpirySeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "secret_kubeconfig_expires_in_seconds",
+			Help:      "Number of seconds til the cert in the kubeconfig stored in the secret expires.",
+		},
+		expiryLabels(objectLabels("key_name", "type", "name", "issuer", "cn", "secret_name", "secret_namespace")...),
+	)
+
+	SecretKubeConfigNotAfterTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "secret_kubeconfig_not_after_timestamp",
+			Help:      "Expiration timestamp for cert in the kubeconfig stored in the secret.",
+		},
+		expiryLabels(objectLabels("key_name", "type", "name", "issuer", "cn", "secret_name", "secret_namespace")...),
+	)
+
+	SecretKubeConfigNotBeforeTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "secret_kubeconfig_not_before_timestamp",
+			Help:      "Timestamp from which the cert in the kubeconfig stored in the secret is valid.",
+		},
+		expiryLabels(objectLabels("key_name", "type", "name", "issuer", "cn", "secret_name", "secret_namespace")...),
+	)
+
+	SecretArchiveExpirySeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "secret_archive_expires_in_seconds",
+			Help:      "Number of seconds til the cert in the archive stored in the secret expires.",
+		},
+		expiryLabels(objectLabels("key_name", "member", "issuer", "cn", "secret_name", "secret_namespace")...),
+	)
+
+	SecretArchiveNotAfterTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "secret_archive_not_after_timestamp",
+			Help:      "Expiration timestamp for cert in the archive stored in the secret.",
+		},
+		expiryLabels(objectLabels("key_name", "member", "issuer", "cn", "secret_name", "secret_namespace")...),
+	)
+
+	SecretArchiveNotBeforeTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "secret_archive_not_before_timestamp",
+			Help:      "Timestamp from which the cert in the archive stored in the secret is valid.",
+		},
+		expiryLabels(objectLabels("key_name", "member", "issuer", "cn", "secret_name", "secret_namespace")...),
+	)
+
+	SecretJWTExpirySeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "secret_jwt_expires_in_seconds",
+			Help:      "Number of seconds til the JWT in the secret expires.",
+		},
+		objectLabels("key_name", "issuer", "subject", "secret_name", "secret_namespace"),
+	)
+
+	SecretJWTExpiryTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "secret_jwt_exp_timestamp",
+			Help:      "Expiration timestamp (exp claim) of the JWT in the secret.",
+		},
+		objectLabels("key_name", "issuer", "subject", "secret_name", "secret_namespace"),
+	)
+
+	SecretPGPKeyExpirySeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "secret_pgp_key_expires_in_seconds",
+			Help:      "Number of seconds til the OpenPGP key in the secret expires.",
+		},
+		objectLabels("key_name", "fingerprint", "type", "uid", "secret_name", "secret_namespace"),
+	)
+
+	SecretPGPKeyExpiryTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "secret_pgp_key_expiration_timestamp",
+			Help:      "Expiration timestamp of the OpenPGP key in the secret.",
+		},
+		objectLabels("key_name", "fingerprint", "type", "uid", "secret_name", "secret_namespace"),
+	)
+
+	SecretSoonestNotAfterTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "secret_soonest_not_after_timestamp",
+			Help:      "Expiration timestamp of the cert in the secret that expires first.",
+		},
+		objectLabels("secret_name", "secret_namespace"),
+	)
+
+	TLSSecretExpirySeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "tls_secret_expires_in_seconds",
+			Help:      "Number of seconds til the cert in the kubernetes.io/tls secret expires.",
+		},
+		expiryLabels(objectLabels("key_name", "role", "issuer", "cn", "secret_name", "secret_namespace")...),
+	)
+
+	TLSSecretNotAfterTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "tls_secret_not_after_timestamp",
+			Help:      "Expiration timestamp for cert in the kubernetes.io/tls secret.",
+		},
+		expiryLabels(objectLabels("key_name", "role", "issuer", "cn", "secret_name", "secret_namespace")...),
+	)
+
+	TLSSecretNotBeforeTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "tls_secret_not_before_timestamp",
+			Help:      "Timestamp from which the cert in the kubernetes.io/tls secret is valid.",
+		},
+		expiryLabels(objectLabels("key_name", "role", "issuer", "cn", "secret_name", "secret_namespace")...),
 	)
 
-	// AwsCertExpirySeconds is a prometheus gauge that indicates the number of seconds until certificates on AWS expires.
 	AwsCertExpirySeconds = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: namespace,
 			Name:      "cert_expires_in_seconds_aws",
 			Help:      "Number of seconds til the cert expires.",
 		},
-		[]string{"secretName", "key", "file", "issuer", "cn"},
+		expiryLabels("secretName", "key", "file", "issuer", "cn"),
 	)
 
-	// ConfigMapExpirySeconds is a prometheus gauge that indicates the number of seconds until a kubernetes configmap certificate expires
 	ConfigMapExpirySeconds = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: namespace,
 			Name:      "configmap_expires_in_seconds",
 			Help:      "Number of seconds til the cert in the configmap expires.",
 		},
-		[]string{"key_name", "issuer", "cn", "configmap_name", "configmap_namespace", "serviceline"},
+		expiryLabels(objectLabels("key_name", "issuer", "cn", "configmap_name", "configmap_namespace")...),
 	)
 
-	// ConfigMapNotAfterTimestamp is a prometheus gauge that indicates the NotAfter timestamp.
 	ConfigMapNotAfterTimestamp = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: namespace,
 			Name:      "configmap_not_after_timestamp",
 			Help:      "Expiration timestamp for cert in the configmap.",
 		},
-		[]string{"key_name", "issuer", "cn", "configmap_name", "configmap_namespace", "serviceline"},
+		expiryLabels(objectLabels("key_name", "issuer", "cn", "configmap_name", "configmap_namespace")...),
+	)
+
+	ConfigMapNotBeforeTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "configmap_not_before_timestamp",
+			Help:      "Timestamp from which the cert in the configmap is valid.",
+		},
+		expiryLabels(objectLabels("key_name", "issuer", "cn", "configmap_name", "configmap_namespace")...),
+	)
+
+	ConfigMapConfigExpirySeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "configmap_config_expires_in_seconds",
+			Help:      "Number of seconds til the cert referenced from the config in the configmap expires.",
+		},
+		expiryLabels(objectLabels("key_name", "config_source", "config_field", "config_path", "issuer", "cn", "configmap_name", "configmap_namespace")...),
+	)
+
+	ConfigMapConfigNotAfterTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "configmap_config_not_after_timestamp",
+			Help:      "Expiration timestamp for cert referenced from the config in the configmap.",
+		},
+		expiryLabels(objectLabels("key_name", "config_source", "config_field", "config_path", "issuer", "cn", "configmap_name", "configmap_namespace")...),
+	)
+
+	ConfigMapConfigNotBeforeTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "configmap_config_not_before_timestamp",
+			Help:      "Timestamp from which the cert referenced from the config in the configmap is valid.",
+		},
+		expiryLabels(objectLabels("key_name", "config_source", "config_field", "config_path", "issuer", "cn", "configmap_name", "configmap_namespace")...),
+	)
+
+	ConfigMapSoonestNotAfterTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "configmap_soonest_not_after_timestamp",
+			Help:      "Expiration timestamp of the cert in the configmap that expires first.",
+		},
+		objectLabels("configmap_name", "configmap_namespace"),
 	)
 
-	// WebhookExpirySeconds is a prometheus gauge that indicates the number of seconds until a kubernetes webhook certificate expires
 	WebhookExpirySeconds = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: namespace,
 			Name:      "webhook_expires_in_seconds",
 			Help:      "Number of seconds til the cert in the webhook expires.",
 		},
-		[]string{"type_name", "issuer", "cn", "webhook_name", "admission_review_version_name"},
+		expiryLabels("type_name", "issuer", "cn", "webhook_name", "admission_review_version_name"),
 	)
 
-	// WebhookNotAfterTimestamp is a prometheus gauge that indicates the NotAfter timestamp.
 	WebhookNotAfterTimestamp = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: namespace,
 			Name:      "webhook_not_after_timestamp",
 			Help:      "Expiration timestamp for cert in the webhook.",
 		},
-		[]string{"type_name", "issuer", "cn", "webhook_name", "admission_review_version_name"},
+		expiryLabels("type_name", "issuer", "cn", "webhook_name", "admission_review_version_name"),
 	)
-)
 
-func Init(prometheusExporterMetricsDisabled bool) {
-	if prometheusExporterMetricsDisabled {
-		emptyRegistry := prometheus.NewRegistry()
-		prometheus.DefaultRegisterer = emptyRegistry
-		prometheus.DefaultGatherer = emptyRegistry
-	}
+	WebhookNotBeforeTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "webhook_not_before_timestamp",
+			Help:      "Timestamp from which the cert in the webhook is valid.",
+		},
+		expiryLabels("type_name", "issuer", "cn", "webhook_name", "admission_review_version_name"),
+	)
 
-	prometheus.MustRegister(ErrorTotal)
-	prometheus.MustRegister(CertExpirySeconds)
-	prometheus.MustRegister(CertNotAfterTimestamp)
-	prometheus.MustRegister(KubeConfigExpirySeconds)
-	prometheus.MustRegister(KubeConfigNotAfterTimestamp)
-	prometheus.MustRegister(SecretExpirySeconds)
-	prometheus.MustRegister(SecretNotAfterTimestamp)
-	prometheus.MustRegister(ConfigMapExpirySeconds)
-	prometheus.MustRegister(ConfigMapNotAfterTimestamp)
-	prometheus.MustRegister(WebhookExpirySeconds)
-	prometheus.MustRegister(WebhookNotAfterTimestamp)
-	prometheus.MustRegister(AwsCertExpirySeconds)
+	RouteExpirySeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "route_expires_in_seconds",
+			Help:      "Number of seconds til the cert in the route expires.",
+		},
+		expiryLabels("field", "issuer", "cn", "route_name", "route_namespace", "host"),
+	)
+
+	RouteNotAfterTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "route_not_after_timestamp",
+			Help:      "Expiration timestamp for cert in the route.",
+		},
+		expiryLabels("field", "issuer", "cn", "route_name", "route_namespace", "host"),
+	)
+
+	RouteNotBeforeTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "route_not_before_timestamp",
+			Help:      "Timestamp from which the cert in the route is valid.",
+		},
+		expiryLabels("field", "issuer", "cn", "route_name", "route_namespace", "host"),
+	)
+
+	GatewayExpirySeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "gateway_expires_in_seconds",
+			Help:      "Number of seconds til the cert of the gateway listener expires.",
+		},
+		expiryLabels("issuer", "cn", "gateway_name", "gateway_namespace", "listener", "hostname", "secret_name", "secret_namespace"),
+	)
+
+	GatewayNotAfterTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "gateway_not_after_timestamp",
+			Help:      "Expiration timestamp for cert of the gateway listener.",
+		},
+		expiryLabels("issuer", "cn", "gateway_name", "gateway_namespace", "listener", "hostname", "secret_name", "secret_namespace"),
+	)
+
+	GatewayNotBeforeTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "gateway_not_before_timestamp",
+			Help:      "Timestamp from which the cert of the gateway listener is valid.",
+		},
+		expiryLabels("issuer", "cn", "gateway_name", "gateway_namespace", "listener", "hostname", "secret_name", "secret_namespace"),
+	)
+
+	CSRExpirySeconds = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "csr_expires_in_seconds",
+			Help:      "Number of seconds til the cert issued for the CertificateSigningRequest expires.",
+		},
+		expiryLabels("issuer", "cn", "csr_name", "signer_name"),
+	)
+
+	CSRNotAfterTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "csr_not_after_timestamp",
+			Help:      "Expiration timestamp for cert issued for the CertificateSigningRequest.",
+		},
+		expiryLabels("issuer", "cn", "csr_name", "signer_name"),
+	)
+
+	CSRNotBeforeTimestamp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "csr_not_before_timestamp",
+			Help:      "Timestamp from which the cert issued for the CertificateSigningRequest is valid.",
+		},
+		expiryLabels("issuer", "cn", "csr_name", "signer_name"),
+	)
 }
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// failureReasons are the reasons a data key may fail to parse for, published even when no key failed for them
+var failureReasons = []string{ReasonParse, ReasonPassword}
+
+// maxParseFailureRatio is the share of the data keys of a checker that may fail to parse before /readyz fails.  0
+// disables the check.
+var (
+	maxParseFailureRatio float64
+
+	parseFailureMutex  sync.Mutex
+	parseFailureRatios = map[string]float64{}
+)
+
+// SetMaxParseFailureRatio fails /readyz while the last scan of any checker could not parse more than ratio of the data
+// keys it scanned.  0 disables the check.
+func SetMaxParseFailureRatio(ratio float64) {
+	maxParseFailureRatio = ratio
+}
+
+// SetDataKeysFailed publishes the data keys the last scan of a checker could not parse per reason, out of the dataKeys
+// it scanned
+func SetDataKeysFailed(checker string, failed map[string]int, dataKeys int) {
+	total := 0
+	for _, reason := range failureReasons {
+		DataKeysFailed.WithLabelValues(checker, reason).Set(float64(failed[reason]))
+		total += failed[reason]
+	}
+
+	ratio := 0.0
+	if dataKeys > 0 {
+		ratio = float64(total) / float64(dataKeys)
+	}
+
+	parseFailureMutex.Lock()
+	defer parseFailureMutex.Unlock()
+	parseFailureRatios[checker] = ratio
+}
+
+// notReady returns why the exporter is not ready, or an empty string if it is
+func notReady() string {
+	if maxParseFailureRatio <= 0 {
+		return ""
+	}
+
+	parseFailureMutex.Lock()
+	defer parseFailureMutex.Unlock()
+
+	var reasons []string
+	for checker, ratio := range parseFailureRatios {
+		if ratio > maxParseFailureRatio {
+			reasons = append(reasons, fmt.Sprintf("%v could not parse %.0f%% of its data keys", checker, ratio*100))
+		}
+	}
+	sort.Strings(reasons)
+	return strings.Join(reasons, "\n")
+}
+
+// ReadyzHandler serves the readiness of the exporter.  It fails while the last scan of a checker could not parse more
+// of its data keys than allowed by SetMaxParseFailureRatio, e.g. because of a wrong password.
+func ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	if reason := notReady(); reason != "" {
+		http.Error(w, reason, http.StatusServiceUnavailable)
+		return
+	}
+
+	fmt.Fprintln(w, "ok")
+}
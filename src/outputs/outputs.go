@@ -0,0 +1,80 @@
+package outputs
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/golang/glog"
+
+	"github.com/joe-elliott/cert-exporter/src/checkers"
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+// Output is a sink the results of the scans are written to, e.g. a remote write endpoint or a report file.  Outputs
+// read the results from the default prometheus gatherer or metrics.Inventory, so checkers do not know about them.
+type Output interface {
+	// Write writes the current results.  It is called after every scan of every checker, once the metrics of the scan
+	// are up to date, and never concurrently.
+	Write() error
+}
+
+// Factory builds an output from its settings in the config file
+type Factory func(settings map[string]string) (Output, error)
+
+var (
+	factoriesMutex sync.Mutex
+	factories      = map[string]Factory{}
+)
+
+// Register makes an output available by name in the outputs of the config file.  Custom outputs are registered from
+// an init function, before the config is loaded.  Registering a name twice panics.
+func Register(name string, factory Factory) {
+	factoriesMutex.Lock()
+	defer factoriesMutex.Unlock()
+
+	if _, ok := factories[name]; ok {
+		panic(fmt.Sprintf("output %q registered twice", name))
+	}
+	factories[name] = factory
+}
+
+// Names returns the names of the registered outputs in alphabetical order
+func Names() []string {
+	factoriesMutex.Lock()
+	defer factoriesMutex.Unlock()
+
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// New builds the output registered as name from settings
+func New(name string, settings map[string]string) (Output, error) {
+	factoriesMutex.Lock()
+	factory, ok := factories[name]
+	factoriesMutex.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown output %q, known outputs are %v", name, Names())
+	}
+	return factory(settings)
+}
+
+// Attach writes o after every scan of every checker.  Failed writes are logged and counted as errors of name; the next
+// scan writes again.
+func Attach(name string, o Output) {
+	var mutex sync.Mutex
+	checkers.OnScanFinished(func() {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		if err := o.Write(); err != nil {
+			metrics.RecordError(name, "", metrics.ReasonOutput)
+			glog.Errorf("Error writing to output %v: %v", name, err)
+		}
+	})
+}
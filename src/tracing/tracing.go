@@ -0,0 +1,271 @@
+// Package tracing records the spans of scans and exports them to an OpenTelemetry collector with OTLP over HTTP, in its
+// JSON encoding, so no OpenTelemetry SDK is needed
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// queueSize bounds the spans waiting to be exported.  Spans are dropped once the collector falls that far behind.
+	queueSize = 4096
+	// batchSize is the most spans sent in a single request
+	batchSize = 512
+	// flushInterval is how long ended spans wait for a batch to fill up
+	flushInterval = 5 * time.Second
+	// requestTimeout bounds every request to the collector
+	requestTimeout = 10 * time.Second
+)
+
+// Span is a timed operation of a scan.  The methods of a nil Span do nothing, so callers need not check whether
+// tracing is enabled.
+type Span struct {
+	traceID    [16]byte
+	spanID     [8]byte
+	parentID   [8]byte
+	name       string
+	start      time.Time
+	end        time.Time
+	attributes []string
+	err        string
+}
+
+type spanKey struct{}
+
+// exporter sends ended spans to the collector.  nil disables tracing.
+var exporter *otlpExporter
+
+// Enable exports the spans of every scan to the OTLP/HTTP collector at endpoint, e.g. http://otel-collector:4318
+func Enable(endpoint, serviceName string) {
+	exporter = &otlpExporter{
+		url:         strings.TrimSuffix(endpoint, "/") + "/v1/traces",
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: requestTimeout},
+		spans:       make(chan *Span, queueSize),
+		flushed:     make(chan chan struct{}),
+	}
+	go exporter.run()
+}
+
+// Start starts a span named name, the child of the span of ctx if any, and returns a context carrying it.  attributes
+// are key value pairs.
+func Start(ctx context.Context, name string, attributes ...string) (context.Context, *Span) {
+	if exporter == nil {
+		return ctx, nil
+	}
+
+	span := &Span{name: name, start: time.Now(), attributes: attributes}
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok && parent != nil {
+		span.traceID = parent.traceID
+		span.parentID = parent.spanID
+	} else {
+		rand.Read(span.traceID[:])
+	}
+	rand.Read(span.spanID[:])
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SetAttribute adds an attribute to the span
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	s.attributes = append(s.attributes, key, value)
+}
+
+// RecordError marks the span as failed with err, if not nil
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.err = err.Error()
+}
+
+// End ends the span and queues it for export
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.end = time.Now()
+
+	select {
+	case exporter.spans <- s:
+	default:
+		glog.Warningf("Dropping span %v: the OTLP collector is not keeping up", s.name)
+	}
+}
+
+// Flush exports the spans that ended so far, e.g. before a --run-once scan exits
+func Flush() {
+	if exporter == nil {
+		return
+	}
+
+	done := make(chan struct{})
+	exporter.flushed <- done
+	<-done
+}
+
+type otlpExporter struct {
+	url         string
+	serviceName string
+	client      *http.Client
+
+	spans   chan *Span
+	flushed chan chan struct{}
+}
+
+// run batches the ended spans and sends them every flushInterval, or as soon as a batch is full
+func (e *otlpExporter) run() {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	var batch []*Span
+	for {
+		select {
+		case span := <-e.spans:
+			batch = append(batch, span)
+			if len(batch) >= batchSize {
+				e.send(batch)
+				batch = nil
+			}
+		case <-ticker.C:
+			e.send(batch)
+			batch = nil
+		case done := <-e.flushed:
+			for len(e.spans) > 0 {
+				batch = append(batch, <-e.spans)
+			}
+			e.send(batch)
+			batch = nil
+			close(done)
+		}
+	}
+}
+
+func (e *otlpExporter) send(batch []*Span) {
+	if len(batch) == 0 {
+		return
+	}
+
+	body, err := json.Marshal(e.request(batch))
+	if err != nil {
+		glog.Errorf("Error encoding %v spans: %v", len(batch), err)
+		return
+	}
+
+	resp, err := e.client.Post(e.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		glog.Warningf("Error exporting %v spans to %v: %v", len(batch), e.url, err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		glog.Warningf("Error exporting %v spans to %v: %v", len(batch), e.url, resp.Status)
+	}
+}
+
+// The types below are the parts of the JSON encoding of an OTLP ExportTraceServiceRequest the exporter fills
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            otlpStatus      `json:"status"`
+}
+
+type otlpAttribute struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+type otlpValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+const (
+	spanKindInternal = 1
+	statusCodeError  = 2
+)
+
+func (e *otlpExporter) request(batch []*Span) otlpRequest {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, s := range batch {
+		span := otlpSpan{
+			TraceID:           hex.EncodeToString(s.traceID[:]),
+			SpanID:            hex.EncodeToString(s.spanID[:]),
+			Name:              s.name,
+			Kind:              spanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.end.UnixNano(), 10),
+			Attributes:        attributes(s.attributes...),
+		}
+		if s.parentID != [8]byte{} {
+			span.ParentSpanID = hex.EncodeToString(s.parentID[:])
+		}
+		if s.err != "" {
+			span.Status = otlpStatus{Code: statusCodeError, Message: s.err}
+		}
+		spans = append(spans, span)
+	}
+
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: attributes("service.name", e.serviceName)},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "cert-exporter"}, Spans: spans}},
+	}}}
+}
+
+// attributes turns key value pairs into OTLP attributes.  A trailing key without a value is reported as an error.
+func attributes(pairs ...string) []otlpAttribute {
+	var attrs []otlpAttribute
+	for i := 0; i < len(pairs); i += 2 {
+		if i+1 == len(pairs) {
+			glog.Errorf("Span attribute %v has no value", pairs[i])
+			break
+		}
+		attrs = append(attrs, otlpAttribute{Key: pairs[i], Value: otlpValue{StringValue: pairs[i+1]}})
+	}
+	return attrs
+}
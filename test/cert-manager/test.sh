@@ -53,6 +53,13 @@ sleep 10 # NB give the deploy more time to be ready. let us know if you know a b
 kubectl --kubeconfig=$CONFIG_PATH apply -f ./certs.yaml
 sleep 10 # NB give cert-manager time to create the certificates. let us know if you know a better way to do this!
 
+# secret holding an encrypted PKCS#8 tls.key and its password
+openssl req -x509 -newkey rsa:2048 -nodes -keyout plain.key -subj "/CN=encrypted-key" -days 100 -out encrypted.crt >/dev/null 2>&1
+openssl pkcs8 -topk8 -v2 aes-256-cbc -in plain.key -out encrypted.key -passout pass:changeit
+kubectl --kubeconfig=$CONFIG_PATH create secret generic encrypted-key --namespace=default \
+    --from-file=tls.crt=encrypted.crt --from-file=tls.key=encrypted.key --from-literal=password=changeit
+rm plain.key encrypted.crt encrypted.key
+
 echo "** Testing Label Selector"
 # run exporter
 $CERT_EXPORTER_PATH \
@@ -105,6 +112,22 @@ validateMetrics 'cert_exporter_secret_expires_in_seconds{cn="example.com",issuer
 echo "** Killing $pid"
 kill $pid
 
+echo "** Testing Encrypted Private Key"
+# run exporter
+$CERT_EXPORTER_PATH \
+    --kubeconfig=$CONFIG_PATH \
+    --secrets-namespace='default' \
+    --secrets-include-glob='tls.*' \
+    --logtostderr &
+pid=$!
+sleep 10
+
+validateMetrics 'cert_exporter_secret_keypair_mismatch{key_name="tls.key",secret_name="encrypted-key",secret_namespace="default"} 0'
+
+# kill exporter
+echo "** Killing $pid"
+kill $pid
+
 echo "** Testing ConfigMap checker"
 # run exporter
 $CERT_EXPORTER_PATH \
@@ -18,7 +18,7 @@ validateMetrics() {
     fi
 
     val=${raw#* }
-    valInDays=$(awk "BEGIN {print $val / (24 * 60 * 60)}")
+    valInDays=$(awk "BEGIN {printf \"%.0f\", $val / (24 * 60 * 60)}")
 
     if [ "$expectedVal" -ne "$valInDays" ]; then
       echo "TEST FAILURE: $metrics"
@@ -99,6 +99,24 @@ validateMetrics 'cert_exporter_kubeconfig_expires_in_seconds{cn="client",filenam
 # kill exporter
 kill $!
 
+#
+# pretend the current time is in the future
+#
+echo "** Testing Pretend Now"
+pretendDays=10
+pretendNow=$(date -u -d "+$pretendDays days" +%Y-%m-%dT%H:%M:%SZ)
+
+# run exporter
+$CERT_EXPORTER_PATH -include-cert-glob=certs/server.crt -pretend-now=$pretendNow &
+
+sleep 2
+
+validateMetrics 'cert_exporter_cert_expires_in_seconds{cn="example.com",filename="certs/server.crt",issuer="root",nodename="master0"}' $((days - pretendDays))
+validateMetrics '^cert_exporter_time_offset_seconds ' $pretendDays
+
+# kill exporter
+kill $!
+
 #
 # confirm error metric works
 #
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/joe-elliott/cert-exporter/src/outputs"
+)
+
+// textfileWriter replaces a file with every metric in the Prometheus text format, e.g. for the textfile collector of
+// the node exporter
+type textfileWriter struct {
+	path string
+}
+
+func init() {
+	outputs.Register("textfile", func(settings map[string]string) (outputs.Output, error) {
+		if settings["path"] == "" {
+			return nil, errors.New("textfile needs a path")
+		}
+		return textfileWriter{path: settings["path"]}, nil
+	})
+}
+
+// Write replaces the file with the current value of every metric.  The metrics are written to a temporary file first,
+// so the collector never reads a partial file.
+func (w textfileWriter) Write() error {
+	return prometheus.WriteToTextfile(w.path, prometheus.DefaultGatherer)
+}
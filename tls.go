@@ -0,0 +1,80 @@
+package main
+
+import (
+	"crypto/tls"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+// certReloader serves the key pair in certFile and keyFile, reloading it whenever either file changes so rotated certs
+// are picked up without a restart
+type certReloader struct {
+	certFile string
+	keyFile  string
+
+	mutex    sync.Mutex
+	cert     *tls.Certificate
+	modified time.Time
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	r := &certReloader{certFile: certFile, keyFile: keyFile}
+	_, err := r.reload()
+	return r, err
+}
+
+// GetCertificate is used as tls.Config.GetCertificate.  If the files cannot be loaded the previous key pair is served.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert, err := r.reload()
+	if err != nil {
+		metrics.RecordError("server", "", metrics.ReasonTLS)
+		glog.Errorf("Error reloading %v and %v: %v", r.certFile, r.keyFile, err)
+	}
+	return cert, nil
+}
+
+// reload loads the key pair if either file was modified since it was last loaded and returns the current key pair
+func (r *certReloader) reload() (*tls.Certificate, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	modified, err := lastModified(r.certFile, r.keyFile)
+	if err != nil {
+		return r.cert, err
+	}
+	if r.cert != nil && !modified.After(r.modified) {
+		return r.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return r.cert, err
+	}
+
+	if r.cert != nil {
+		glog.Infof("Reloaded %v and %v", r.certFile, r.keyFile)
+	}
+	r.cert = &cert
+	r.modified = modified
+	return r.cert, nil
+}
+
+// lastModified returns the latest modification time of the files
+func lastModified(files ...string) (time.Time, error) {
+	var latest time.Time
+	for _, file := range files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return latest, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
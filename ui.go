@@ -0,0 +1,125 @@
+package main
+
+import (
+	"html/template"
+	"net/http"
+	"sort"
+
+	"github.com/golang/glog"
+
+	"github.com/joe-elliott/cert-exporter/src/metrics"
+)
+
+var uiPath string
+
+var uiTemplate = template.Must(template.New("ui").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>cert-exporter</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { padding: 0.3em 0.8em; text-align: left; border-bottom: 1px solid #ddd; }
+tr.expired { background: #f8d7da; }
+tr.critical { background: #fde2c8; }
+tr.warning { background: #fff3cd; }
+</style>
+</head>
+<body>
+<h1>Certificates</h1>
+<form method="get">
+<label>Namespace <select name="namespace">
+<option value="">all</option>
+{{range .Namespaces}}<option{{if eq . $.Namespace}} selected{{end}}>{{.}}</option>
+{{end}}</select></label>
+<label>Kind <select name="kind">
+<option value="">all</option>
+{{range .Kinds}}<option{{if eq . $.Kind}} selected{{end}}>{{.}}</option>
+{{end}}</select></label>
+<input type="submit" value="Filter">
+</form>
+<p>{{len .Entries}} certs, soonest expiry first.</p>
+<table>
+<tr>{{if .Clusters}}<th>Cluster</th>{{end}}<th>Namespace</th><th>Kind</th><th>Name</th><th>Key</th>{{if .Nodes}}<th>Node</th>{{end}}<th>CN</th><th>Days left</th></tr>
+{{range .Entries}}<tr class="{{$.Status .}}">{{if $.Clusters}}<td>{{.Cluster}}</td>{{end}}<td>{{.Namespace}}</td><td>{{.Kind}}</td><td>{{.Name}}</td><td>{{.Key}}</td>{{if $.Nodes}}<td>{{.Node}}</td>{{end}}<td>{{.CN}}</td><td>{{.DaysLeft}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// uiPage is rendered by uiTemplate
+type uiPage struct {
+	Entries    []metrics.InventoryEntry
+	Namespaces []string
+	Kinds      []string
+	Namespace  string
+	Kind       string
+	// Clusters is set when certs of other clusters are listed, to show their cluster
+	Clusters bool
+	// Nodes is set when certs read on nodes are listed, to show their node
+	Nodes bool
+}
+
+// Status returns the row class of an entry, by the thresholds of cert_exporter_cert_warning and cert_exporter_cert_critical
+func (p uiPage) Status(e metrics.InventoryEntry) string {
+	switch {
+	case e.Expired:
+		return "expired"
+	case e.DaysLeft < criticalDays:
+		return "critical"
+	case e.DaysLeft < warningDays:
+		return "warning"
+	}
+	return ""
+}
+
+// serveUI renders the certs of every checker as an HTML table, soonest expiry first, filtered by
+// the namespace and kind query parameters
+func serveUI(w http.ResponseWriter, r *http.Request) {
+	entries, err := metrics.Inventory()
+	if err != nil {
+		glog.Errorf("Error gathering metrics: %v", err)
+		http.Error(w, "error gathering metrics", http.StatusInternalServerError)
+		return
+	}
+
+	page := uiPage{
+		Namespace: r.URL.Query().Get("namespace"),
+		Kind:      r.URL.Query().Get("kind"),
+	}
+	namespaces := map[string]bool{}
+	kinds := map[string]bool{}
+	for _, e := range entries {
+		namespaces[e.Namespace] = true
+		kinds[e.Kind] = true
+		if e.Cluster != "" {
+			page.Clusters = true
+		}
+		if e.Node != "" {
+			page.Nodes = true
+		}
+		if (page.Namespace == "" || e.Namespace == page.Namespace) && (page.Kind == "" || e.Kind == page.Kind) {
+			page.Entries = append(page.Entries, e)
+		}
+	}
+	page.Namespaces = sortedKeys(namespaces)
+	page.Kinds = sortedKeys(kinds)
+	sort.SliceStable(page.Entries, func(i, j int) bool {
+		return page.Entries[i].DaysLeft < page.Entries[j].DaysLeft
+	})
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := uiTemplate.Execute(w, page); err != nil {
+		glog.Errorf("Error rendering %v: %v", uiPath, err)
+	}
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}